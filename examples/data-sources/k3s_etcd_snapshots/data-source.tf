@@ -0,0 +1,30 @@
+variable "host" {
+  type = string
+}
+
+variable "user" {
+  type = string
+}
+
+variable "private_key" {
+  type      = string
+  sensitive = true
+}
+
+data "k3s_etcd_snapshots" "scheduled" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+
+  name_prefix = "etcd-snapshot"
+}
+
+output "latest_snapshot" {
+  value = try(data.k3s_etcd_snapshots.scheduled.snapshots[0].name, null)
+}
+
+output "s3_snapshots" {
+  value = [for snapshot in data.k3s_etcd_snapshots.scheduled.snapshots : snapshot.location if snapshot.storage == "s3"]
+}
@@ -0,0 +1,3 @@
+# Snapshots are imported by the SSH URL of the server they were taken on and
+# their full name as listed by `k3s etcd-snapshot ls`.
+terraform import k3s_etcd_snapshot.pre_upgrade 'ssh://root@example.com?private_key_file=/home/me/.ssh/id_rsa&snapshot=pre-upgrade-server-1-1714557600'
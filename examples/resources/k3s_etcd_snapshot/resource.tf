@@ -0,0 +1,39 @@
+variable "host" {
+  type = string
+}
+
+variable "user" {
+  type = string
+}
+
+variable "private_key" {
+  type      = string
+  sensitive = true
+}
+
+variable "k3s_version" {
+  type = string
+}
+
+resource "k3s_server" "main" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+
+  version = var.k3s_version
+  highly_available = {
+    cluster_init = true
+  }
+}
+
+# Taken again whenever the k3s version changes, before the servers are upgraded
+resource "k3s_etcd_snapshot" "pre_upgrade" {
+  auth = k3s_server.main.auth
+  name = "pre-upgrade"
+
+  triggers = {
+    version = var.k3s_version
+  }
+}
@@ -0,0 +1,48 @@
+variable "host" {
+  type = string
+}
+
+variable "user" {
+  type = string
+}
+
+variable "private_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "k3s_server" "main" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+
+  highly_available = {
+    cluster_init = true
+  }
+}
+
+resource "k3s_etcd_snapshot" "nightly" {
+  auth = k3s_server.main.auth
+  name = "nightly"
+
+  triggers = {
+    day = formatdate("YYYY-MM-DD", plantimestamp())
+  }
+}
+
+# Keeps the last seven nightly snapshots, pruning after each new one
+resource "k3s_etcd_snapshot_retention" "nightly" {
+  auth      = k3s_server.main.auth
+  name      = k3s_etcd_snapshot.nightly.name
+  retention = 7
+
+  triggers = {
+    snapshot = k3s_etcd_snapshot.nightly.id
+  }
+}
+
+output "pruned" {
+  value = k3s_etcd_snapshot_retention.nightly.removed
+}
@@ -0,0 +1,60 @@
+variable "host" {
+  type = string
+}
+
+variable "user" {
+  type = string
+}
+
+variable "private_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "k3s_server" "main" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+}
+
+# Installed through the API with the server credentials
+resource "k3s_helm_chart" "grafana" {
+  cluster_auth = k3s_server.main.cluster_auth
+
+  name             = "grafana"
+  repo             = "https://grafana.github.io/helm-charts"
+  chart            = "grafana"
+  version          = "8.5.0"
+  target_namespace = "monitoring"
+
+  values = {
+    "persistence.enabled" = "true"
+  }
+  values_content = <<-YAML
+  replicas: 2
+  YAML
+}
+
+# Written to the server manifests directory over SSH
+resource "k3s_helm_chart" "cert_manager" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+
+  name             = "cert-manager"
+  repo             = "https://charts.jetstack.io"
+  chart            = "cert-manager"
+  target_namespace = "cert-manager"
+  timeout          = "15m"
+
+  values_content = <<-YAML
+  crds:
+    enabled: true
+  YAML
+
+  depends_on = [k3s_server.main]
+}
@@ -0,0 +1,35 @@
+variable "host" {
+  type = string
+}
+
+variable "user" {
+  type = string
+}
+
+variable "private_key" {
+  type      = string
+  sensitive = true
+}
+
+resource "k3s_server" "main" {
+  auth = {
+    host        = var.host
+    user        = var.user
+    private_key = var.private_key
+  }
+}
+
+# Overrides the values of the traefik chart packaged with k3s
+resource "k3s_helm_chart_config" "traefik" {
+  cluster_auth = k3s_server.main.cluster_auth
+
+  name = "traefik"
+  values_content = <<-YAML
+  ports:
+    web:
+      redirections:
+        entryPoint:
+          to: websecure
+          scheme: https
+  YAML
+}
@@ -0,0 +1,217 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Default time the packaged add-ons may take to roll out after install.
+const ADDON_TIMEOUT = 10 * time.Minute
+
+// Deployments in kube-system created by the packaged components, keyed by
+// the name used to disable the component.
+var addonDeployments = map[string]string{
+	"coredns":        "coredns",
+	"local-storage":  "local-path-provisioner",
+	"metrics-server": "metrics-server",
+	"traefik":        "traefik",
+}
+
+// AddonStatus is the rollout state of a packaged add-on.
+type AddonStatus struct {
+	// deployment or helm job
+	Kind   string
+	Name   string
+	Ready  bool
+	Status string
+}
+
+func (a AddonStatus) String() string {
+	return fmt.Sprintf("%s %s/%s: %s", a.Kind, HELM_CHART_NAMESPACE, a.Name, a.Status)
+}
+
+// Waits until the deployments of every packaged component that is not
+// disabled are available, and the install job of every HelmChart in
+// kube-system succeeded. The kubeconfig is read from the host when empty. A
+// zero timeout skips the wait.
+func waitForAddons(ctx context.Context, client ssh_client.SSHClient, kubeconfig string, timeout time.Duration) error {
+	if timeout == 0 {
+		return nil
+	}
+
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return err
+	}
+	if kubeconfig == "" {
+		if kubeconfig, err = readKubeConfig(client); err != nil {
+			return err
+		}
+	}
+	kube, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return err
+	}
+	return waitForAddonStatus(ctx, kube, expectedAddonDeployments(config), timeout)
+}
+
+func waitForAddonStatus(ctx context.Context, kube *kubeClient, deployments []string, timeout time.Duration) error {
+	tflog.Info(ctx, fmt.Sprintf("Waiting up to %s for the add-ons %s", timeout, strings.Join(deployments, ", ")))
+
+	var statuses []AddonStatus
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, helmPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		statuses, lastErr = readAddonStatus(ctx, kube, deployments)
+		if lastErr != nil {
+			tflog.Debug(ctx, fmt.Sprintf("Could not read add-on status: %s", lastErr.Error()))
+			return false, nil
+		}
+		for _, status := range statuses {
+			if !status.Ready {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if !wait.Interrupted(err) {
+		return err
+	}
+
+	if lastErr != nil && len(statuses) == 0 {
+		return fmt.Errorf("add-ons were not healthy within %s: %s", timeout, lastErr.Error())
+	}
+	lines := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		lines = append(lines, "  "+status.String())
+	}
+	return fmt.Errorf("add-ons were not healthy within %s:\n%s", timeout, strings.Join(lines, "\n"))
+}
+
+// Deployments of the packaged components that are not disabled in config.
+func expectedAddonDeployments(config map[any]any) []string {
+	disabled := map[string]bool{}
+	for _, component := range configList(config["disable"]) {
+		disabled[component] = true
+	}
+
+	var deployments []string
+	for _, component := range sortedKeys(addonDeployments) {
+		if !disabled[component] {
+			deployments = append(deployments, addonDeployments[component])
+		}
+	}
+	return deployments
+}
+
+// A config value given either as a YAML list or as a comma separated
+// string, as k3s accepts for slice flags.
+func configList(value any) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			items = append(items, strings.Split(fmt.Sprint(item), ",")...)
+		}
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func readAddonStatus(ctx context.Context, kube *kubeClient, deployments []string) ([]AddonStatus, error) {
+	statuses := make([]AddonStatus, 0, len(deployments))
+	for _, name := range deployments {
+		deployment, err := kube.core.AppsV1().Deployments(HELM_CHART_NAMESPACE).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			statuses = append(statuses, AddonStatus{Kind: "deployment", Name: name, Status: "not created yet"})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading deployment %s/%s: %s", HELM_CHART_NAMESPACE, name, err.Error())
+		}
+		ready, status := deploymentStatus(*deployment)
+		statuses = append(statuses, AddonStatus{Kind: "deployment", Name: name, Ready: ready, Status: status})
+	}
+
+	charts, err := kube.dynamic.Resource(helmChartResource).Namespace(HELM_CHART_NAMESPACE).List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		// The HelmChart CRD is not registered yet
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing helm charts: %s", err.Error())
+	}
+	names := make([]string, 0, len(charts.Items))
+	for _, chart := range charts.Items {
+		names = append(names, chart.GetName())
+	}
+	sort.Strings(names)
+	for _, chart := range names {
+		job, err := kube.core.BatchV1().Jobs(HELM_CHART_NAMESPACE).Get(ctx, helmJobName(chart), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			statuses = append(statuses, AddonStatus{Kind: "helm job", Name: helmJobName(chart), Status: "not created yet"})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading helm job %s/%s: %s", HELM_CHART_NAMESPACE, helmJobName(chart), err.Error())
+		}
+		ready, status := helmJobStatus(*job)
+		if !ready && strings.HasPrefix(status, "failed") {
+			status += helmJobFailure(ctx, kube, HELM_CHART_NAMESPACE, job.Name)
+		}
+		statuses = append(statuses, AddonStatus{Kind: "helm job", Name: job.Name, Ready: ready, Status: status})
+	}
+	return statuses, nil
+}
+
+func deploymentStatus(deployment appsv1.Deployment) (bool, string) {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, "rollout not observed yet"
+	}
+	status := fmt.Sprintf("%d/%d available", deployment.Status.AvailableReplicas, replicas)
+	if deployment.Status.UpdatedReplicas < replicas || deployment.Status.AvailableReplicas < replicas {
+		return false, status
+	}
+	return true, status
+}
+
+// Whether the helm install job succeeded. The helm controller reruns a
+// failed job, so a failure is reported but not final.
+func helmJobStatus(job batchv1.Job) (bool, string) {
+	if job.Status.Succeeded > 0 {
+		return true, "succeeded"
+	}
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return false, "failed: " + condition.Message
+		}
+	}
+	if job.Status.Failed > 0 {
+		return false, fmt.Sprintf("failed: %d attempts failed", job.Status.Failed)
+	}
+	return false, "running"
+}
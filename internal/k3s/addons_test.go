@@ -0,0 +1,151 @@
+package k3s
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExpectedAddonDeployments(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[any]any
+		want   []string
+	}{
+		{name: "all packaged", config: map[any]any{}, want: []string{"coredns", "local-path-provisioner", "metrics-server", "traefik"}},
+		{name: "disabled list", config: map[any]any{"disable": []any{"traefik", "metrics-server"}}, want: []string{"coredns", "local-path-provisioner"}},
+		{name: "disabled string", config: map[any]any{"disable": "traefik, local-storage"}, want: []string{"coredns", "metrics-server"}},
+		{name: "comma separated list items", config: map[any]any{"disable": []any{"traefik,coredns"}}, want: []string{"local-path-provisioner", "metrics-server"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expectedAddonDeployments(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expectedAddonDeployments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeploymentStatus(t *testing.T) {
+	two := int32(2)
+	tests := []struct {
+		name       string
+		deployment appsv1.Deployment
+		wantReady  bool
+		wantStatus string
+	}{
+		{
+			name:       "available",
+			deployment: appsv1.Deployment{Status: appsv1.DeploymentStatus{UpdatedReplicas: 1, AvailableReplicas: 1}},
+			wantReady:  true,
+			wantStatus: "1/1 available",
+		},
+		{
+			name:       "rolling out",
+			deployment: appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &two}, Status: appsv1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: 1}},
+			wantStatus: "1/2 available",
+		},
+		{
+			name:       "old replicas available",
+			deployment: appsv1.Deployment{Status: appsv1.DeploymentStatus{AvailableReplicas: 1}},
+			wantStatus: "1/1 available",
+		},
+		{
+			name:       "generation not observed",
+			deployment: appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Generation: 2}, Status: appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 1, AvailableReplicas: 1}},
+			wantStatus: "rollout not observed yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, status := deploymentStatus(tt.deployment)
+			if ready != tt.wantReady || status != tt.wantStatus {
+				t.Errorf("deploymentStatus() = %v, %q, want %v, %q", ready, status, tt.wantReady, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWaitForAddonStatus(t *testing.T) {
+	previous := helmPollInterval
+	helmPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { helmPollInterval = previous })
+
+	deployment := func(name string, available int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system"},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: available, AvailableReplicas: available},
+		}
+	}
+	job := func(status batchv1.JobStatus) *batchv1.Job {
+		return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "helm-install-traefik", Namespace: "kube-system"}, Status: status}
+	}
+	failed := batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}}
+	chart := (&HelmChart{Name: "traefik", Namespace: "kube-system", Chart: "traefik"}).object()
+
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		wantError []string
+	}{
+		{
+			name:    "healthy",
+			objects: []runtime.Object{deployment("coredns", 1), deployment("traefik", 1), job(batchv1.JobStatus{Succeeded: 1})},
+		},
+		{
+			name:      "deployment unavailable",
+			objects:   []runtime.Object{deployment("coredns", 0), deployment("traefik", 1), job(batchv1.JobStatus{Succeeded: 1})},
+			wantError: []string{"deployment kube-system/coredns: 0/1 available", "deployment kube-system/traefik: 1/1 available"},
+		},
+		{
+			name:      "deployment missing",
+			objects:   []runtime.Object{deployment("coredns", 1), job(batchv1.JobStatus{Succeeded: 1})},
+			wantError: []string{"deployment kube-system/traefik: not created yet"},
+		},
+		{
+			name:      "helm job failed",
+			objects:   []runtime.Object{deployment("coredns", 1), deployment("traefik", 1), job(failed)},
+			wantError: []string{"helm job kube-system/helm-install-traefik: failed: BackoffLimitExceeded"},
+		},
+		{
+			name:      "helm job missing",
+			objects:   []runtime.Object{deployment("coredns", 1), deployment("traefik", 1)},
+			wantError: []string{"helm job kube-system/helm-install-traefik: not created yet"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				helmChartResource: "HelmChartList",
+			}, chart.DeepCopy())
+			client := &kubeClient{core: fake.NewClientset(tt.objects...), dynamic: dynamic}
+
+			err := waitForAddonStatus(context.Background(), client, []string{"coredns", "traefik"}, 50*time.Millisecond)
+			if len(tt.wantError) == 0 && err != nil {
+				t.Fatalf("waitForAddonStatus() error = %v", err)
+			}
+			if len(tt.wantError) > 0 && err == nil {
+				t.Fatalf("waitForAddonStatus() error = nil, want %q", tt.wantError)
+			}
+			for _, want := range tt.wantError {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("waitForAddonStatus() error = %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
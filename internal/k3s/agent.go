@@ -3,7 +3,6 @@ package k3s
 import (
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/hashicorp/terraform-plugin-log/tflog"
@@ -20,9 +19,41 @@ type Agent struct {
 	Token      string
 	Version    string
 	BinDir     string
-	ExtraFiles map[string]string
+	ExtraFiles map[string]ExtraFile
 	Env        map[string]string
-	Server     string
+	// Previously managed extra files that should be removed
+	StaleExtraFiles []string
+	// Observed extra files, keyed by path
+	ObservedExtraFiles map[string]ExtraFileStatus
+	// Managed config.yaml.d drop-ins, keyed by name without extension
+	ConfigFragments map[string]string
+	// Previously managed drop-ins that should be removed
+	StaleConfigFragments []string
+	// Typed registries.yaml, used instead of Registry when set
+	Registries *Registries
+	// Registry hosts whose uploaded TLS material should be removed
+	StaleRegistryHosts []string
+	// Containerd config template, written to the agent data dir when set
+	ContainerdConfigTemplate string
+	// Whether a previously managed containerd config template should be removed
+	RemoveContainerdConfigTemplate bool
+	// Managed static pod manifests, keyed by name without extension
+	StaticPods map[string]string
+	// Previously managed static pods that should be removed
+	StaleStaticPods []string
+	// Observed config.yaml merged with every drop-in
+	MergedConfig string
+	Server       string
+	// Drains and deletes the node before uninstalling when set
+	Drain *NodeDrain
+	// How long Install and Update wait for the Node to be Ready, no waiting
+	// when zero
+	ReadyTimeout time.Duration
+	// Observed identity of the node, set by Refresh
+	NodeInfo *NodeInfo
+	// Labels and taints managed on the Node through the API, requires
+	// ClusterAuth
+	NodeMetadata *NodeMetadata
 
 	// Internal fields to check for
 	// correct formatting and config merging
@@ -36,8 +67,13 @@ func (a *Agent) Install(ctx context.Context, client ssh_client.SSHClient) error
 		tflog.MaskMessageStrings(ctx, a.Token)
 	}
 
+	installCommand, err := a.installCommand()
+	if err != nil {
+		return err
+	}
+
 	commands := []string{
-		a.installCommand(),
+		installCommand,
 		"sudo systemctl daemon-reload",
 		"sudo systemctl --no-block start k3s-agent",
 	}
@@ -48,8 +84,11 @@ func (a *Agent) Install(ctx context.Context, client ssh_client.SSHClient) error
 	if err := waitForK3sSystemdServiceActive(client, "k3s-agent", 4*time.Minute); err != nil {
 		return err
 	}
+	if err := waitForNodeReady(ctx, client, a.BinDir, false, a.ReadyTimeout); err != nil {
+		return err
+	}
 
-	return nil
+	return waitForStaticPods(ctx, client, a.BinDir, a.StaticPods, a.ReadyTimeout)
 }
 
 // PreInstall implements [K3sComponent].
@@ -66,7 +105,11 @@ func (a *Agent) PreInstall(ctx context.Context, client ssh_client.SSHClient) err
 	if err != nil {
 		return err
 	}
-	extraFileCommands := syncExtraFiles(a.ExtraFiles)
+	fileCommands := extraFileCommands(a.ExtraFiles, a.StaleExtraFiles)
+	fragmentCommands := configFragmentCommands(a.ConfigFragments, a.StaleConfigFragments)
+	tlsCommands := registryTLSCommands(a.Registries, a.StaleRegistryHosts)
+	containerdCommands := containerdConfigTemplateCommands(a.dataDir(), a.ContainerdConfigTemplate, a.RemoveContainerdConfigTemplate)
+	podCommands := staticPodCommands(a.dataDir(), a.StaticPods, a.StaleStaticPods)
 
 	tflog.Debug(ctx, "Reading install script")
 	installContents, err := ReadInstallScript()
@@ -75,12 +118,12 @@ func (a *Agent) PreInstall(ctx context.Context, client ssh_client.SSHClient) err
 	}
 
 	commands := []string{
-		fmt.Sprintf("sudo mkdir -p %s", CONFIG_DIR),
-		fmt.Sprintf("sudo mkdir -p %s", a.dataDir()),
+		sudo("mkdir", "-p").Arg(CONFIG_DIR).String(),
+		sudo("mkdir", "-p").Arg(a.dataDir()).String(),
 	}
 
 	if a.BinDir != BIN_DIR {
-		commands = append(commands, fmt.Sprintf("sudo mkdir -p %s", a.BinDir))
+		commands = append(commands, sudo("mkdir", "-p").Arg(a.BinDir).String())
 	}
 
 	commands = append(commands, WriteFileCommands(a.BinDir+"/k3s-install.sh", installContents)...)
@@ -89,8 +132,20 @@ func (a *Agent) PreInstall(ctx context.Context, client ssh_client.SSHClient) err
 	if len(regCommands) > 0 {
 		commands = append(commands, regCommands...)
 	}
-	if len(extraFileCommands) > 0 {
-		commands = append(commands, extraFileCommands...)
+	if len(tlsCommands) > 0 {
+		commands = append(commands, tlsCommands...)
+	}
+	if len(fragmentCommands) > 0 {
+		commands = append(commands, fragmentCommands...)
+	}
+	if len(containerdCommands) > 0 {
+		commands = append(commands, containerdCommands...)
+	}
+	if len(podCommands) > 0 {
+		commands = append(commands, podCommands...)
+	}
+	if len(fileCommands) > 0 {
+		commands = append(commands, fileCommands...)
 	}
 
 	return client.RunStream(commands)
@@ -135,6 +190,35 @@ func (a *Agent) Refresh(ctx context.Context, client ssh_client.SSHClient) (exist
 	}
 	a.Server = server
 
+	if err := a.refreshConfigFragments(client); err != nil {
+		return true, active, err
+	}
+	if err := a.refreshStaticPods(client); err != nil {
+		return true, active, err
+	}
+	template, err := refreshContainerdConfigTemplate(client, a.MergedConfig, a.ContainerdConfigTemplate)
+	if err != nil {
+		return true, active, err
+	}
+	a.ContainerdConfigTemplate = template
+
+	observed, err := readExtraFiles(client, sortedKeys(a.ExtraFiles))
+	if err != nil {
+		return true, active, err
+	}
+	a.ObservedExtraFiles = observed
+
+	nodeInfo, err := readNodeInfo(ctx, client, a.BinDir)
+	if err != nil {
+		return true, active, err
+	}
+	a.NodeInfo = nodeInfo
+
+	// The Node lives on the servers, so it is read while the agent is down too
+	if err := a.NodeMetadata.Refresh(ctx, client, ""); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not read node labels and taints: %s", err.Error()))
+	}
+
 	return true, active, nil
 }
 
@@ -153,13 +237,19 @@ func (a *Agent) Uninstall(ctx context.Context, client ssh_client.SSHClient) erro
 		return nil
 	}
 
+	if a.Drain != nil {
+		if err := a.Drain.RemoveHost(ctx, client); err != nil {
+			return fmt.Errorf("draining node before uninstall: %s", err.Error())
+		}
+	}
+
 	binDir := a.BinDir
 	if binDir == "" {
 		binDir = BIN_DIR
 	}
 
 	if err := client.RunStream([]string{
-		fmt.Sprintf("sudo test -f %[1]s/k3s-agent-uninstall.sh && sudo bash %[1]s/k3s-agent-uninstall.sh", binDir),
+		fmt.Sprintf("%s && %s", sudo("test", "-f").Arg(binDir+"/k3s-agent-uninstall.sh"), sudo("bash").Arg(binDir+"/k3s-agent-uninstall.sh")),
 	}); err != nil {
 		return err
 	}
@@ -187,6 +277,51 @@ func (a *Agent) Validate(ctx context.Context) error {
 	if err := yaml.Unmarshal([]byte(a.Registry), &a.registry); err != nil {
 		return fmt.Errorf("parsing registry: %s", err.Error())
 	}
+	if a.Registries != nil {
+		if len(a.registry) > 0 {
+			return fmt.Errorf("registry and registries cannot both be set")
+		}
+		if err := a.Registries.Validate(); err != nil {
+			return err
+		}
+		a.registry = a.Registries.registry()
+	}
+	if err := validateEnv(a.Env); err != nil {
+		return err
+	}
+	if err := ValidateExtraFiles(a.ExtraFiles); err != nil {
+		return err
+	}
+	if err := ValidateConfigFragments(a.ConfigFragments); err != nil {
+		return err
+	}
+	if err := ValidateStaticPods(a.StaticPods); err != nil {
+		return err
+	}
+	if a.ContainerdConfigTemplate != "" {
+		if err := ValidateContainerdConfigTemplate(a.ContainerdConfigTemplate); err != nil {
+			return err
+		}
+	}
+	if a.Drain != nil {
+		if err := a.Drain.Validate(); err != nil {
+			return err
+		}
+	}
+	if a.NodeMetadata != nil {
+		if err := a.NodeMetadata.Validate(); err != nil {
+			return err
+		}
+		if a.NodeMetadata.managed() && a.NodeMetadata.ClusterAuth == nil {
+			return fmt.Errorf("cluster_auth is required to manage the labels and taints of an agent")
+		}
+	}
+	if err := ValidateConfigKeys(ROLE_AGENT, a.Version, a.config); err != nil {
+		return err
+	}
+	if err := validateConfigFragmentKeys(ROLE_AGENT, a.Version, a.ConfigFragments); err != nil {
+		return err
+	}
 	if a.BinDir == "" {
 		a.BinDir = BIN_DIR
 	}
@@ -203,8 +338,13 @@ func (a *Agent) Update(ctx context.Context, client ssh_client.SSHClient) error {
 		tflog.MaskMessageStrings(ctx, a.Token)
 	}
 
+	installCommand, err := a.installCommand()
+	if err != nil {
+		return err
+	}
+
 	commands := []string{
-		a.installCommand(),
+		installCommand,
 		"sudo systemctl daemon-reload",
 		"sudo systemctl --no-block restart k3s-agent",
 	}
@@ -213,37 +353,57 @@ func (a *Agent) Update(ctx context.Context, client ssh_client.SSHClient) error {
 		return err
 	}
 	if err := waitForK3sSystemdServiceActive(client, "k3s-agent", 4*time.Minute); err != nil {
+		return rollback(ctx, client, "k3s-agent", a.backupPaths(), err, 4*time.Minute)
+	}
+
+	if err := removeBackup(client, "k3s-agent"); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not remove k3s-agent backup: %s", err.Error()))
+	}
+
+	if err := waitForNodeReady(ctx, client, a.BinDir, false, a.ReadyTimeout); err != nil {
 		return err
 	}
+	return waitForStaticPods(ctx, client, a.BinDir, a.StaticPods, a.ReadyTimeout)
+}
 
-	return nil
+// Backup copies the current config, registries, extra files, binary and
+// service files aside so a failed Update can be rolled back.
+func (a *Agent) Backup(ctx context.Context, client ssh_client.SSHClient) error {
+	return takeBackup(ctx, client, "k3s-agent", a.backupPaths())
 }
 
-func (a *Agent) installCommand() string {
-	flags := []string{
-		"INSTALL_K3S_SKIP_START=true",
-		fmt.Sprintf("INSTALL_K3S_BIN_DIR=%s", a.BinDir),
-		fmt.Sprintf("INSTALL_K3S_EXEC='agent --config %s/config.yaml'", CONFIG_DIR),
-		fmt.Sprintf("K3S_URL=%s", a.Server),
-		fmt.Sprintf("K3S_TOKEN=%s", a.Token),
+func (a *Agent) backupPaths() []string {
+	extraPaths := append(sortedKeys(a.ExtraFiles), a.StaleExtraFiles...)
+	extraPaths = append(extraPaths, configFragmentPaths(sortedKeys(a.ConfigFragments), a.StaleConfigFragments)...)
+	extraPaths = append(extraPaths, registryTLSPaths(a.Registries.hosts(), a.StaleRegistryHosts)...)
+	extraPaths = append(extraPaths, staticPodPaths(a.dataDir(), sortedKeys(a.StaticPods), a.StaleStaticPods)...)
+	if a.ContainerdConfigTemplate != "" || a.RemoveContainerdConfigTemplate {
+		extraPaths = append(extraPaths, containerdConfigTemplatePath(a.dataDir()))
 	}
+	return backupPaths(a.BinDir, "k3s-agent", extraPaths)
+}
+
+func (a *Agent) installCommand() (string, error) {
+	command := sudo().
+		Env("INSTALL_K3S_SKIP_START", "true").
+		Env("INSTALL_K3S_BIN_DIR", a.BinDir).
+		Env("INSTALL_K3S_EXEC", fmt.Sprintf("agent --config %s/config.yaml", CONFIG_DIR)).
+		Env("K3S_URL", a.Server).
+		Env("K3S_TOKEN", a.Token)
 
 	if a.Version != "" {
-		flags = append(flags, fmt.Sprintf("INSTALL_K3S_VERSION=\"%s\"", a.Version))
+		command.Env("INSTALL_K3S_VERSION", a.Version)
 	}
 
-	for k, v := range a.Env {
-		flags = append(flags, fmt.Sprintf("%s=\"%s\"", k, v))
+	for _, k := range sortedKeys(a.Env) {
+		command.Env(k, a.Env[k])
 	}
 
-	return fmt.Sprintf("sudo %s bash %s/k3s-install.sh", strings.Join(flags, " "), a.BinDir)
+	return command.Literal("bash").Arg(a.BinDir + "/k3s-install.sh").Build()
 }
 
 func (a *Agent) dataDir() string {
-	if dir, ok := a.config["data_dir"].(string); ok && dir != "" {
-		return dir
-	}
-	return DATA_DIR
+	return configDataDir(a.config)
 }
 
 func k3sAgentServiceExists(client ssh_client.SSHClient) (bool, error) {
@@ -261,3 +421,32 @@ func (a *Agent) getAgentEnv(client ssh_client.SSHClient) (map[string]string, err
 	}
 	return godotenv.Unmarshal(file)
 }
+
+// Reads back the managed static pods. Must run after refreshConfigFragments.
+func (a *Agent) refreshStaticPods(client ssh_client.SSHClient) error {
+	pods, err := refreshStaticPods(client, a.MergedConfig, a.StaticPods)
+	if err != nil {
+		return err
+	}
+	a.StaticPods = pods
+	return nil
+}
+
+// Reads back the managed drop-ins and the merged config.
+func (a *Agent) refreshConfigFragments(client ssh_client.SSHClient) error {
+	if len(a.ConfigFragments) > 0 {
+		fragments, err := readConfigFragments(client, sortedKeys(a.ConfigFragments))
+		if err != nil {
+			return err
+		}
+		a.ConfigFragments = fragments
+	}
+
+	merged, err := readMergedConfig(client)
+	if err != nil {
+		return err
+	}
+	a.MergedConfig = merged
+
+	return nil
+}
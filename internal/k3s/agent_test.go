@@ -8,7 +8,7 @@ import (
 
 func TestAgentValidateDefaultsBinDir(t *testing.T) {
 	agent := Agent{
-		Config: "data_dir: /opt/rancher/k3s\n",
+		Config: "data-dir: /opt/rancher/k3s\n",
 	}
 
 	if err := agent.Validate(context.Background()); err != nil {
@@ -34,16 +34,19 @@ func TestAgentInstallCommand(t *testing.T) {
 		},
 	}
 
-	command := agent.installCommand()
+	command, err := agent.installCommand()
+	if err != nil {
+		t.Fatalf("installCommand() error = %v", err)
+	}
 	wantParts := []string{
-		"INSTALL_K3S_SKIP_START=true",
-		"INSTALL_K3S_BIN_DIR=/opt/bin",
+		"INSTALL_K3S_SKIP_START='true'",
+		"INSTALL_K3S_BIN_DIR='/opt/bin'",
 		"INSTALL_K3S_EXEC='agent --config /etc/rancher/k3s/config.yaml'",
-		"K3S_URL=https://10.0.0.1:6443",
-		"K3S_TOKEN=join-token",
-		"INSTALL_K3S_VERSION=\"v1.32.6+k3s1\"",
-		"INSTALL_K3S_CHANNEL=\"stable\"",
-		"bash /opt/bin/k3s-install.sh",
+		"K3S_URL='https://10.0.0.1:6443'",
+		"K3S_TOKEN='join-token'",
+		"INSTALL_K3S_VERSION='v1.32.6+k3s1'",
+		"INSTALL_K3S_CHANNEL='stable'",
+		"bash '/opt/bin/k3s-install.sh'",
 	}
 
 	for _, want := range wantParts {
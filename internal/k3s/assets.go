@@ -3,7 +3,6 @@ package k3s
 import (
 	"embed"
 	"encoding/base64"
-	"fmt"
 )
 
 //go:embed assets/*
@@ -20,12 +19,15 @@ func ReadInstallScript() (string, error) {
 }
 
 // Generate a list of commands to write a base64 encoded string
-// to a remote file and chown to root.
+// to a remote file and chown to root. The encoded content is staged in a
+// file only root can read, as it may hold secrets.
 func WriteFileCommands(path string, b64Content string) []string {
+	tmp := path + ".tmp"
 	return []string{
-		fmt.Sprintf("echo %q | sudo tee %s.tmp > /dev/null", b64Content, path),
-		fmt.Sprintf("sudo base64 -d %[1]s.tmp | sudo tee %[1]s > /dev/null", path),
-		fmt.Sprintf("sudo chown root:root %s", path),
-		fmt.Sprintf("sudo rm %s.tmp", path),
+		sudo("install", "-m", "0600", "/dev/null").Arg(tmp).String(),
+		pipeline(newCommand("echo").Arg(b64Content), sudo("tee").Arg(tmp).Literal("> /dev/null")),
+		pipeline(sudo("base64", "-d").Arg(tmp), sudo("tee").Arg(path).Literal("> /dev/null")),
+		sudo("chown", "root:root").Arg(path).String(),
+		sudo("rm", "-f").Arg(tmp).String(),
 	}
 }
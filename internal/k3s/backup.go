@@ -0,0 +1,137 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+const BACKUP_DIR string = "/var/lib/rancher/k3s-backup"
+
+// RollbackError is returned when the service did not become active after
+// an update and the previous node configuration was restored.
+type RollbackError struct {
+	// Err is the original failure, including the service journal.
+	Err error
+	// RollbackErr is set when restoring the previous configuration failed.
+	RollbackErr error
+}
+
+func (e *RollbackError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("%s; rollback failed: %s", e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("%s; previous configuration was restored", e.Err)
+}
+
+func (e *RollbackError) Unwrap() error {
+	return e.Err
+}
+
+// Files that PreInstall and the install script overwrite during an update.
+func backupPaths(binDir string, serviceName string, extraPaths []string) []string {
+	paths := []string{
+		fmt.Sprintf("%s/config.yaml", CONFIG_DIR),
+		fmt.Sprintf("%s/registries.yaml", CONFIG_DIR),
+		fmt.Sprintf("%s/k3s", binDir),
+		fmt.Sprintf("/etc/systemd/system/%s.service", serviceName),
+		fmt.Sprintf("/etc/systemd/system/%s.service.env", serviceName),
+	}
+
+	return append(paths, extraPaths...)
+}
+
+func backupDir(serviceName string) string {
+	return fmt.Sprintf("%s/%s", BACKUP_DIR, serviceName)
+}
+
+// Commands for copying the current node configuration to the backup directory.
+// A marker file is written last so a partial backup is never restored.
+func backupCommands(serviceName string, paths []string) []string {
+	dir := backupDir(serviceName)
+	commands := []string{
+		sudo("rm", "-rf").Arg(dir).String(),
+		sudo("mkdir", "-p").Arg(dir).String(),
+	}
+
+	for _, path := range paths {
+		backup := dir + path
+		commands = append(commands, fmt.Sprintf(
+			"if %s; then %s && %s; fi",
+			sudo("test", "-e").Arg(path), sudo("mkdir", "-p").Arg(filepath.Dir(backup)), sudo("cp", "-a").Arg(path, backup),
+		))
+	}
+
+	return append(commands, sudo("touch").Arg(dir+"/.complete").String())
+}
+
+// Commands for restoring a backup. Files that did not exist when the backup
+// was taken are removed.
+func restoreCommands(serviceName string, paths []string) []string {
+	dir := backupDir(serviceName)
+	commands := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		backup := dir + path
+		commands = append(commands, fmt.Sprintf(
+			"if %s; then %s; else %s; fi",
+			sudo("test", "-e").Arg(backup), sudo("cp", "-a").Arg(backup, path), sudo("rm", "-f").Arg(path),
+		))
+	}
+
+	return commands
+}
+
+func backupExists(client ssh_client.SSHClient, serviceName string) (bool, error) {
+	return remoteFileExists(client, backupDir(serviceName)+"/.complete")
+}
+
+func takeBackup(ctx context.Context, client ssh_client.SSHClient, serviceName string, paths []string) error {
+	if err := client.WaitForReady(); err != nil {
+		return err
+	}
+
+	tflog.Debug(ctx, fmt.Sprintf("Backing up %s configuration to %s", serviceName, backupDir(serviceName)))
+	return client.RunStream(backupCommands(serviceName, paths))
+}
+
+func removeBackup(client ssh_client.SSHClient, serviceName string) error {
+	return client.RunStream([]string{sudo("rm", "-rf").Arg(backupDir(serviceName)).String()})
+}
+
+// Restores the backup taken before an update and restarts the service.
+// The returned error always wraps the original update failure.
+func rollback(ctx context.Context, client ssh_client.SSHClient, serviceName string, paths []string, updateErr error, timeout time.Duration) error {
+	exists, err := backupExists(client, serviceName)
+	if err != nil {
+		return &RollbackError{Err: updateErr, RollbackErr: fmt.Errorf("checking for backup: %w", err)}
+	}
+	if !exists {
+		tflog.Warn(ctx, fmt.Sprintf("No %s backup found, skipping rollback", serviceName))
+		return updateErr
+	}
+
+	tflog.Warn(ctx, fmt.Sprintf("%s did not become active, restoring previous configuration", serviceName))
+	commands := append(restoreCommands(serviceName, paths),
+		"sudo systemctl daemon-reload",
+		fmt.Sprintf("%s || true", sudo("systemctl", "reset-failed").Arg(serviceName)),
+		sudo("systemctl", "--no-block", "restart").Arg(serviceName).String(),
+	)
+	if err := client.RunStream(commands); err != nil {
+		return &RollbackError{Err: updateErr, RollbackErr: fmt.Errorf("restoring backup: %w", err)}
+	}
+
+	if err := waitForK3sSystemdServiceActive(client, serviceName, timeout); err != nil {
+		return &RollbackError{Err: updateErr, RollbackErr: err}
+	}
+
+	if err := removeBackup(client, serviceName); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not remove %s backup: %s", serviceName, err.Error()))
+	}
+
+	return &RollbackError{Err: updateErr}
+}
@@ -0,0 +1,72 @@
+package k3s
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBackupPaths(t *testing.T) {
+	paths := backupPaths("/opt/bin", "k3s-agent", []string{
+		"/etc/rancher/k3s/tls/a.key",
+		"/etc/rancher/k3s/tls/b.key",
+	})
+
+	want := []string{
+		"/etc/rancher/k3s/config.yaml",
+		"/etc/rancher/k3s/registries.yaml",
+		"/opt/bin/k3s",
+		"/etc/systemd/system/k3s-agent.service",
+		"/etc/systemd/system/k3s-agent.service.env",
+		"/etc/rancher/k3s/tls/a.key",
+		"/etc/rancher/k3s/tls/b.key",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("backupPaths() = %v, want %v", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("backupPaths()[%d] = %q, want %q", i, paths[i], want[i])
+		}
+	}
+}
+
+func TestBackupAndRestoreCommands(t *testing.T) {
+	paths := []string{"/etc/rancher/k3s/config.yaml"}
+
+	backup := backupCommands("k3s", paths)
+	if got, want := backup[0], "sudo rm -rf '/var/lib/rancher/k3s-backup/k3s'"; got != want {
+		t.Errorf("backupCommands()[0] = %q, want %q", got, want)
+	}
+	if !strings.Contains(backup[2], "sudo cp -a '/etc/rancher/k3s/config.yaml' '/var/lib/rancher/k3s-backup/k3s/etc/rancher/k3s/config.yaml'") {
+		t.Errorf("backupCommands()[2] = %q, want copy into backup dir", backup[2])
+	}
+	if got, want := backup[len(backup)-1], "sudo touch '/var/lib/rancher/k3s-backup/k3s/.complete'"; got != want {
+		t.Errorf("backupCommands() last = %q, want %q", got, want)
+	}
+
+	restore := restoreCommands("k3s", paths)
+	if len(restore) != 1 {
+		t.Fatalf("restoreCommands() = %v, want one command", restore)
+	}
+	if !strings.Contains(restore[0], "else sudo rm -f '/etc/rancher/k3s/config.yaml'") {
+		t.Errorf("restoreCommands()[0] = %q, want removal of files missing from backup", restore[0])
+	}
+}
+
+func TestRollbackError(t *testing.T) {
+	updateErr := errors.New("k3s service did not become active")
+
+	restored := &RollbackError{Err: updateErr}
+	if !errors.Is(restored, updateErr) {
+		t.Errorf("RollbackError does not unwrap to the update error")
+	}
+	if !strings.Contains(restored.Error(), "previous configuration was restored") {
+		t.Errorf("Error() = %q, want restored message", restored.Error())
+	}
+
+	failed := &RollbackError{Err: updateErr, RollbackErr: errors.New("still failing")}
+	if !strings.Contains(failed.Error(), "rollback failed: still failing") {
+		t.Errorf("Error() = %q, want rollback failure", failed.Error())
+	}
+}
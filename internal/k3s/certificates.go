@@ -0,0 +1,153 @@
+package k3s
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Services accepted by `k3s certificate rotate --service`.
+var CertificateServices = []string{
+	"admin",
+	"api-server",
+	"auth-proxy",
+	"cloud-controller",
+	"controller-manager",
+	"etcd",
+	"k3s-controller",
+	"k3s-server",
+	"kube-proxy",
+	"kubelet",
+	"scheduler",
+	"supervisor",
+}
+
+// ValidateCertificateServices checks every service against CertificateServices.
+func ValidateCertificateServices(services []string) error {
+	for _, service := range services {
+		if !slices.Contains(CertificateServices, service) {
+			return fmt.Errorf("certificate service %q must be one of %s", service, strings.Join(CertificateServices, ", "))
+		}
+	}
+	return nil
+}
+
+// RotateCertificates stops the service, rotates the certificates of the
+// given services, or of all of them when none are given, and starts the
+// service again. The kubeconfig is read again by the next Refresh.
+func (s *Server) RotateCertificates(ctx context.Context, client ssh_client.SSHClient, services []string) error {
+	if err := ValidateCertificateServices(services); err != nil {
+		return err
+	}
+	if err := client.WaitForReady(); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Rotating k3s certificates of %s", certificateServicesLabel(services)))
+	rotateErr := client.RunStream(certificateRotateCommands(s.BinDir, services))
+	// The service is started again even when the rotation failed
+	if err := waitForK3sSystemdServiceActive(client, "k3s", 5*time.Minute); err != nil && rotateErr == nil {
+		return err
+	}
+	if rotateErr != nil {
+		return fmt.Errorf("rotating certificates: %s", rotateErr.Error())
+	}
+	return nil
+}
+
+func certificateRotateCommands(binDir string, services []string) []string {
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+
+	rotate := sudo().Arg(binDir+"/k3s").Literal("certificate", "rotate")
+	for _, service := range services {
+		rotate.Literal("--service").Arg(service)
+	}
+
+	// Start the service whether or not the rotation succeeded, so a failure
+	// does not leave the control plane down
+	return []string{
+		"sudo systemctl stop k3s",
+		fmt.Sprintf("%s; status=$?; sudo systemctl --no-block start k3s; exit $status", rotate),
+	}
+}
+
+func certificateServicesLabel(services []string) string {
+	if len(services) == 0 {
+		return "all services"
+	}
+	return strings.Join(services, ", ")
+}
+
+// Reads the expiry of every certificate under <data-dir>/server/tls, keyed
+// by path relative to that directory.
+func readCertificateExpiry(client ssh_client.SSHClient, dataDir string) (map[string]string, error) {
+	dir := dataDir + "/server/tls"
+	res, err := client.Run(fmt.Sprintf("%s | sort || true", sudo("find").Arg(dir).Literal("-maxdepth", "2", "-type", "f", "-name").Arg("*.crt").Literal("2>/dev/null")))
+	if err != nil {
+		return nil, err
+	}
+
+	expiry := make(map[string]string)
+	for _, p := range strings.Fields(strings.Join(res, "\n")) {
+		content, err := client.ReadFile(p, true, true)
+		if err != nil {
+			return nil, err
+		}
+		notAfter, err := certificateNotAfter(content)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %s", p, err.Error())
+		}
+		expiry[strings.TrimPrefix(p, dir+"/")] = notAfter.UTC().Format(time.RFC3339)
+	}
+
+	return expiry, nil
+}
+
+// Expiry of the first certificate in a PEM bundle, which is the leaf.
+func certificateNotAfter(content string) (time.Time, error) {
+	cert, err := firstCertificate(content)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+func firstCertificate(content string) (*x509.Certificate, error) {
+	rest := []byte(content)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM encoded certificate found")
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		return x509.ParseCertificate(block.Bytes)
+	}
+}
+
+// Reads the certificate expiry. Must run after refreshConfigFragments.
+func (s *Server) refreshCertificateExpiry(client ssh_client.SSHClient) error {
+	config, err := parseMergedConfig(s.MergedConfig)
+	if err != nil {
+		return err
+	}
+
+	expiry, err := readCertificateExpiry(client, path.Clean(configDataDir(config)))
+	if err != nil {
+		return err
+	}
+	s.CertificateExpiry = expiry
+	return nil
+}
@@ -0,0 +1,51 @@
+package k3s
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateCertificateServices(t *testing.T) {
+	if err := ValidateCertificateServices(nil); err != nil {
+		t.Errorf("ValidateCertificateServices(nil) error = %v", err)
+	}
+	if err := ValidateCertificateServices([]string{"admin", "api-server", "kubelet"}); err != nil {
+		t.Errorf("ValidateCertificateServices() error = %v", err)
+	}
+	if err := ValidateCertificateServices([]string{"admin", "coredns"}); err == nil {
+		t.Error("ValidateCertificateServices() accepted an unknown service")
+	}
+}
+
+func TestCertificateRotateCommands(t *testing.T) {
+	all := certificateRotateCommands("", nil)
+	want := []string{
+		"sudo systemctl stop k3s",
+		"sudo '/usr/local/bin/k3s' certificate rotate; status=$?; sudo systemctl --no-block start k3s; exit $status",
+	}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("certificateRotateCommands() = %q, want %q", all, want)
+	}
+
+	selected := certificateRotateCommands("/opt/bin", []string{"admin", "api-server"})
+	if got, want := selected[1], "sudo '/opt/bin/k3s' certificate rotate --service 'admin' --service 'api-server'; status=$?; sudo systemctl --no-block start k3s; exit $status"; got != want {
+		t.Errorf("certificateRotateCommands() rotate = %q, want %q", got, want)
+	}
+}
+
+func TestCertificateNotAfter(t *testing.T) {
+	cert, key := testCertificate(t)
+
+	notAfter, err := certificateNotAfter(key + cert)
+	if err != nil {
+		t.Fatalf("certificateNotAfter() error = %v", err)
+	}
+	if until := time.Until(notAfter); until <= 0 || until > time.Hour {
+		t.Errorf("certificateNotAfter() = %s, want within the next hour", notAfter)
+	}
+
+	if _, err := certificateNotAfter(key); err == nil {
+		t.Error("certificateNotAfter() accepted a bundle without certificates")
+	}
+}
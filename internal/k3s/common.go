@@ -24,6 +24,25 @@ type K3sComponent interface {
 	Refresh(context.Context, ssh_client.SSHClient) (bool, bool, error)
 }
 
+// Data dir set in a parsed config, DATA_DIR when unset.
+func configDataDir(config map[any]any) string {
+	if dir, ok := config["data-dir"].(string); ok && dir != "" {
+		return dir
+	}
+	return DATA_DIR
+}
+
+// Boolean config flag, which may also be written as a string.
+func configBool(config map[any]any, key string) bool {
+	switch value := config[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
 // Commands for configuring server/agent config.
 func configCommands(ctx context.Context, config map[any]any) ([]string, error) {
 	tflog.Debug(ctx, "Reading config path")
@@ -33,12 +52,33 @@ func configCommands(ctx context.Context, config map[any]any) ([]string, error) {
 		return []string{}, err
 	}
 
-	return []string{
-		// Write config file
-		fmt.Sprintf("echo %q | sudo tee %s.tmp > /dev/null", base64.StdEncoding.EncodeToString(configContents), CONFIG_DIR),
-		fmt.Sprintf("sudo base64 -d %s.tmp | sudo tee %s > /dev/null", CONFIG_DIR, configPath),
-		fmt.Sprintf("sudo rm %s.tmp", CONFIG_DIR),
-	}, nil
+	return WriteFileCommands(configPath, base64.StdEncoding.EncodeToString(configContents)), nil
+}
+
+// Merges typed values into the parsed raw config. A key that is set in both
+// places, including its appending "+" form, is reported as a conflict with
+// the attribute or block that label names for the key.
+func mergeConfigValues(config map[any]any, values map[string]any, label func(key string) string) error {
+	for _, key := range sortedKeys(values) {
+		for _, existing := range []string{key, key + "+"} {
+			if _, ok := config[existing]; ok {
+				return fmt.Errorf("%q is set in config and by %s, set it in only one place", existing, label(key))
+			}
+		}
+		config[key] = values[key]
+	}
+
+	return nil
+}
+
+// Label of the typed attribute rendering a flag.
+func flagAttribute(key string) string {
+	return fmt.Sprintf("the %s attribute", strings.ReplaceAll(key, "-", "_"))
+}
+
+// Label of a typed block rendering all of its keys.
+func blockLabel(block string) func(string) string {
+	return func(string) string { return block }
 }
 
 // Commands for configuring server/agent registry.
@@ -55,19 +95,15 @@ func registryCommands(ctx context.Context, registry map[any]any) (commands []str
 	}
 
 	if len(registryContents) != 0 {
-		commands = []string{
-			// Write registries file
-			fmt.Sprintf("echo %q | sudo tee %s.tmp > /dev/null", base64.StdEncoding.EncodeToString(registryContents), CONFIG_DIR),
-			fmt.Sprintf("sudo base64 -d %s.tmp | sudo tee %s > /dev/null", CONFIG_DIR, registryPath),
-			fmt.Sprintf("sudo rm %s.tmp", CONFIG_DIR),
-		}
+		// Write registries file
+		commands = WriteFileCommands(registryPath, base64.StdEncoding.EncodeToString(registryContents))
 	}
 
 	return commands, err
 }
 
 func k3sSystemdServiceExists(client ssh_client.SSHClient, serviceName string) (bool, error) {
-	res, err := client.Run(fmt.Sprintf("sudo test -f /etc/systemd/system/%s.service && echo present || echo missing", serviceName))
+	res, err := client.Run(fmt.Sprintf("%s && echo present || echo missing", sudo("test", "-f").Arg(fmt.Sprintf("/etc/systemd/system/%s.service", serviceName))))
 	if err != nil {
 		return false, err
 	}
@@ -79,7 +115,7 @@ func k3sSystemdServiceExists(client ssh_client.SSHClient, serviceName string) (b
 }
 
 func k3sSystemdServiceActive(client ssh_client.SSHClient, serviceName string) (bool, error) {
-	res, err := client.Run(fmt.Sprintf("sudo systemctl is-active --quiet %s && echo active || echo inactive", serviceName))
+	res, err := client.Run(fmt.Sprintf("%s && echo active || echo inactive", sudo("systemctl", "is-active", "--quiet").Arg(serviceName)))
 	if err != nil {
 		return false, err
 	}
@@ -91,7 +127,7 @@ func k3sSystemdServiceActive(client ssh_client.SSHClient, serviceName string) (b
 }
 
 func restartFailedK3sSystemdService(client ssh_client.SSHClient, serviceName string) error {
-	res, err := client.Run(fmt.Sprintf("sudo systemctl is-failed --quiet %[1]s && echo failed || echo not-failed", serviceName))
+	res, err := client.Run(fmt.Sprintf("%s && echo failed || echo not-failed", sudo("systemctl", "is-failed", "--quiet").Arg(serviceName)))
 	if err != nil {
 		return err
 	}
@@ -102,7 +138,7 @@ func restartFailedK3sSystemdService(client ssh_client.SSHClient, serviceName str
 		return nil
 	}
 
-	_, err = client.Run(fmt.Sprintf("sudo systemctl reset-failed %[1]s && sudo systemctl --no-block start %[1]s", serviceName))
+	_, err = client.Run(fmt.Sprintf("%s && %s", sudo("systemctl", "reset-failed").Arg(serviceName), sudo("systemctl", "--no-block", "start").Arg(serviceName)))
 	return err
 }
 
@@ -125,7 +161,7 @@ func waitForK3sSystemdServiceActive(client ssh_client.SSHClient, serviceName str
 		time.Sleep(5 * time.Second)
 	}
 
-	journal, err := client.Run(fmt.Sprintf("sudo journalctl -u %s --no-pager -n 120 || true", serviceName))
+	journal, err := client.Run(fmt.Sprintf("%s || true", sudo("journalctl", "-u").Arg(serviceName).Literal("--no-pager", "-n", "120")))
 	if err == nil && len(journal) == 1 && strings.TrimSpace(journal[0]) != "" {
 		return fmt.Errorf("%s service did not become active within %s; recent journal:\n%s", serviceName, timeout, journal[0])
 	}
@@ -141,7 +177,7 @@ func k3sBinaryVersion(client ssh_client.SSHClient, binDir string) (string, error
 		binDir = BIN_DIR
 	}
 
-	res, err := client.Run(fmt.Sprintf("sudo %s/k3s -v", binDir))
+	res, err := client.Run(sudo().Arg(binDir + "/k3s").Literal("-v").String())
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,112 @@
+package k3s
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"text/template/parse"
+
+	"github.com/BurntSushi/toml"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Stands in for template actions while the surrounding TOML is checked.
+const templateActionMarker = "\x00"
+
+// ValidateContainerdConfigTemplate checks the Go template syntax of a
+// containerd config template and that the TOML around its actions parses.
+// Template functions are not checked since k3s provides its own.
+func ValidateContainerdConfigTemplate(content string) error {
+	tree := parse.New("config.toml.tmpl")
+	tree.Mode = parse.SkipFuncCheck
+	if _, err := tree.Parse(content, "", "", map[string]*parse.Tree{}); err != nil {
+		return fmt.Errorf("parsing containerd config template: %s", err.Error())
+	}
+
+	var b strings.Builder
+	if tree.Root != nil {
+		stripTemplateActions(tree.Root.Nodes, &b)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(strings.ReplaceAll(line, templateActionMarker, "")) == "" {
+			// Actions on a line of their own, such as the base template
+			lines[i] = ""
+			continue
+		}
+		lines[i] = strings.ReplaceAll(line, templateActionMarker, "0")
+	}
+
+	var decoded map[string]any
+	if _, err := toml.Decode(strings.Join(lines, "\n"), &decoded); err != nil {
+		return fmt.Errorf("parsing containerd config template as TOML: %s", err.Error())
+	}
+
+	return nil
+}
+
+// Writes the text of a template with every action replaced by a marker.
+// Only the first branch of conditionals and loops is kept so tables are
+// not declared twice.
+func stripTemplateActions(nodes []parse.Node, b *strings.Builder) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			b.Write(n.Text)
+		case *parse.IfNode:
+			stripTemplateActions(n.List.Nodes, b)
+		case *parse.RangeNode:
+			stripTemplateActions(n.List.Nodes, b)
+		case *parse.WithNode:
+			stripTemplateActions(n.List.Nodes, b)
+		case *parse.ListNode:
+			stripTemplateActions(n.Nodes, b)
+		case *parse.CommentNode:
+		default:
+			b.WriteString(templateActionMarker)
+		}
+	}
+}
+
+func containerdConfigTemplatePath(dataDir string) string {
+	return fmt.Sprintf("%s/agent/etc/containerd/config.toml.tmpl", dataDir)
+}
+
+// Commands for writing the containerd config template, or removing it when
+// it is no longer declared so k3s goes back to its generated config.
+func containerdConfigTemplateCommands(dataDir string, content string, remove bool) []string {
+	path := containerdConfigTemplatePath(dataDir)
+	if content == "" {
+		if remove {
+			return []string{sudo("rm", "-f").Arg(path).String()}
+		}
+		return nil
+	}
+
+	return append(
+		[]string{sudo("mkdir", "-p").Arg(dataDir + "/agent/etc/containerd").String()},
+		WriteFileCommands(path, base64.StdEncoding.EncodeToString([]byte(content)))...,
+	)
+}
+
+// Reads back the containerd config template from the data dir k3s actually
+// uses. A missing template reads as empty so drift shows up in the plan.
+func refreshContainerdConfigTemplate(client ssh_client.SSHClient, merged string, content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+
+	config, err := parseMergedConfig(merged)
+	if err != nil {
+		return "", err
+	}
+	path := containerdConfigTemplatePath(configDataDir(config))
+
+	exists, err := remoteFileExists(client, path)
+	if err != nil || !exists {
+		return "", err
+	}
+	return client.ReadFile(path, false, true)
+}
@@ -0,0 +1,70 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateContainerdConfigTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+	}{
+		{
+			name: "base template with extra runtime",
+			content: `{{ template "base" . }}
+
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  discard_unpacked_layers = true
+
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes."crun"]
+  runtime_type = "io.containerd.runc.v2"
+`,
+		},
+		{
+			name: "actions in values and conditionals",
+			content: `version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "{{ .NodeConfig.AgentConfig.PauseImage }}"
+{{ if .NodeConfig.AgentConfig.Snapshotter }}
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  snapshotter = "{{ .NodeConfig.AgentConfig.Snapshotter }}"
+{{ else }}
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  snapshotter = "overlayfs"
+{{ end }}
+  disable_snapshot_annotations = {{ printf "%t" true }}
+`,
+		},
+		{name: "unclosed action", content: "version = 2\n{{ if .X }}\n", expectError: true},
+		{name: "invalid toml", content: "{{ template \"base\" . }}\n[plugins\n", expectError: true},
+		{name: "duplicate key", content: "version = 2\nversion = 3\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateContainerdConfigTemplate(tt.content)
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestContainerdConfigTemplateCommands(t *testing.T) {
+	if commands := containerdConfigTemplateCommands(DATA_DIR, "", false); len(commands) != 0 {
+		t.Fatalf("containerdConfigTemplateCommands() = %v, want none", commands)
+	}
+	if got, want := containerdConfigTemplateCommands(DATA_DIR, "", true), "sudo rm -f '/var/lib/rancher/k3s/agent/etc/containerd/config.toml.tmpl'"; len(got) != 1 || got[0] != want {
+		t.Errorf("containerdConfigTemplateCommands() = %v, want %q", got, want)
+	}
+
+	joined := strings.Join(containerdConfigTemplateCommands("/data/k3s", "version = 2\n", false), "\n")
+	if !strings.Contains(joined, "'/data/k3s/agent/etc/containerd/config.toml.tmpl'") {
+		t.Errorf("containerdConfigTemplateCommands() = %q, want the template path", joined)
+	}
+}
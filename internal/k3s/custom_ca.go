@@ -0,0 +1,293 @@
+package k3s
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Validity of the generated k3s CAs, capped at the expiry of the signer.
+const customCAValidity = 10 * 365 * 24 * time.Hour
+
+// The CAs k3s expects under <data-dir>/server/tls, without extension.
+var customCANames = []string{
+	"client-ca",
+	"request-header-ca",
+	"server-ca",
+	"etcd/peer-ca",
+	"etcd/server-ca",
+}
+
+// CustomCA is the organisation's root, and optionally intermediate, CA the
+// k3s cluster CAs are issued from. The k3s CAs are generated by the
+// provider, so only the key of the signing CA is needed.
+type CustomCA struct {
+	RootCert string
+	// Only needed when there is no intermediate
+	RootKey          string
+	IntermediateCert string
+	IntermediateKey  string
+}
+
+func (c *CustomCA) Validate() error {
+	_, _, err := c.signer()
+	return err
+}
+
+// Certificate and key the k3s CAs are signed with, the intermediate when
+// one is set.
+func (c *CustomCA) signer() (*x509.Certificate, crypto.Signer, error) {
+	root, err := parseCACertificate("root_cert", c.RootCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.IntermediateCert == "" {
+		if c.IntermediateKey != "" {
+			return nil, nil, fmt.Errorf("custom_ca intermediate_key requires intermediate_cert")
+		}
+		if c.RootKey == "" {
+			return nil, nil, fmt.Errorf("custom_ca needs root_key, or intermediate_cert and intermediate_key")
+		}
+		key, err := parseCAKey("root_key", c.RootKey, root)
+		if err != nil {
+			return nil, nil, err
+		}
+		return root, key, nil
+	}
+
+	intermediate, err := parseCACertificate("intermediate_cert", c.IntermediateCert)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := intermediate.CheckSignatureFrom(root); err != nil {
+		return nil, nil, fmt.Errorf("custom_ca intermediate_cert is not signed by root_cert: %s", err.Error())
+	}
+	if c.IntermediateKey == "" {
+		return nil, nil, fmt.Errorf("custom_ca intermediate_cert requires intermediate_key")
+	}
+	key, err := parseCAKey("intermediate_key", c.IntermediateKey, intermediate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return intermediate, key, nil
+}
+
+func parseCACertificate(name string, content string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(content))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("custom_ca %s must be a PEM encoded certificate", name)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("custom_ca %s: %s", name, err.Error())
+	}
+	if !cert.IsCA {
+		return nil, fmt.Errorf("custom_ca %s is not a CA certificate", name)
+	}
+	return cert, nil
+}
+
+// Parses a PEM encoded key and checks that it belongs to cert.
+func parseCAKey(name string, content string, cert *x509.Certificate) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(content))
+	if block == nil {
+		return nil, fmt.Errorf("custom_ca %s must be a PEM encoded private key", name)
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("custom_ca %s has unsupported PEM type %q", name, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("custom_ca %s: %s", name, err.Error())
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("custom_ca %s cannot sign certificates", name)
+	}
+	public, ok := signer.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(cert.PublicKey) {
+		return nil, fmt.Errorf("custom_ca %s does not match its certificate", name)
+	}
+	return signer, nil
+}
+
+// Generates the k3s CAs and service account key, keyed by path relative to
+// the tls directory. Every CA certificate file holds the full chain.
+func (c *CustomCA) files(now time.Time) (map[string]ExtraFile, error) {
+	signerCert, signerKey, err := c.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := strings.TrimSpace(c.RootCert) + "\n"
+	if c.IntermediateCert != "" {
+		chain = strings.TrimSpace(c.IntermediateCert) + "\n" + chain
+	}
+
+	files := map[string]ExtraFile{
+		"root-ca.pem": {Content: c.RootCert, Mode: "0644"},
+	}
+	if c.IntermediateCert != "" {
+		files["intermediate-ca.pem"] = ExtraFile{Content: c.IntermediateCert, Mode: "0644"}
+	}
+
+	notAfter := now.Add(customCAValidity)
+	if signerCert.NotAfter.Before(notAfter) {
+		notAfter = signerCert.NotAfter
+	}
+	for _, name := range customCANames {
+		cert, key, err := issueCA(name, signerCert, signerKey, now, notAfter)
+		if err != nil {
+			return nil, fmt.Errorf("generating %s: %s", name, err.Error())
+		}
+		files[name+".crt"] = ExtraFile{Content: cert + chain, Mode: "0644"}
+		files[name+".key"] = ExtraFile{Content: key, Mode: "0600"}
+	}
+
+	serviceKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating service.key: %s", err.Error())
+	}
+	files["service.key"] = ExtraFile{
+		Content: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(serviceKey)})),
+		Mode:    "0600",
+	}
+
+	return files, nil
+}
+
+// Issues one k3s CA, returning the PEM encoded certificate and key.
+func issueCA(name string, signerCert *x509.Certificate, signerKey crypto.Signer, now time.Time, notAfter time.Time) (string, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("k3s-%s@%d", strings.ReplaceAll(name, "/", "-"), now.Unix())},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		return "", "", err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})), nil
+}
+
+// Commands for writing files relative to dir.
+func customCACommands(dir string, files map[string]ExtraFile) []string {
+	absolute := make(map[string]ExtraFile, len(files))
+	for name, file := range files {
+		absolute[dir+"/"+name] = file
+	}
+	return extraFileCommands(absolute, nil)
+}
+
+func (s *Server) tlsDir() string {
+	return s.dataDir() + "/server/tls"
+}
+
+// Commands for placing the custom CA before the first start. A server
+// that already has CAs is refused, its CA can only be changed by RotateCA.
+func (s *Server) customCAPreInstallCommands(ctx context.Context, client ssh_client.SSHClient) ([]string, error) {
+	if s.CustomCA == nil {
+		return nil, nil
+	}
+
+	exists, err := remoteFileExists(client, s.tlsDir()+"/server-ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%s/server-ca.crt already exists, custom_ca can only be placed before k3s first starts", s.tlsDir())
+	}
+
+	files, err := s.CustomCA.files(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	tflog.Debug(ctx, fmt.Sprintf("Placing custom CA in %s", s.tlsDir()))
+
+	return customCACommands(s.tlsDir(), files), nil
+}
+
+// RotateCA hands a new set of CAs to `k3s certificate rotate-ca`. The
+// current service account key is kept so existing tokens stay valid. Force
+// is needed when the new CAs are not cross-signed by the old ones. The
+// service must be restarted afterwards.
+func (s *Server) RotateCA(ctx context.Context, client ssh_client.SSHClient, ca *CustomCA, force bool) error {
+	if err := client.WaitForReady(); err != nil {
+		return err
+	}
+
+	files, err := ca.files(time.Now())
+	if err != nil {
+		return err
+	}
+	delete(files, "service.key")
+
+	tflog.Info(ctx, "Rotating k3s cluster CA")
+	if err := client.RunStream(rotateCACommands(s.BinDir, s.dataDir(), files, force)); err != nil {
+		return fmt.Errorf("rotating custom CA: %s", err.Error())
+	}
+	return nil
+}
+
+func rotateCACommands(binDir string, dataDir string, files map[string]ExtraFile, force bool) []string {
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+	dir := dataDir + "/server/rotate-ca"
+
+	rotate := sudo().Arg(binDir+"/k3s").Literal("certificate", "rotate-ca", "--path").Arg(dir)
+	if force {
+		rotate.Literal("--force")
+	}
+
+	commands := []string{sudo("rm", "-rf").Arg(dir).String()}
+	commands = append(commands, customCACommands(dir, files)...)
+	return append(commands,
+		sudo("cp", "-a").Arg(dataDir+"/server/tls/service.key", dir+"/service.key").String(),
+		fmt.Sprintf("%s; status=$?; %s; exit $status", rotate, sudo("rm", "-rf").Arg(dir)),
+	)
+}
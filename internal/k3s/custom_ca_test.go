@@ -0,0 +1,147 @@
+package k3s
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Issues a CA certificate, self-signed when parent is nil.
+func testCA(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+
+	return cert, key,
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestCustomCAValidate(t *testing.T) {
+	root, rootKey, rootPEM, rootKeyPEM := testCA(t, "root", nil, nil)
+	_, _, intermediatePEM, intermediateKeyPEM := testCA(t, "intermediate", root, rootKey)
+	_, _, otherPEM, otherKeyPEM := testCA(t, "other", nil, nil)
+	leaf, _ := testCertificate(t)
+
+	valid := []CustomCA{
+		{RootCert: rootPEM, RootKey: rootKeyPEM},
+		{RootCert: rootPEM, IntermediateCert: intermediatePEM, IntermediateKey: intermediateKeyPEM},
+	}
+	for _, ca := range valid {
+		if err := ca.Validate(); err != nil {
+			t.Errorf("Validate() error = %v", err)
+		}
+	}
+
+	invalid := map[string]CustomCA{
+		"no signer key":        {RootCert: rootPEM},
+		"not a ca":             {RootCert: leaf, RootKey: rootKeyPEM},
+		"mismatched key":       {RootCert: rootPEM, RootKey: otherKeyPEM},
+		"missing intermediate": {RootCert: rootPEM, IntermediateKey: intermediateKeyPEM},
+		"foreign intermediate": {RootCert: rootPEM, IntermediateCert: otherPEM, IntermediateKey: otherKeyPEM},
+		"no intermediate key":  {RootCert: rootPEM, RootKey: rootKeyPEM, IntermediateCert: intermediatePEM},
+	}
+	for name, ca := range invalid {
+		if err := ca.Validate(); err == nil {
+			t.Errorf("%s: Validate() accepted an invalid custom CA", name)
+		}
+	}
+}
+
+func TestCustomCAFiles(t *testing.T) {
+	root, rootKey, rootPEM, _ := testCA(t, "root", nil, nil)
+	intermediate, _, intermediatePEM, intermediateKeyPEM := testCA(t, "intermediate", root, rootKey)
+	ca := CustomCA{RootCert: rootPEM, IntermediateCert: intermediatePEM, IntermediateKey: intermediateKeyPEM}
+
+	files, err := ca.files(time.Now())
+	if err != nil {
+		t.Fatalf("files() error = %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	intermediates.AddCert(intermediate)
+	for _, name := range customCANames {
+		certFile, ok := files[name+".crt"]
+		if !ok {
+			t.Fatalf("files() missing %s.crt", name)
+		}
+		if key := files[name+".key"]; key.Mode != "0600" || !strings.Contains(key.Content, "PRIVATE KEY") {
+			t.Errorf("files() %s.key = %+v", name, key)
+		}
+		if !strings.HasSuffix(certFile.Content, strings.TrimSpace(intermediatePEM)+"\n"+strings.TrimSpace(rootPEM)+"\n") {
+			t.Errorf("files() %s.crt does not end with the chain", name)
+		}
+
+		block, _ := pem.Decode([]byte(certFile.Content))
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("parsing %s.crt: %v", name, err)
+		}
+		if !cert.IsCA || cert.NotAfter.After(intermediate.NotAfter) {
+			t.Errorf("%s.crt is not a CA within the intermediate's validity", name)
+		}
+		if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			t.Errorf("%s.crt does not chain to the root: %v", name, err)
+		}
+	}
+	if files["service.key"].Mode != "0600" {
+		t.Errorf("files() service.key mode = %q", files["service.key"].Mode)
+	}
+	if _, ok := files["intermediate-ca.key"]; ok {
+		t.Error("files() uploads the intermediate key")
+	}
+}
+
+func TestRotateCACommands(t *testing.T) {
+	files := map[string]ExtraFile{"server-ca.crt": {Content: "cert", Mode: "0644"}}
+	got := rotateCACommands("", DATA_DIR, files, true)
+
+	if got[0] != "sudo rm -rf '/var/lib/rancher/k3s/server/rotate-ca'" {
+		t.Errorf("rotateCACommands()[0] = %q", got[0])
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{
+		"'/var/lib/rancher/k3s/server/rotate-ca/server-ca.crt'",
+		"sudo cp -a '/var/lib/rancher/k3s/server/tls/service.key' '/var/lib/rancher/k3s/server/rotate-ca/service.key'",
+		"sudo '/usr/local/bin/k3s' certificate rotate-ca --path '/var/lib/rancher/k3s/server/rotate-ca' --force",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("rotateCACommands() missing %q in\n%s", want, joined)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Default time a drain may take before the uninstall is aborted.
+const DRAIN_TIMEOUT = 5 * time.Minute
+
+var drainPollInterval = 5 * time.Second
+
+// NodeDrain removes a node from the cluster before k3s is uninstalled: the
+// node is cordoned, its pods are evicted and the Node object is deleted
+// through the API of another server.
+type NodeDrain struct {
+	ClusterAuth *ClusterAuth
+	// How long evicting the pods may take, DRAIN_TIMEOUT when zero
+	Timeout time.Duration
+	// Seconds each pod gets to terminate, the pod's own setting when negative
+	GracePeriod int64
+	// Leave pods of daemon sets running instead of refusing to drain
+	IgnoreDaemonSets bool
+
+	client *kubeClient
+}
+
+func (d *NodeDrain) Validate() error {
+	if d.ClusterAuth == nil || d.ClusterAuth.Server == "" {
+		return fmt.Errorf("cluster_auth of another server is required to drain the node")
+	}
+	if d.Timeout < 0 {
+		return fmt.Errorf("drain timeout cannot be negative")
+	}
+	return nil
+}
+
+func (d *NodeDrain) timeout() time.Duration {
+	if d.Timeout == 0 {
+		return DRAIN_TIMEOUT
+	}
+	return d.Timeout
+}
+
+func (d *NodeDrain) kube() (*kubeClient, error) {
+	if d.client != nil {
+		return d.client, nil
+	}
+	client, err := newKubeClientForConfig(d.ClusterAuth.restConfig())
+	if err != nil {
+		return nil, err
+	}
+	d.client = client
+	return client, nil
+}
+
+// RemoveHost drains and deletes the node running on the ssh host.
+func (d *NodeDrain) RemoveHost(ctx context.Context, client ssh_client.SSHClient) error {
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return err
+	}
+	node, err := nodeName(client, config)
+	if err != nil {
+		return err
+	}
+	return d.RemoveNode(ctx, node)
+}
+
+// RemoveNode cordons the node, evicts its pods while respecting
+// PodDisruptionBudgets, and deletes the Node object. A node that is already
+// gone is not an error.
+func (d *NodeDrain) RemoveNode(ctx context.Context, node string) error {
+	found, err := d.evacuate(ctx, node)
+	if err != nil || !found {
+		return err
+	}
+	return d.deleteNode(ctx, node)
+}
+
+// Cordons the node and evicts its pods. Reports whether the node exists.
+func (d *NodeDrain) evacuate(ctx context.Context, node string) (bool, error) {
+	client, err := d.kube()
+	if err != nil {
+		return false, err
+	}
+
+	current, err := client.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		tflog.Debug(ctx, fmt.Sprintf("Node %s is already absent", node))
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading node %s: %s", node, err.Error())
+	}
+
+	if !current.Spec.Unschedulable {
+		tflog.Info(ctx, fmt.Sprintf("Cordoning node %s", node))
+		patch := []byte(`{"spec":{"unschedulable":true}}`)
+		if _, err := client.core.CoreV1().Nodes().Patch(ctx, node, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return false, fmt.Errorf("cordoning node %s: %s", node, err.Error())
+		}
+	}
+
+	return true, d.drain(ctx, client, node)
+}
+
+func (d *NodeDrain) deleteNode(ctx context.Context, node string) error {
+	client, err := d.kube()
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Deleting node %s", node))
+	if err := client.core.CoreV1().Nodes().Delete(ctx, node, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("deleting node %s: %s", node, err.Error())
+	}
+	return nil
+}
+
+func (d *NodeDrain) drain(ctx context.Context, client *kubeClient, node string) error {
+	list, err := client.core.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("listing pods on node %s: %s", node, err.Error())
+	}
+	pods, err := drainablePods(list.Items, node, d.IgnoreDaemonSets)
+	if err != nil {
+		return err
+	}
+
+	timeout := d.timeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tflog.Info(ctx, fmt.Sprintf("Evicting %d pods from node %s", len(pods), node))
+	for _, pod := range pods {
+		if err := d.evict(ctx, client, pod); err != nil {
+			return err
+		}
+	}
+
+	var remaining []string
+	err = wait.PollUntilContextCancel(ctx, drainPollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := remainingPods(ctx, client, pods)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			return false, err
+		}
+		remaining = current
+		return len(remaining) == 0, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("pods on node %s did not terminate within %s: %s", node, timeout, strings.Join(remaining, ", "))
+	}
+	return err
+}
+
+// Evicts the pod, retrying while a PodDisruptionBudget blocks it until ctx
+// is done.
+func (d *NodeDrain) evict(ctx context.Context, client *kubeClient, pod corev1.Pod) error {
+	eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+	if d.GracePeriod >= 0 {
+		grace := d.GracePeriod
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: &grace}
+	}
+
+	var blocked error
+	err := wait.PollUntilContextCancel(ctx, drainPollInterval, true, func(ctx context.Context) (bool, error) {
+		err := client.core.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			blocked = err
+			tflog.Debug(ctx, fmt.Sprintf("Eviction of pod %s/%s blocked, retrying: %s", pod.Namespace, pod.Name, err.Error()))
+			return false, nil
+		case ctx.Err() != nil:
+			return false, ctx.Err()
+		default:
+			return false, fmt.Errorf("evicting pod %s/%s: %s", pod.Namespace, pod.Name, err.Error())
+		}
+	})
+	if wait.Interrupted(err) && blocked != nil {
+		return fmt.Errorf("evicting pod %s/%s: blocked by a PodDisruptionBudget until the drain timed out: %s", pod.Namespace, pod.Name, blocked.Error())
+	}
+	if wait.Interrupted(err) {
+		return fmt.Errorf("evicting pod %s/%s: drain timed out", pod.Namespace, pod.Name)
+	}
+	return err
+}
+
+// Pods that still exist, as namespace/name.
+func remainingPods(ctx context.Context, client *kubeClient, pods []corev1.Pod) ([]string, error) {
+	var remaining []string
+	for _, pod := range pods {
+		current, err := client.core.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading pod %s/%s: %s", pod.Namespace, pod.Name, err.Error())
+		}
+		// A pod of the same name was recreated elsewhere
+		if current.UID != pod.UID || current.Spec.NodeName != pod.Spec.NodeName {
+			continue
+		}
+		remaining = append(remaining, pod.Namespace+"/"+pod.Name)
+	}
+	return remaining, nil
+}
+
+// Pods on the node that have to be evicted. Mirror pods of static pods
+// cannot be evicted and stop with k3s. Pods of daemon sets would be
+// recreated on the node right away, so they are refused unless ignored, as
+// kubectl drain does.
+func drainablePods(pods []corev1.Pod, node string, ignoreDaemonSets bool) ([]corev1.Pod, error) {
+	var drainable []corev1.Pod
+	var daemonSetPods []string
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node {
+			continue
+		}
+		if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+			continue
+		}
+		if owner := metav1.GetControllerOf(&pod); owner != nil && owner.Kind == "DaemonSet" {
+			if !ignoreDaemonSets {
+				daemonSetPods = append(daemonSetPods, pod.Namespace+"/"+pod.Name)
+			}
+			continue
+		}
+		drainable = append(drainable, pod)
+	}
+	if len(daemonSetPods) > 0 {
+		sort.Strings(daemonSetPods)
+		return nil, fmt.Errorf("node %s runs daemon set pods, set ignore_daemonsets to drain it anyway: %s", node, strings.Join(daemonSetPods, ", "))
+	}
+
+	sort.Slice(drainable, func(i, j int) bool {
+		if drainable[i].Namespace != drainable[j].Namespace {
+			return drainable[i].Namespace < drainable[j].Namespace
+		}
+		return drainable[i].Name < drainable[j].Name
+	})
+	return drainable, nil
+}
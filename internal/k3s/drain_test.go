@@ -0,0 +1,137 @@
+package k3s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func drainTestPod(name string, node string, owner string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: k8stypes.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: node},
+	}
+	switch owner {
+	case "DaemonSet", "ReplicaSet":
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner", Controller: &controller}}
+	case "mirror":
+		pod.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "hash"}
+	}
+	return pod
+}
+
+func TestDrainablePods(t *testing.T) {
+	pods := []corev1.Pod{
+		*drainTestPod("web", "agent-1", "ReplicaSet"),
+		*drainTestPod("api", "agent-1", ""),
+		*drainTestPod("other", "agent-2", "ReplicaSet"),
+		*drainTestPod("static", "agent-1", "mirror"),
+		*drainTestPod("proxy", "agent-1", "DaemonSet"),
+	}
+
+	if _, err := drainablePods(pods, "agent-1", false); err == nil || !strings.Contains(err.Error(), "default/proxy") {
+		t.Fatalf("drainablePods() error = %v, want daemon set pods to be refused", err)
+	}
+
+	drainable, err := drainablePods(pods, "agent-1", true)
+	if err != nil {
+		t.Fatalf("drainablePods() error = %v", err)
+	}
+	var names []string
+	for _, pod := range drainable {
+		names = append(names, pod.Name)
+	}
+	if strings.Join(names, ",") != "api,web" {
+		t.Errorf("drainablePods() = %v, want api,web", names)
+	}
+}
+
+func TestNodeDrainRemoveNode(t *testing.T) {
+	previous := drainPollInterval
+	drainPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainPollInterval = previous })
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "agent-1"}}
+	core := fake.NewClientset(node, drainTestPod("web", "agent-1", "ReplicaSet"), drainTestPod("db", "agent-1", "ReplicaSet"))
+
+	// The first eviction of db is blocked by its PodDisruptionBudget
+	blocked := false
+	core.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		name := action.(k8stesting.CreateAction).GetObject().(metav1.Object).GetName()
+		if name == "db" && !blocked {
+			blocked = true
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 1)
+		}
+		return true, nil, core.Tracker().Delete(schema.GroupVersionResource{Version: "v1", Resource: "pods"}, "default", name)
+	})
+
+	drain := NodeDrain{Timeout: time.Second, GracePeriod: -1, client: &kubeClient{core: core}}
+	if err := drain.RemoveNode(context.Background(), "agent-1"); err != nil {
+		t.Fatalf("RemoveNode() error = %v", err)
+	}
+	if !blocked {
+		t.Errorf("RemoveNode() did not retry the blocked eviction")
+	}
+	if _, err := core.CoreV1().Nodes().Get(context.Background(), "agent-1", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("node still exists after RemoveNode(), err = %v", err)
+	}
+	pods, _ := core.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+	if len(pods.Items) != 0 {
+		t.Errorf("%d pods left after RemoveNode()", len(pods.Items))
+	}
+
+	// Removing it again finds nothing to do
+	if err := drain.RemoveNode(context.Background(), "agent-1"); err != nil {
+		t.Errorf("RemoveNode() error = %v for a missing node", err)
+	}
+}
+
+func TestNodeDrainTimeout(t *testing.T) {
+	previous := drainPollInterval
+	drainPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainPollInterval = previous })
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "agent-1"}}
+	core := fake.NewClientset(node, drainTestPod("db", "agent-1", "ReplicaSet"))
+	core.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewTooManyRequests("disruption budget", 1)
+	})
+
+	drain := NodeDrain{Timeout: 50 * time.Millisecond, GracePeriod: -1, client: &kubeClient{core: core}}
+	err := drain.RemoveNode(context.Background(), "agent-1")
+	if err == nil || !strings.Contains(err.Error(), "PodDisruptionBudget") {
+		t.Fatalf("RemoveNode() error = %v, want a PodDisruptionBudget timeout", err)
+	}
+	current, err := core.CoreV1().Nodes().Get(context.Background(), "agent-1", metav1.GetOptions{})
+	if err != nil || !current.Spec.Unschedulable {
+		t.Errorf("node should stay cordoned after a failed drain, got %v, %v", current, err)
+	}
+	// A cancelled apply stops waiting long before the drain timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	drain = NodeDrain{Timeout: time.Hour, GracePeriod: -1, client: &kubeClient{core: core}}
+	start := time.Now()
+	if err := drain.RemoveNode(ctx, "agent-1"); err == nil {
+		t.Errorf("RemoveNode() error = nil after the context was cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("RemoveNode() took %s after the context was cancelled", elapsed)
+	}
+}
@@ -0,0 +1,168 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// How long a cluster reset may take before it is abandoned.
+const ETCD_RESTORE_TIMEOUT = 10 * time.Minute
+
+// Root-only env file holding the token and S3 credentials during a reset.
+const etcdRestoreEnvPath = CONFIG_DIR + "/etcd-restore.env"
+
+// EtcdRestore restores the embedded etcd datastore of a new server from a
+// snapshot with a cluster reset before the service first starts.
+type EtcdRestore struct {
+	// Path of the snapshot on the node, or its object name when S3 is set
+	Path string
+	S3   *EtcdRestoreS3
+}
+
+// EtcdRestoreS3 is the bucket a snapshot is downloaded from.
+type EtcdRestoreS3 struct {
+	Bucket        string
+	Endpoint      string
+	Region        string
+	Folder        string
+	AccessKey     string
+	SecretKey     string
+	SkipSSLVerify bool
+	Insecure      bool
+}
+
+func (r *EtcdRestore) Validate() error {
+	if r.Path == "" {
+		return fmt.Errorf("restore_from_snapshot path must be set")
+	}
+	if r.S3 == nil {
+		if !path.IsAbs(r.Path) {
+			return fmt.Errorf("restore_from_snapshot path %q must be absolute", r.Path)
+		}
+		return nil
+	}
+
+	if strings.Contains(r.Path, "/") {
+		return fmt.Errorf("restore_from_snapshot path %q must be the snapshot name when restoring from S3, set the folder in s3", r.Path)
+	}
+	if r.S3.Bucket == "" {
+		return fmt.Errorf("restore_from_snapshot s3 bucket must be set")
+	}
+	if (r.S3.AccessKey == "") != (r.S3.SecretKey == "") {
+		return fmt.Errorf("restore_from_snapshot s3 access_key and secret_key must be set together")
+	}
+	return nil
+}
+
+// Source describes the restored snapshot, its path on the node or its
+// s3:// URL.
+func (r *EtcdRestore) Source() string {
+	if r.S3 == nil {
+		return r.Path
+	}
+	return "s3://" + path.Join(r.S3.Bucket, r.S3.Folder, r.Path)
+}
+
+func etcdResetFlagPath(dataDir string) string {
+	return dataDir + "/server/db/reset-flag"
+}
+
+// Command that resets the cluster from the snapshot. It reads the same
+// config as the service and takes its secrets from the env file.
+func (r *EtcdRestore) resetCommand(binDir string) *shellCommand {
+	reset := newCommand("exec", "timeout").
+		Literal(strconv.Itoa(int(ETCD_RESTORE_TIMEOUT.Seconds()))).
+		Arg(binDir+"/k3s").
+		Literal("server", "--config").Arg(CONFIG_DIR+"/config.yaml").
+		Literal("--cluster-reset", "--cluster-reset-restore-path").Arg(r.Path)
+	if r.S3 != nil {
+		reset.Literal("--etcd-s3", "--etcd-s3-bucket").Arg(r.S3.Bucket)
+		if r.S3.Endpoint != "" {
+			reset.Literal("--etcd-s3-endpoint").Arg(r.S3.Endpoint)
+		}
+		if r.S3.Region != "" {
+			reset.Literal("--etcd-s3-region").Arg(r.S3.Region)
+		}
+		if r.S3.Folder != "" {
+			reset.Literal("--etcd-s3-folder").Arg(r.S3.Folder)
+		}
+		if r.S3.SkipSSLVerify {
+			reset.Literal("--etcd-s3-skip-ssl-verify")
+		}
+		if r.S3.Insecure {
+			reset.Literal("--etcd-s3-insecure")
+		}
+	}
+
+	script := fmt.Sprintf("set -a; . %s; set +a; %s", shellQuote(etcdRestoreEnvPath), reset)
+	return sudo("sh", "-c").Arg(script)
+}
+
+// Contents of the env file. k3s reads the S3 credentials from the AWS
+// variables so they never show up on a command line.
+func (r *EtcdRestore) envFile(token string, env map[string]string) string {
+	values := make(map[string]string, len(env)+3)
+	for k, v := range env {
+		values[k] = v
+	}
+	if token != "" {
+		values["K3S_TOKEN"] = token
+	}
+	if r.S3 != nil && r.S3.AccessKey != "" {
+		values["AWS_ACCESS_KEY_ID"] = r.S3.AccessKey
+		values["AWS_SECRET_ACCESS_KEY"] = r.S3.SecretKey
+	}
+
+	var b strings.Builder
+	for _, k := range sortedKeys(values) {
+		fmt.Fprintf(&b, "%s=%s\n", k, shellQuote(values[k]))
+	}
+	return b.String()
+}
+
+// Runs the cluster reset and checks that k3s left its reset marker behind.
+// The service is started normally afterwards.
+func (r *EtcdRestore) reset(ctx context.Context, client ssh_client.SSHClient, binDir string, dataDir string, token string, env map[string]string) error {
+	if r.S3 == nil {
+		exists, err := remoteFileExists(client, r.Path)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("etcd snapshot %s not found on %s", r.Path, client.Host())
+		}
+	}
+
+	envFile := map[string]ExtraFile{etcdRestoreEnvPath: {Content: r.envFile(token, env), Mode: "0600"}}
+	if err := client.RunStream(extraFileCommands(envFile, nil)); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Restoring etcd from %s", r.Source()))
+	output, resetErr := runCaptured(client, r.resetCommand(binDir))
+	if _, err := client.Run(sudo("rm", "-f").Arg(etcdRestoreEnvPath).String()); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not remove %s: %s", etcdRestoreEnvPath, err.Error()))
+	}
+
+	// k3s may keep running after the reset on some versions, so the marker
+	// decides whether it succeeded rather than the exit status
+	reset, err := remoteFileExists(client, etcdResetFlagPath(dataDir))
+	if err != nil {
+		return err
+	}
+	if !reset {
+		if resetErr != nil {
+			return fmt.Errorf("restoring etcd from %s: %s", r.Source(), resetErr.Error())
+		}
+		return fmt.Errorf("restoring etcd from %s: reset marker %s missing: %s", r.Source(), etcdResetFlagPath(dataDir), strings.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEtcdRestoreValidate(t *testing.T) {
+	valid := []EtcdRestore{
+		{Path: "/var/lib/rancher/k3s/server/db/snapshots/nightly-server-1-1714550400"},
+		{Path: "nightly-server-1-1714550400", S3: &EtcdRestoreS3{Bucket: "backups", Folder: "cluster"}},
+		{Path: "nightly-server-1-1714550400", S3: &EtcdRestoreS3{Bucket: "backups", AccessKey: "AKIA", SecretKey: "secret"}},
+	}
+	for _, restore := range valid {
+		if err := restore.Validate(); err != nil {
+			t.Errorf("Validate(%+v) error = %v", restore, err)
+		}
+	}
+
+	invalid := []EtcdRestore{
+		{},
+		{Path: "snapshots/nightly"},
+		{Path: "cluster/nightly", S3: &EtcdRestoreS3{Bucket: "backups"}},
+		{Path: "nightly", S3: &EtcdRestoreS3{}},
+		{Path: "nightly", S3: &EtcdRestoreS3{Bucket: "backups", AccessKey: "AKIA"}},
+	}
+	for _, restore := range invalid {
+		if err := restore.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid restore", restore)
+		}
+	}
+}
+
+func TestEtcdRestoreSource(t *testing.T) {
+	local := EtcdRestore{Path: "/backups/nightly"}
+	if got := local.Source(); got != "/backups/nightly" {
+		t.Errorf("Source() = %q", got)
+	}
+	s3 := EtcdRestore{Path: "nightly", S3: &EtcdRestoreS3{Bucket: "backups", Folder: "cluster"}}
+	if got := s3.Source(); got != "s3://backups/cluster/nightly" {
+		t.Errorf("Source() = %q", got)
+	}
+}
+
+func TestEtcdRestoreResetCommand(t *testing.T) {
+	restore := EtcdRestore{
+		Path: "nightly",
+		S3:   &EtcdRestoreS3{Bucket: "backups", Region: "us-east-1", AccessKey: "AKIA", SecretKey: "secret", SkipSSLVerify: true},
+	}
+	got := restore.resetCommand("/usr/local/bin").String()
+	for _, want := range []string{
+		"sudo sh -c ",
+		"/etc/rancher/k3s/etcd-restore.env",
+		"--cluster-reset --cluster-reset-restore-path",
+		"--etcd-s3 --etcd-s3-bucket",
+		"--etcd-s3-region",
+		"--etcd-s3-skip-ssl-verify",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("resetCommand() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "secret") || strings.Contains(got, "AKIA") {
+		t.Errorf("resetCommand() = %q, leaks S3 credentials", got)
+	}
+
+	env := restore.envFile("K10abc::server:token", map[string]string{"HTTP_PROXY": "http://proxy:3128"})
+	want := "AWS_ACCESS_KEY_ID='AKIA'\nAWS_SECRET_ACCESS_KEY='secret'\nHTTP_PROXY='http://proxy:3128'\nK3S_TOKEN='K10abc::server:token'\n"
+	if env != want {
+		t.Errorf("envFile() = %q, want %q", env, want)
+	}
+}
@@ -0,0 +1,262 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Printed after a captured command so its exit status survives 2>&1.
+const exitStatusMarker = "k3s-provider-exit-status="
+
+var (
+	etcdSnapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+	etcdSnapshotTimePattern = regexp.MustCompile(`-[0-9]+$`)
+)
+
+// EtcdSnapshot is a snapshot as listed by k3s etcd-snapshot ls.
+type EtcdSnapshot struct {
+	// Full name, the requested name followed by the node name and a timestamp
+	Name string
+	// file:// URL of a local snapshot, or s3:// URL of an uploaded one
+	Location  string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// Local reports whether the snapshot is stored on the server itself.
+func (s EtcdSnapshot) Local() bool {
+	return strings.HasPrefix(s.Location, "file://")
+}
+
+// EtcdSnapshotTarget is a server with embedded etcd that snapshot commands
+// run on over SSH.
+type EtcdSnapshotTarget struct {
+	BinDir string
+	// Data dir of the server, read from its config.yaml when empty
+	DataDir string
+}
+
+// ValidateEtcdSnapshotName checks a name passed to etcd-snapshot save.
+func ValidateEtcdSnapshotName(name string) error {
+	if len(name) > 200 || !etcdSnapshotNamePattern.MatchString(name) {
+		return fmt.Errorf("etcd snapshot name %q must start with a letter or digit and contain only letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+func (t EtcdSnapshotTarget) command(subcommand string) *shellCommand {
+	binDir := t.BinDir
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+
+	// The S3 credentials of etcd_snapshots only reach the service through
+	// its env file, so the command loads the file too. Everything appended
+	// to the command is passed on to k3s as positional arguments.
+	script := fmt.Sprintf(`if [ -f %s ]; then set -a; . %s; set +a; fi; exec "$0" "$@"`, shellQuote(etcdS3EnvPath), shellQuote(etcdS3EnvPath))
+	command := sudo("sh", "-c").Arg(script).Arg(binDir+"/k3s").Literal("etcd-snapshot", subcommand)
+	if t.DataDir != "" {
+		command.Literal("--data-dir").Arg(t.DataDir)
+	}
+	return command
+}
+
+// Save takes an on-demand snapshot and returns it as listed afterwards.
+func (t EtcdSnapshotTarget) Save(ctx context.Context, client ssh_client.SSHClient, name string) (EtcdSnapshot, error) {
+	before, err := t.List(ctx, client)
+	if err != nil {
+		return EtcdSnapshot{}, err
+	}
+	existing := make(map[string]bool, len(before))
+	for _, snapshot := range before {
+		existing[snapshot.Name] = true
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Saving etcd snapshot %s", name))
+	output, err := runCaptured(client, t.command("save").Literal("--name").Arg(name))
+	if err != nil {
+		return EtcdSnapshot{}, fmt.Errorf("saving etcd snapshot: %s", err.Error())
+	}
+
+	after, err := t.List(ctx, client)
+	if err != nil {
+		return EtcdSnapshot{}, err
+	}
+	var saved []EtcdSnapshot
+	for _, snapshot := range after {
+		if !existing[snapshot.Name] && strings.HasPrefix(snapshot.Name, name+"-") {
+			saved = append(saved, snapshot)
+		}
+	}
+	if len(saved) == 0 {
+		return EtcdSnapshot{}, fmt.Errorf("etcd snapshot %s was not listed after saving: %s", name, strings.TrimSpace(output))
+	}
+
+	// Prefer the local copy when the snapshot was also uploaded to S3
+	sort.SliceStable(saved, func(i, j int) bool {
+		if saved[i].Local() != saved[j].Local() {
+			return saved[i].Local()
+		}
+		return saved[i].CreatedAt.After(saved[j].CreatedAt)
+	})
+	return saved[0], nil
+}
+
+// List returns every snapshot known to the server, local and on S3.
+func (t EtcdSnapshotTarget) List(ctx context.Context, client ssh_client.SSHClient) ([]EtcdSnapshot, error) {
+	output, err := runCaptured(client, t.command("ls"))
+	if err != nil {
+		return nil, fmt.Errorf("listing etcd snapshots: %s", err.Error())
+	}
+	return parseEtcdSnapshotList(output), nil
+}
+
+// Find returns the snapshot with the given full name, preferring the local
+// copy. The second result is false when no such snapshot exists.
+func (t EtcdSnapshotTarget) Find(ctx context.Context, client ssh_client.SSHClient, name string) (EtcdSnapshot, bool, error) {
+	snapshots, err := t.List(ctx, client)
+	if err != nil {
+		return EtcdSnapshot{}, false, err
+	}
+
+	var found *EtcdSnapshot
+	for i, snapshot := range snapshots {
+		if snapshot.Name == name && (found == nil || (!found.Local() && snapshot.Local())) {
+			found = &snapshots[i]
+		}
+	}
+	if found == nil {
+		return EtcdSnapshot{}, false, nil
+	}
+	return *found, true, nil
+}
+
+// Delete removes a snapshot, locally and from S3 when it was uploaded.
+func (t EtcdSnapshotTarget) Delete(ctx context.Context, client ssh_client.SSHClient, name string) error {
+	tflog.Info(ctx, fmt.Sprintf("Deleting etcd snapshot %s", name))
+	if _, err := runCaptured(client, t.command("delete").Arg(name)); err != nil {
+		return fmt.Errorf("deleting etcd snapshot: %s", err.Error())
+	}
+	return nil
+}
+
+// Prune removes the oldest snapshots taken with the given name so that only
+// retention of them are kept, and returns the names of the removed ones.
+func (t EtcdSnapshotTarget) Prune(ctx context.Context, client ssh_client.SSHClient, name string, retention int) ([]string, error) {
+	before, err := t.List(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Pruning etcd snapshots named %s to %d", name, retention))
+	command := t.command("prune").Literal("--name").Arg(name).Literal("--snapshot-retention").Arg(strconv.Itoa(retention))
+	if _, err := runCaptured(client, command); err != nil {
+		return nil, fmt.Errorf("pruning etcd snapshots: %s", err.Error())
+	}
+
+	after, err := t.List(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	return removedEtcdSnapshots(before, after), nil
+}
+
+// Names listed before but not after, once each.
+func removedEtcdSnapshots(before []EtcdSnapshot, after []EtcdSnapshot) []string {
+	remaining := make(map[string]bool, len(after))
+	for _, snapshot := range after {
+		remaining[snapshot.Name] = true
+	}
+
+	removed := []string{}
+	seen := make(map[string]bool)
+	for _, snapshot := range before {
+		if !remaining[snapshot.Name] && !seen[snapshot.Name] {
+			removed = append(removed, snapshot.Name)
+			seen[snapshot.Name] = true
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Name passed to save for a snapshot, with the node name and timestamp
+// that k3s appends removed. Falls back to the full name when the snapshot
+// was not taken on this node.
+func (t EtcdSnapshotTarget) RequestedName(client ssh_client.SSHClient, snapshot string) (string, error) {
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return "", err
+	}
+	node, err := nodeName(client, config)
+	if err != nil {
+		return "", err
+	}
+	return requestedEtcdSnapshotName(snapshot, node), nil
+}
+
+func requestedEtcdSnapshotName(snapshot string, node string) string {
+	trimmed := etcdSnapshotTimePattern.ReplaceAllString(snapshot, "")
+	if name, ok := strings.CutSuffix(trimmed, "-"+node); ok && trimmed != snapshot && name != "" {
+		return name
+	}
+	return snapshot
+}
+
+// Parses the table printed by etcd-snapshot ls. Log lines and the header
+// are skipped.
+func parseEtcdSnapshotList(output string) []EtcdSnapshot {
+	snapshots := []EtcdSnapshot{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		created, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			continue
+		}
+
+		snapshots = append(snapshots, EtcdSnapshot{
+			Name:      fields[0],
+			Location:  fields[1],
+			Size:      size,
+			CreatedAt: created,
+		})
+	}
+	return snapshots
+}
+
+// Runs a command with stderr folded into its output. Unlike client.Run,
+// the output is kept in the error when the command fails.
+func runCaptured(client ssh_client.SSHClient, command *shellCommand) (string, error) {
+	res, err := client.Run(fmt.Sprintf("%s 2>&1; echo %s$?", command, exitStatusMarker))
+	if err != nil {
+		return "", err
+	}
+	if len(res) != 1 {
+		return "", fmt.Errorf("wrong number of results from %s", command)
+	}
+
+	output, status, found := strings.Cut(res[0], exitStatusMarker)
+	if !found {
+		return "", fmt.Errorf("missing exit status from %s: %s", command, res[0])
+	}
+	if status = strings.TrimSpace(status); status != "0" {
+		return output, fmt.Errorf("exit status %s: %s", status, strings.TrimSpace(output))
+	}
+	return output, nil
+}
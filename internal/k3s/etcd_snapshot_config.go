@@ -0,0 +1,160 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Root-only env file with the S3 credentials, loaded by the service and
+	// sourced by the etcd-snapshot commands
+	etcdS3EnvPath = CONFIG_DIR + "/etcd-s3.env"
+	// Systemd drop-in that loads the env file into k3s.service
+	etcdS3DropInPath = "/etc/systemd/system/k3s.service.d/etcd-s3.conf"
+	// Uploaded CA bundle of the S3 endpoint
+	etcdS3CAPath = CONFIG_DIR + "/etcd-s3-ca.crt"
+)
+
+// EtcdSnapshotConfig configures scheduled etcd snapshots and their upload
+// to S3 compatible storage.
+type EtcdSnapshotConfig struct {
+	ScheduleCron string
+	// Zero keeps the k3s default
+	Retention int64
+	Compress  bool
+	Dir       string
+	S3        *EtcdS3Config
+}
+
+type EtcdS3Config struct {
+	Endpoint  string
+	Bucket    string
+	Folder    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// PEM encoded CA bundle uploaded for the endpoint
+	EndpointCA    string
+	SkipSSLVerify bool
+	Insecure      bool
+}
+
+func (c *EtcdSnapshotConfig) Validate(config map[any]any) error {
+	if c.ScheduleCron != "" && len(strings.Fields(c.ScheduleCron)) != 5 {
+		return fmt.Errorf("etcd_snapshots schedule_cron %q must have five fields", c.ScheduleCron)
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("etcd_snapshots retention must not be negative")
+	}
+	if c.S3 == nil {
+		return nil
+	}
+
+	if c.S3.Bucket == "" {
+		return fmt.Errorf("etcd_snapshots s3 bucket must be set")
+	}
+	if (c.S3.AccessKey == "") != (c.S3.SecretKey == "") {
+		return fmt.Errorf("etcd_snapshots s3 access_key and secret_key must be set together")
+	}
+	for name, value := range map[string]string{"access_key": c.S3.AccessKey, "secret_key": c.S3.SecretKey} {
+		if strings.ContainsAny(value, "'\"\\\n\r") {
+			return fmt.Errorf("etcd_snapshots s3 %s must not contain quotes, backslashes or newlines", name)
+		}
+	}
+	if c.S3.AccessKey != "" {
+		for _, key := range []string{"etcd-s3-access-key", "etcd-s3-secret-key"} {
+			if _, ok := config[key]; ok {
+				return fmt.Errorf("%q is set in config and by etcd_snapshots s3, set the credentials in only one place", key)
+			}
+		}
+	}
+	if c.S3.EndpointCA != "" && !validCertificates(c.S3.EndpointCA) {
+		return fmt.Errorf("etcd_snapshots s3 endpoint_ca must contain PEM encoded certificates")
+	}
+	return nil
+}
+
+// Merges the rendered keys into the parsed config. A key that is also set
+// in config is reported as a conflict.
+func (c *EtcdSnapshotConfig) mergeInto(config map[any]any) error {
+	return mergeConfigValues(config, c.configValues(), blockLabel("etcd_snapshots"))
+}
+
+// Config keys rendered into config.yaml. Credentials are left out, the
+// service reads them from the env file instead.
+func (c *EtcdSnapshotConfig) configValues() map[string]any {
+	values := make(map[string]any)
+	if c.ScheduleCron != "" {
+		values["etcd-snapshot-schedule-cron"] = c.ScheduleCron
+	}
+	if c.Retention > 0 {
+		values["etcd-snapshot-retention"] = c.Retention
+	}
+	if c.Compress {
+		values["etcd-snapshot-compress"] = true
+	}
+	if c.Dir != "" {
+		values["etcd-snapshot-dir"] = c.Dir
+	}
+	if c.S3 == nil {
+		return values
+	}
+
+	values["etcd-s3"] = true
+	values["etcd-s3-bucket"] = c.S3.Bucket
+	for key, value := range map[string]string{
+		"etcd-s3-endpoint": c.S3.Endpoint,
+		"etcd-s3-folder":   c.S3.Folder,
+		"etcd-s3-region":   c.S3.Region,
+	} {
+		if value != "" {
+			values[key] = value
+		}
+	}
+	if c.S3.EndpointCA != "" {
+		values["etcd-s3-endpoint-ca"] = etcdS3CAPath
+	}
+	if c.S3.SkipSSLVerify {
+		values["etcd-s3-skip-ssl-verify"] = true
+	}
+	if c.S3.Insecure {
+		values["etcd-s3-insecure"] = true
+	}
+	return values
+}
+
+// Commands for writing the S3 credentials and CA, removing whichever of
+// them is no longer declared. The files are only ever written by the
+// provider, so removing them unconditionally is safe.
+func etcdSnapshotCommands(c *EtcdSnapshotConfig) []string {
+	var s3 EtcdS3Config
+	if c != nil && c.S3 != nil {
+		s3 = *c.S3
+	}
+
+	files := make(map[string]ExtraFile)
+	var stale []string
+	if s3.AccessKey != "" {
+		files[etcdS3EnvPath] = ExtraFile{
+			Content: fmt.Sprintf("AWS_ACCESS_KEY_ID=%s\nAWS_SECRET_ACCESS_KEY=%s\n", shellQuote(s3.AccessKey), shellQuote(s3.SecretKey)),
+			Mode:    "0600",
+		}
+		files[etcdS3DropInPath] = ExtraFile{
+			Content: fmt.Sprintf("[Service]\nEnvironmentFile=%s\n", etcdS3EnvPath),
+			Mode:    "0644",
+		}
+	} else {
+		stale = append(stale, etcdS3EnvPath, etcdS3DropInPath)
+	}
+	if s3.EndpointCA != "" {
+		files[etcdS3CAPath] = ExtraFile{Content: s3.EndpointCA, Mode: "0644"}
+	} else {
+		stale = append(stale, etcdS3CAPath)
+	}
+
+	return extraFileCommands(files, stale)
+}
+
+func etcdSnapshotPaths() []string {
+	return []string{etcdS3EnvPath, etcdS3DropInPath, etcdS3CAPath}
+}
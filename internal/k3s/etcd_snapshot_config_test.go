@@ -0,0 +1,109 @@
+package k3s
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEtcdSnapshotConfigValidate(t *testing.T) {
+	valid := []EtcdSnapshotConfig{
+		{},
+		{ScheduleCron: "0 */6 * * *", Retention: 10, Compress: true},
+		{S3: &EtcdS3Config{Endpoint: "minio:9000", Bucket: "backups", Insecure: true}},
+		{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin", SecretKey: "minioadmin"}},
+	}
+	for _, c := range valid {
+		if err := c.Validate(map[any]any{}); err != nil {
+			t.Errorf("Validate(%+v) error = %v", c, err)
+		}
+	}
+
+	invalid := []EtcdSnapshotConfig{
+		{ScheduleCron: "@hourly"},
+		{Retention: -1},
+		{S3: &EtcdS3Config{}},
+		{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin"}},
+		{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin", SecretKey: "bad\"secret"}},
+		{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin", SecretKey: "bad'secret"}},
+		{S3: &EtcdS3Config{Bucket: "backups", EndpointCA: "not a certificate"}},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(map[any]any{}); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid config", c)
+		}
+	}
+
+	withCredentials := EtcdSnapshotConfig{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin", SecretKey: "minioadmin"}}
+	if err := withCredentials.Validate(map[any]any{"etcd-s3-secret-key": "plaintext"}); err == nil {
+		t.Error("Validate() accepted credentials set in config and etcd_snapshots")
+	}
+}
+
+func TestEtcdSnapshotConfigMergeInto(t *testing.T) {
+	c := EtcdSnapshotConfig{
+		ScheduleCron: "0 */6 * * *",
+		Retention:    10,
+		S3: &EtcdS3Config{
+			Endpoint:   "minio:9000",
+			Bucket:     "backups",
+			Folder:     "cluster",
+			AccessKey:  "minioadmin",
+			SecretKey:  "minioadmin",
+			EndpointCA: "-----BEGIN CERTIFICATE-----",
+		},
+	}
+	config := map[any]any{"write-kubeconfig-mode": "0644"}
+	if err := c.mergeInto(config); err != nil {
+		t.Fatalf("mergeInto() error = %v", err)
+	}
+
+	want := map[string]any{
+		"etcd-snapshot-schedule-cron": "0 */6 * * *",
+		"etcd-snapshot-retention":     int64(10),
+		"etcd-s3":                     true,
+		"etcd-s3-endpoint":            "minio:9000",
+		"etcd-s3-bucket":              "backups",
+		"etcd-s3-folder":              "cluster",
+		"etcd-s3-endpoint-ca":         etcdS3CAPath,
+	}
+	for key, value := range want {
+		if config[key] != value {
+			t.Errorf("config[%q] = %v, want %v", key, config[key], value)
+		}
+	}
+	for _, key := range []string{"etcd-s3-access-key", "etcd-s3-secret-key"} {
+		if _, ok := config[key]; ok {
+			t.Errorf("config has %q, credentials belong in the env file", key)
+		}
+	}
+
+	if err := c.mergeInto(map[any]any{"etcd-s3-bucket": "other"}); err == nil {
+		t.Error("mergeInto() accepted a key already set in config")
+	}
+}
+
+func TestEtcdSnapshotCommands(t *testing.T) {
+	c := &EtcdSnapshotConfig{S3: &EtcdS3Config{Bucket: "backups", AccessKey: "minioadmin", SecretKey: "s3cr3t"}}
+	got := strings.Join(etcdSnapshotCommands(c), "\n")
+	for _, want := range []string{
+		"sudo install -m '0600' /dev/null '" + etcdS3EnvPath + "'",
+		"sudo install -m '0644' /dev/null '" + etcdS3DropInPath + "'",
+		base64.StdEncoding.EncodeToString([]byte("AWS_ACCESS_KEY_ID='minioadmin'\nAWS_SECRET_ACCESS_KEY='s3cr3t'\n")),
+		"sudo rm -f '" + etcdS3CAPath + "'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("etcdSnapshotCommands() missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "s3cr3t") {
+		t.Error("etcdSnapshotCommands() has the secret key in plain text")
+	}
+
+	removed := strings.Join(etcdSnapshotCommands(nil), "\n")
+	for _, p := range etcdSnapshotPaths() {
+		if !strings.Contains(removed, "sudo rm -f '"+p+"'") {
+			t.Errorf("etcdSnapshotCommands(nil) does not remove %s", p)
+		}
+	}
+}
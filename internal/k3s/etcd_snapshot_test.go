@@ -0,0 +1,91 @@
+package k3s
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEtcdSnapshotList(t *testing.T) {
+	output := `time="2024-05-01T10:00:00Z" level=info msg="Managed etcd cluster bootstrap already complete"
+Name                             Location                                                                      Size    Created
+pre-upgrade-server-1-1714557600  file:///var/lib/rancher/k3s/server/db/snapshots/pre-upgrade-server-1-1714557600 4534304 2024-05-01T10:00:00Z
+pre-upgrade-server-1-1714557600  s3://backups/k3s/pre-upgrade-server-1-1714557600                                4534304 2024-05-01T10:00:01Z
+`
+
+	snapshots := parseEtcdSnapshotList(output)
+	if len(snapshots) != 2 {
+		t.Fatalf("parseEtcdSnapshotList() = %v, want 2 snapshots", snapshots)
+	}
+	local := snapshots[0]
+	if local.Name != "pre-upgrade-server-1-1714557600" || local.Size != 4534304 || !local.Local() {
+		t.Errorf("parseEtcdSnapshotList() = %+v", local)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC); !local.CreatedAt.Equal(want) {
+		t.Errorf("parseEtcdSnapshotList() created = %s, want %s", local.CreatedAt, want)
+	}
+	if snapshots[1].Local() {
+		t.Errorf("parseEtcdSnapshotList() = %+v, want an s3 snapshot", snapshots[1])
+	}
+}
+
+func TestRequestedEtcdSnapshotName(t *testing.T) {
+	tests := []struct {
+		snapshot string
+		node     string
+		want     string
+	}{
+		{snapshot: "pre-upgrade-server-1-1714557600", node: "server-1", want: "pre-upgrade"},
+		{snapshot: "etcd-snapshot-server-2-1714557600", node: "server-1", want: "etcd-snapshot-server-2-1714557600"},
+		{snapshot: "server-1-1714557600", node: "server-1", want: "server-1-1714557600"},
+		{snapshot: "manual", node: "server-1", want: "manual"},
+	}
+
+	for _, tt := range tests {
+		if got := requestedEtcdSnapshotName(tt.snapshot, tt.node); got != tt.want {
+			t.Errorf("requestedEtcdSnapshotName(%q, %q) = %q, want %q", tt.snapshot, tt.node, got, tt.want)
+		}
+	}
+}
+
+func TestValidateEtcdSnapshotName(t *testing.T) {
+	for _, name := range []string{"pre-upgrade", "nightly.1", "on_demand"} {
+		if err := ValidateEtcdSnapshotName(name); err != nil {
+			t.Errorf("ValidateEtcdSnapshotName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-flag", "a b", "a/b", "$(reboot)"} {
+		if err := ValidateEtcdSnapshotName(name); err == nil {
+			t.Errorf("ValidateEtcdSnapshotName(%q) = nil, want an error", name)
+		}
+	}
+}
+
+func TestEtcdSnapshotTargetCommand(t *testing.T) {
+	loadEnv := `sudo sh -c 'if [ -f '\''/etc/rancher/k3s/etcd-s3.env'\'' ]; then set -a; . '\''/etc/rancher/k3s/etcd-s3.env'\''; set +a; fi; exec "$0" "$@"' `
+	if got, want := (EtcdSnapshotTarget{}).command("ls").String(), loadEnv+"'/usr/local/bin/k3s' etcd-snapshot ls"; got != want {
+		t.Errorf("command() = %q, want %q", got, want)
+	}
+	got := (EtcdSnapshotTarget{BinDir: "/opt/bin", DataDir: "/data/k3s"}).command("save").Literal("--name").Arg("nightly").String()
+	if want := loadEnv + "'/opt/bin/k3s' etcd-snapshot save --data-dir '/data/k3s' --name 'nightly'"; got != want {
+		t.Errorf("command() = %q, want %q", got, want)
+	}
+}
+
+func TestRemovedEtcdSnapshots(t *testing.T) {
+	before := []EtcdSnapshot{
+		{Name: "on-demand-server-1-1714550400", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/on-demand-server-1-1714550400"},
+		{Name: "on-demand-server-1-1714550400", Location: "s3://backups/on-demand-server-1-1714550400"},
+		{Name: "on-demand-server-1-1714554000", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/on-demand-server-1-1714554000"},
+		{Name: "etcd-snapshot-server-1-1714557600", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/etcd-snapshot-server-1-1714557600"},
+	}
+	after := before[2:]
+
+	got := removedEtcdSnapshots(before, after)
+	if !reflect.DeepEqual(got, []string{"on-demand-server-1-1714550400"}) {
+		t.Errorf("removedEtcdSnapshots() = %v", got)
+	}
+	if got := removedEtcdSnapshots(before, before); len(got) != 0 {
+		t.Errorf("removedEtcdSnapshots() = %v, want none", got)
+	}
+}
@@ -0,0 +1,160 @@
+package k3s
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// ExtraFile is a file written to the host next to the k3s config.
+type ExtraFile struct {
+	Content string
+	// Octal file mode, left to the remote umask when empty
+	Mode string
+	// Owner in user[:group] format, root:root when empty
+	Owner string
+}
+
+// Sha256 returns the hex encoded checksum of the file content.
+func (f ExtraFile) Sha256() string {
+	return sha256Hex(f.Content)
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExtraFileStatus is an extra file as observed on the host.
+type ExtraFileStatus struct {
+	Sha256 string
+	// Octal mode as printed by stat, for example 644
+	Mode string
+	// Owner by name and by id, in user:group format
+	Owner   string
+	OwnerId string
+}
+
+// ModeMatches reports whether the observed mode equals the octal mode.
+func (s ExtraFileStatus) ModeMatches(mode string) bool {
+	want, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return false
+	}
+	got, err := strconv.ParseUint(s.Mode, 8, 32)
+	return err == nil && got == want
+}
+
+// OctalMode returns the observed mode with a leading zero, for example 0644.
+func (s ExtraFileStatus) OctalMode() string {
+	mode, err := strconv.ParseUint(s.Mode, 8, 32)
+	if err != nil {
+		return s.Mode
+	}
+	return fmt.Sprintf("%04o", mode)
+}
+
+// OwnerMatches reports whether the observed owner equals a user[:group]
+// owner given by name or by id.
+func (s ExtraFileStatus) OwnerMatches(owner string) bool {
+	wantUser, wantGroup, hasGroup := strings.Cut(owner, ":")
+	names := strings.SplitN(s.Owner, ":", 2)
+	ids := strings.SplitN(s.OwnerId, ":", 2)
+	if len(names) != 2 || len(ids) != 2 {
+		return false
+	}
+
+	if wantUser != names[0] && wantUser != ids[0] {
+		return false
+	}
+	return !hasGroup || wantGroup == names[1] || wantGroup == ids[1]
+}
+
+// Paths that are managed by the provider and cannot be extra files.
+func reservedFilePaths() []string {
+	return []string{
+		fmt.Sprintf("%s/config.yaml", CONFIG_DIR),
+		fmt.Sprintf("%s/registries.yaml", CONFIG_DIR),
+	}
+}
+
+// ValidateExtraFiles checks that no extra file replaces a file the provider
+// already manages.
+func ValidateExtraFiles(files map[string]ExtraFile) error {
+	for _, p := range sortedKeys(files) {
+		if !path.IsAbs(p) || path.Clean(p) != p {
+			return fmt.Errorf("extra file path %q must be a clean absolute file path", p)
+		}
+		for _, reserved := range reservedFilePaths() {
+			if p == reserved {
+				return fmt.Errorf("extra file path %q is managed by the provider", p)
+			}
+		}
+		if path.Dir(p) == CONFIG_FRAGMENTS_DIR {
+			return fmt.Errorf("extra file path %q is in %s, use config_fragments instead", p, CONFIG_FRAGMENTS_DIR)
+		}
+	}
+
+	return nil
+}
+
+// Commands for writing extra files and removing the ones that are no longer
+// declared.
+func extraFileCommands(files map[string]ExtraFile, stale []string) (commands []string) {
+	for _, p := range sortedKeys(files) {
+		file := files[p]
+		commands = append(commands, sudo("mkdir", "-p").Arg(path.Dir(p)).String())
+		if file.Mode != "" {
+			// Create the file with its final mode, so the decoded content is
+			// never readable with the default umask
+			commands = append(commands, sudo("install", "-m").Arg(file.Mode).Literal("/dev/null").Arg(p).String())
+		}
+		commands = append(commands, WriteFileCommands(p, base64.StdEncoding.EncodeToString([]byte(file.Content)))...)
+		if file.Owner != "" {
+			commands = append(commands, sudo("chown").Arg(file.Owner, p).String())
+		}
+		if file.Mode != "" {
+			commands = append(commands, sudo("chmod").Arg(file.Mode, p).String())
+		}
+	}
+	for _, p := range stale {
+		commands = append(commands, sudo("rm", "-f").Arg(p).String())
+	}
+
+	return commands
+}
+
+// Reads the checksum, mode and owner of every path. Paths missing from the
+// host are left out of the result.
+func readExtraFiles(client ssh_client.SSHClient, paths []string) (map[string]ExtraFileStatus, error) {
+	files := make(map[string]ExtraFileStatus, len(paths))
+	for _, p := range paths {
+		res, err := client.Run(fmt.Sprintf(
+			"if %s; then %s && %s; fi",
+			sudo("test", "-f").Arg(p), sudo("stat", "-c").Arg("%a %U:%G %u:%g", p), sudo("sha256sum").Arg(p),
+		))
+		if err != nil {
+			return nil, err
+		}
+		if len(res) != 1 {
+			return nil, fmt.Errorf("wrong number of results from %s status check", p)
+		}
+
+		fields := strings.Fields(res[0])
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("could not parse status of %s", p)
+		}
+		files[p] = ExtraFileStatus{Mode: fields[0], Owner: fields[1], OwnerId: fields[2], Sha256: fields[3]}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,91 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateExtraFiles(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		expectError bool
+	}{
+		{name: "absolute path", path: "/etc/rancher/k3s/audit-policy.yaml"},
+		{name: "relative path", path: "etc/rancher/k3s/audit.yaml", expectError: true},
+		{name: "unclean path", path: "/etc/rancher/k3s/../k3s/audit.yaml", expectError: true},
+		{name: "managed config", path: "/etc/rancher/k3s/config.yaml", expectError: true},
+		{name: "managed registries", path: "/etc/rancher/k3s/registries.yaml", expectError: true},
+		{name: "config fragment", path: "/etc/rancher/k3s/config.yaml.d/10-a.yaml", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateExtraFiles(map[string]ExtraFile{tt.path: {Content: "a"}})
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestExtraFileCommands(t *testing.T) {
+	commands := extraFileCommands(map[string]ExtraFile{
+		"/etc/rancher/k3s/tls/key.pem": {Content: "secret", Mode: "0600", Owner: "k3s:k3s"},
+	}, []string{"/etc/rancher/k3s/old.yaml"})
+
+	wantOrder := []string{
+		"sudo mkdir -p '/etc/rancher/k3s/tls'",
+		"sudo install -m '0600' /dev/null '/etc/rancher/k3s/tls/key.pem'",
+		"sudo chown 'k3s:k3s' '/etc/rancher/k3s/tls/key.pem'",
+		"sudo chmod '0600' '/etc/rancher/k3s/tls/key.pem'",
+		"sudo rm -f '/etc/rancher/k3s/old.yaml'",
+	}
+	joined := strings.Join(commands, "\n")
+	last := -1
+	for _, want := range wantOrder {
+		i := strings.Index(joined, want)
+		if i < 0 {
+			t.Fatalf("extraFileCommands() = %q, want %q", joined, want)
+		}
+		if i < last {
+			t.Errorf("extraFileCommands() runs %q out of order", want)
+		}
+		last = i
+	}
+
+	plain := strings.Join(extraFileCommands(map[string]ExtraFile{"/opt/a": {Content: "a"}}, nil), "\n")
+	if strings.Contains(plain, "chmod") || strings.Contains(plain, "/dev/null '/opt/a'") {
+		t.Errorf("extraFileCommands() = %q, want no mode change without a mode", plain)
+	}
+}
+
+func TestExtraFileStatus(t *testing.T) {
+	status := ExtraFileStatus{Mode: "640", Owner: "root:k3s", OwnerId: "0:990"}
+
+	for _, mode := range []string{"640", "0640"} {
+		if !status.ModeMatches(mode) {
+			t.Errorf("ModeMatches(%q) = false, want true", mode)
+		}
+	}
+	if status.ModeMatches("0600") {
+		t.Errorf("ModeMatches(0600) = true, want false")
+	}
+	if got, want := status.OctalMode(), "0640"; got != want {
+		t.Errorf("OctalMode() = %q, want %q", got, want)
+	}
+
+	for _, owner := range []string{"root", "root:k3s", "0:990", "root:990"} {
+		if !status.OwnerMatches(owner) {
+			t.Errorf("OwnerMatches(%q) = false, want true", owner)
+		}
+	}
+	for _, owner := range []string{"k3s", "root:root", "1000:990"} {
+		if status.OwnerMatches(owner) {
+			t.Errorf("OwnerMatches(%q) = true, want false", owner)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package k3s
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.yaml.in/yaml/v2"
+)
+
+const (
+	ROLE_SERVER string = "server"
+	ROLE_AGENT  string = "agent"
+)
+
+type flagSpec struct {
+	Type    string   `yaml:"type"`
+	Aliases []string `yaml:"aliases"`
+	Since   string   `yaml:"since"`
+	Until   string   `yaml:"until"`
+}
+
+type flagCatalog struct {
+	MinVersion string              `yaml:"min_version"`
+	MaxVersion string              `yaml:"max_version"`
+	Common     map[string]flagSpec `yaml:"common"`
+	Server     map[string]flagSpec `yaml:"server"`
+	Agent      map[string]flagSpec `yaml:"agent"`
+}
+
+type catalogFlag struct {
+	name string
+	role string
+	spec flagSpec
+}
+
+var (
+	loadCatalogOnce sync.Once
+	loadedCatalog   flagCatalog
+	loadCatalogErr  error
+)
+
+// The embedded catalog of valid config keys.
+func readFlagCatalog() (flagCatalog, error) {
+	loadCatalogOnce.Do(func() {
+		contents, err := assets.ReadFile("assets/config-flags.yaml")
+		if err != nil {
+			loadCatalogErr = err
+			return
+		}
+		loadCatalogErr = yaml.UnmarshalStrict(contents, &loadedCatalog)
+	})
+
+	return loadedCatalog, loadCatalogErr
+}
+
+// Looks up a config key, resolving aliases, across every role.
+func (c flagCatalog) lookup(key string) (catalogFlag, bool) {
+	sections := []struct {
+		role  string
+		flags map[string]flagSpec
+	}{
+		{"", c.Common},
+		{ROLE_SERVER, c.Server},
+		{ROLE_AGENT, c.Agent},
+	}
+
+	for _, section := range sections {
+		for name, spec := range section.flags {
+			if name == key {
+				return catalogFlag{name: name, role: section.role, spec: spec}, true
+			}
+			for _, alias := range spec.Aliases {
+				if alias == key {
+					return catalogFlag{name: name, role: section.role, spec: spec}, true
+				}
+			}
+		}
+	}
+
+	return catalogFlag{}, false
+}
+
+// Every flag name that is valid for a role and minor version.
+func (c flagCatalog) names(role string, minor int) []string {
+	names := []string{}
+	for _, flags := range []map[string]flagSpec{c.Common, c.roleFlags(role)} {
+		for name, spec := range flags {
+			if spec.availableIn(minor) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c flagCatalog) roleFlags(role string) map[string]flagSpec {
+	if role == ROLE_AGENT {
+		return c.Agent
+	}
+	return c.Server
+}
+
+func (f flagSpec) availableIn(minor int) bool {
+	if since, err := parseMinorVersion(f.Since); err == nil && minor < since {
+		return false
+	}
+	if until, err := parseMinorVersion(f.Until); err == nil && minor > until {
+		return false
+	}
+	return true
+}
+
+// Parses the minor version out of "1.30", "v1.30.4" or "v1.30.4+k3s1".
+func parseMinorVersion(version string) (int, error) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0, fmt.Errorf("could not parse k3s minor version from %q", version)
+	}
+
+	minor := parts[1]
+	if i := strings.IndexAny(minor, "+-"); i >= 0 {
+		minor = minor[:i]
+	}
+	return strconv.Atoi(minor)
+}
+
+// ValidateConfigKeys checks every key of a parsed k3s config against the
+// embedded flag catalog for the given role and k3s version. Versions that
+// cannot be parsed, such as channels, validate against the newest catalogued
+// release.
+func ValidateConfigKeys(role string, version string, config map[any]any) error {
+	catalog, err := readFlagCatalog()
+	if err != nil {
+		return fmt.Errorf("reading k3s flag catalog: %s", err.Error())
+	}
+
+	minor, err := parseMinorVersion(version)
+	if err != nil {
+		if minor, err = parseMinorVersion(catalog.MaxVersion); err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, fmt.Sprint(k))
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if err := catalog.validateKey(role, minor, key, config[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validates the keys of every config.yaml.d drop-in.
+func validateConfigFragmentKeys(role string, version string, fragments map[string]string) error {
+	for _, name := range sortedKeys(fragments) {
+		fragment := make(map[any]any)
+		if err := yaml.Unmarshal([]byte(fragments[name]), &fragment); err != nil {
+			return fmt.Errorf("parsing config fragment %q: %s", name, err.Error())
+		}
+		if err := ValidateConfigKeys(role, version, fragment); err != nil {
+			return fmt.Errorf("config fragment %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c flagCatalog) validateKey(role string, minor int, key string, value any) error {
+	name, appending := strings.CutSuffix(key, "+")
+	flag, ok := c.lookup(name)
+	if !ok {
+		if suggestion := closestFlag(name, c.names(role, minor)); suggestion != "" {
+			return fmt.Errorf("%q is not a valid k3s %s config key, did you mean %q?", key, role, suggestion)
+		}
+		return fmt.Errorf("%q is not a valid k3s %s config key", key, role)
+	}
+	if flag.role != "" && flag.role != role {
+		return fmt.Errorf("%q is a k3s %s-only config key and cannot be used on a k3s %s", key, flag.role, role)
+	}
+	if !flag.spec.availableIn(minor) {
+		return fmt.Errorf("%q is not supported by k3s v1.%d", key, minor)
+	}
+	if appending && flag.spec.Type != "list" {
+		return fmt.Errorf("%q appends to %q, which does not accept a list", key, flag.name)
+	}
+
+	return validateFlagValue(key, flag.spec.Type, value)
+}
+
+func validateFlagValue(key string, flagType string, value any) error {
+	switch flagType {
+	case "list":
+		if _, ok := value.([]string); ok {
+			return nil
+		}
+		if list, ok := value.([]any); ok {
+			for _, element := range list {
+				if !isScalar(element) {
+					return fmt.Errorf("%q must be a list of strings", key)
+				}
+			}
+			return nil
+		}
+		if !isScalar(value) {
+			return fmt.Errorf("%q must be a string or a list of strings", key)
+		}
+	case "bool":
+		if _, ok := value.(bool); ok {
+			return nil
+		}
+		if s, ok := value.(string); ok {
+			if _, err := strconv.ParseBool(s); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be a boolean", key)
+	case "int":
+		if _, ok := value.(int); ok {
+			return nil
+		}
+		if s, ok := value.(string); ok {
+			if _, err := strconv.Atoi(s); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be an integer", key)
+	case "duration":
+		if s, ok := value.(string); ok {
+			if _, err := time.ParseDuration(s); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be a duration such as 5m0s", key)
+	default:
+		if !isScalar(value) {
+			return fmt.Errorf("%q must be a string", key)
+		}
+	}
+
+	return nil
+}
+
+func isScalar(value any) bool {
+	switch value.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// Suggests the closest known flag for a misspelled key.
+func closestFlag(key string, names []string) string {
+	best := ""
+	bestDistance := len(key)/3 + 1
+	for _, name := range names {
+		if distance := levenshtein(key, name); distance <= bestDistance && (best == "" || distance < levenshtein(key, best)) {
+			best = name
+		}
+	}
+	return best
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
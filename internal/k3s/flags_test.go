@@ -0,0 +1,128 @@
+package k3s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v2"
+)
+
+func TestValidateConfigKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		version   string
+		config    string
+		wantError string
+	}{
+		{
+			name:    "valid server config",
+			role:    ROLE_SERVER,
+			version: "v1.32.6+k3s1",
+			config:  "disable-agent: true\ndisable:\n  - traefik\nwrite-kubeconfig-mode: \"0600\"\ntls-san: k3s.local\nsnapshotter: native\n",
+		},
+		{
+			name:   "valid agent config without version",
+			role:   ROLE_AGENT,
+			config: "node-label+:\n  - zone=a\nkubelet-arg: max-pods=200\nd: /opt/rancher/k3s\n",
+		},
+		{
+			name:    "channel version uses newest catalog",
+			role:    ROLE_SERVER,
+			version: "stable",
+			config:  "embedded-registry: true\n",
+		},
+		{
+			name:      "misspelled key",
+			role:      ROLE_SERVER,
+			config:    "write-kubeconfig-mod: \"0600\"\n",
+			wantError: `did you mean "write-kubeconfig-mode"?`,
+		},
+		{
+			name:      "unknown key",
+			role:      ROLE_AGENT,
+			config:    "frobnicate: true\n",
+			wantError: `"frobnicate" is not a valid k3s agent config key`,
+		},
+		{
+			name:      "server key on agent",
+			role:      ROLE_AGENT,
+			config:    "cluster-init: true\n",
+			wantError: `"cluster-init" is a k3s server-only config key`,
+		},
+		{
+			name:      "key newer than version",
+			role:      ROLE_SERVER,
+			version:   "v1.28.15+k3s1",
+			config:    "embedded-registry: true\n",
+			wantError: `"embedded-registry" is not supported by k3s v1.28`,
+		},
+		{
+			name:      "append to scalar",
+			role:      ROLE_SERVER,
+			config:    "write-kubeconfig-mode+: \"0600\"\n",
+			wantError: "does not accept a list",
+		},
+		{
+			name:      "wrong bool type",
+			role:      ROLE_SERVER,
+			config:    "disable-agent: sometimes\n",
+			wantError: `"disable-agent" must be a boolean`,
+		},
+		{
+			name:      "map value",
+			role:      ROLE_SERVER,
+			config:    "tls-san:\n  host: k3s.local\n",
+			wantError: `"tls-san" must be a string or a list of strings`,
+		},
+		{
+			name:      "invalid duration",
+			role:      ROLE_SERVER,
+			config:    "etcd-s3-timeout: five minutes\n",
+			wantError: `"etcd-s3-timeout" must be a duration`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := make(map[any]any)
+			if err := yaml.Unmarshal([]byte(tt.config), &config); err != nil {
+				t.Fatalf("parsing config: %v", err)
+			}
+
+			err := ValidateConfigKeys(tt.role, tt.version, config)
+			if tt.wantError == "" && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+				t.Fatalf("ValidateConfigKeys() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestValidateConfigKeysReportsEveryKey(t *testing.T) {
+	config := map[any]any{"frobnicate": true, "cluster-init": true}
+
+	err := ValidateConfigKeys(ROLE_AGENT, "", config)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	for _, key := range []string{"frobnicate", "cluster-init"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("ValidateConfigKeys() error = %q, want %q reported", err.Error(), key)
+		}
+	}
+}
+
+func TestServerValidateRejectsUnknownFragmentKeys(t *testing.T) {
+	server := Server{
+		ConfigFragments: map[string]string{"90-cis": "protect-kernel-default: true\n"},
+	}
+
+	err := server.Validate(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `config fragment "90-cis"`) {
+		t.Fatalf("Validate() error = %v, want fragment key error", err)
+	}
+}
@@ -0,0 +1,191 @@
+package k3s
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+const CONFIG_FRAGMENTS_DIR string = CONFIG_DIR + "/config.yaml.d"
+
+var configFragmentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateConfigFragments checks that every fragment has a usable file name
+// and parses as a yaml mapping.
+func ValidateConfigFragments(fragments map[string]string) error {
+	for _, name := range sortedKeys(fragments) {
+		if !configFragmentNamePattern.MatchString(name) || strings.HasSuffix(name, ".yaml") {
+			return fmt.Errorf("config fragment name %q must match %s and must not include the .yaml extension", name, configFragmentNamePattern.String())
+		}
+
+		parsed := make(map[any]any)
+		if err := yaml.Unmarshal([]byte(fragments[name]), &parsed); err != nil {
+			return fmt.Errorf("parsing config fragment %q: %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func configFragmentPath(name string) string {
+	return fmt.Sprintf("%s/%s.yaml", CONFIG_FRAGMENTS_DIR, name)
+}
+
+func configFragmentPaths(names ...[]string) (paths []string) {
+	for _, list := range names {
+		for _, name := range list {
+			paths = append(paths, configFragmentPath(name))
+		}
+	}
+	return
+}
+
+// Commands for writing managed drop-ins and removing the ones that are no
+// longer declared. Fragments written by other tooling are never touched.
+func configFragmentCommands(fragments map[string]string, stale []string) []string {
+	if len(fragments) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	commands := []string{sudo("mkdir", "-p").Arg(CONFIG_FRAGMENTS_DIR).String()}
+	for _, name := range sortedKeys(fragments) {
+		commands = append(commands, WriteFileCommands(configFragmentPath(name), base64.StdEncoding.EncodeToString([]byte(fragments[name])))...)
+	}
+	for _, name := range stale {
+		commands = append(commands, sudo("rm", "-f").Arg(configFragmentPath(name)).String())
+	}
+
+	return commands
+}
+
+// Reads back the managed fragments. Fragments missing from the host are
+// dropped from the result so drift shows up in the plan.
+func readConfigFragments(client ssh_client.SSHClient, names []string) (map[string]string, error) {
+	return readNamedFiles(client, names, configFragmentPath)
+}
+
+// Reads the file of every name, leaving out files missing from the host.
+func readNamedFiles(client ssh_client.SSHClient, names []string, path func(string) string) (map[string]string, error) {
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		exists, err := remoteFileExists(client, path(name))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		content, err := client.ReadFile(path(name), false, true)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+
+	return files, nil
+}
+
+// Reads config.yaml and every drop-in, managed or not, and merges them the
+// way k3s does.
+func readMergedConfig(client ssh_client.SSHClient) (string, error) {
+	config, err := client.ReadFile(fmt.Sprintf("%s/config.yaml", CONFIG_DIR), true, true)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := client.Run(fmt.Sprintf("%s | sort || true", sudo("find").Arg(CONFIG_FRAGMENTS_DIR).Literal("-maxdepth", "1", "-type", "f", "-name").Arg("*.yaml").Literal("2>/dev/null")))
+	if err != nil {
+		return "", err
+	}
+
+	documents := []string{config}
+	for _, path := range outputPaths(strings.Join(res, "\n")) {
+		content, err := client.ReadFile(path, true, true)
+		if err != nil {
+			return "", err
+		}
+		documents = append(documents, content)
+	}
+
+	return mergeConfigDocuments(documents...)
+}
+
+// Paths listed one per line, as find prints them. Paths may contain
+// whitespace, so only newlines separate them.
+func outputPaths(output string) (paths []string) {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSuffix(line, "\r"); line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+// Merges config documents in order. Later documents override earlier keys,
+// and keys ending in "+" append to the existing value.
+func mergeConfigDocuments(documents ...string) (string, error) {
+	merged := make(map[any]any)
+	for _, document := range documents {
+		parsed := make(map[any]any)
+		if err := yaml.Unmarshal([]byte(document), &parsed); err != nil {
+			return "", err
+		}
+
+		for k, v := range parsed {
+			key := fmt.Sprint(k)
+			if base, ok := strings.CutSuffix(key, "+"); ok {
+				merged[base] = append(toList(merged[base]), toList(v)...)
+				continue
+			}
+			merged[key] = v
+		}
+	}
+
+	if len(merged) == 0 {
+		return "", nil
+	}
+
+	contents, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	return string(contents), nil
+}
+
+func toList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
+
+func remoteFileExists(client ssh_client.SSHClient, path string) (bool, error) {
+	res, err := client.Run(fmt.Sprintf("%s && echo present || echo missing", sudo("test", "-f").Arg(path)))
+	if err != nil {
+		return false, err
+	}
+	if len(res) != 1 {
+		return false, fmt.Errorf("wrong number of results from %s existence check", path)
+	}
+
+	return strings.TrimSpace(res[0]) == "present", nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
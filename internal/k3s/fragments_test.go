@@ -0,0 +1,99 @@
+package k3s
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigFragments(t *testing.T) {
+	tests := []struct {
+		name        string
+		fragments   map[string]string
+		expectError bool
+	}{
+		{
+			name:      "valid fragments",
+			fragments: map[string]string{"90-cis": "protect-kernel-defaults: true\n", "node.labels": "node-label+:\n  - a=b\n"},
+		},
+		{
+			name:        "extension in name",
+			fragments:   map[string]string{"90-cis.yaml": "protect-kernel-defaults: true\n"},
+			expectError: true,
+		},
+		{
+			name:        "path in name",
+			fragments:   map[string]string{"../config": "protect-kernel-defaults: true\n"},
+			expectError: true,
+		},
+		{
+			name:        "not a mapping",
+			fragments:   map[string]string{"90-cis": "- protect-kernel-defaults\n"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfigFragments(tt.fragments)
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestConfigFragmentCommands(t *testing.T) {
+	commands := configFragmentCommands(map[string]string{"50-provider": "debug: true\n"}, []string{"40-old"})
+
+	if got, want := commands[0], "sudo mkdir -p '/etc/rancher/k3s/config.yaml.d'"; got != want {
+		t.Errorf("configFragmentCommands()[0] = %q, want %q", got, want)
+	}
+	joined := strings.Join(commands, "\n")
+	if !strings.Contains(joined, "/etc/rancher/k3s/config.yaml.d/50-provider.yaml") {
+		t.Errorf("configFragmentCommands() = %q, want managed fragment written", joined)
+	}
+	if got, want := commands[len(commands)-1], "sudo rm -f '/etc/rancher/k3s/config.yaml.d/40-old.yaml'"; got != want {
+		t.Errorf("configFragmentCommands() last = %q, want %q", got, want)
+	}
+
+	if commands := configFragmentCommands(nil, nil); len(commands) != 0 {
+		t.Errorf("configFragmentCommands() = %v, want no commands", commands)
+	}
+}
+
+func TestOutputPaths(t *testing.T) {
+	output := "/etc/rancher/k3s/config.yaml.d/10-base.yaml\n/etc/rancher/k3s/config.yaml.d/20 extra node.yaml\n"
+	want := []string{"/etc/rancher/k3s/config.yaml.d/10-base.yaml", "/etc/rancher/k3s/config.yaml.d/20 extra node.yaml"}
+	if got := outputPaths(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("outputPaths() = %q, want %q", got, want)
+	}
+	if got := outputPaths(""); len(got) != 0 {
+		t.Errorf("outputPaths(\"\") = %q, want none", got)
+	}
+}
+
+func TestMergeConfigDocuments(t *testing.T) {
+	merged, err := mergeConfigDocuments(
+		"write-kubeconfig-mode: \"0600\"\nnode-label:\n  - role=server\n",
+		"write-kubeconfig-mode: \"0644\"\n",
+		"node-label+:\n  - zone=a\nkube-apiserver-arg+: audit-log-maxage=30\n",
+	)
+	if err != nil {
+		t.Fatalf("mergeConfigDocuments() error = %v", err)
+	}
+
+	want := `kube-apiserver-arg:
+- audit-log-maxage=30
+node-label:
+- role=server
+- zone=a
+write-kubeconfig-mode: "0644"
+`
+	if merged != want {
+		t.Errorf("mergeConfigDocuments() = %q, want %q", merged, want)
+	}
+}
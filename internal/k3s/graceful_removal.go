@@ -0,0 +1,186 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Label k3s sets on servers that are members of the embedded etcd cluster.
+const etcdRoleLabel = "node-role.kubernetes.io/etcd"
+
+// Annotations of the k3s etcd member controller. Setting the remove
+// annotation on a Node makes a surviving server remove the member named by
+// the node-name annotation from etcd, which it then records in the
+// removed-node-name annotation.
+const (
+	etcdNodeNameAnnotation        = "etcd.k3s.cattle.io/node-name"
+	etcdRemoveAnnotation          = "etcd.k3s.cattle.io/remove"
+	etcdRemovedNodeNameAnnotation = "etcd.k3s.cattle.io/removed-node-name"
+)
+
+// Removes the server from the cluster before it is uninstalled. An etcd
+// member is drained, removed from etcd by a surviving server and only then
+// is its Node deleted.
+func (s *Server) removeFromCluster(ctx context.Context, client ssh_client.SSHClient) error {
+	removal := s.GracefulRemoval
+	if sameHost(removal.ClusterAuth.Server, client.HostnameOrIPAddress) {
+		return fmt.Errorf("graceful_removal cluster_auth must reach another server than %s", client.HostnameOrIPAddress)
+	}
+
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return err
+	}
+	node, err := nodeName(client, config)
+	if err != nil {
+		return err
+	}
+
+	kube, err := removal.kube()
+	if err != nil {
+		return err
+	}
+	current, err := kube.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		tflog.Debug(ctx, fmt.Sprintf("Node %s is already absent", node))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading node %s: %s", node, err.Error())
+	}
+
+	if current.Labels[etcdRoleLabel] != "true" {
+		return removal.RemoveNode(ctx, node)
+	}
+
+	servers, err := kube.core.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: etcdRoleLabel + "=true"})
+	if err != nil {
+		return fmt.Errorf("listing etcd members: %s", err.Error())
+	}
+	if err := checkEtcdQuorum(servers.Items, node); err != nil {
+		return err
+	}
+
+	found, err := removal.evacuate(ctx, node)
+	if err != nil || !found {
+		return err
+	}
+	if err := removeEtcdMember(ctx, kube, node, removal.timeout()); err != nil {
+		return err
+	}
+	if err := removal.deleteNode(ctx, node); err != nil {
+		return err
+	}
+	return waitForNodeRemoved(ctx, kube, node, removal.timeout())
+}
+
+// Refuses to remove an etcd member when the ready members left afterwards
+// cannot form a quorum of the smaller cluster.
+func checkEtcdQuorum(members []corev1.Node, removing string) error {
+	ready := 0
+	for _, member := range members {
+		if member.Name != removing && nodeReady(member) {
+			ready++
+		}
+	}
+
+	remaining := len(members) - 1
+	if remaining < 1 {
+		return fmt.Errorf("%s is the last etcd member of the cluster and cannot be removed gracefully, unset graceful_removal to uninstall it", removing)
+	}
+	quorum := remaining/2 + 1
+	if ready < quorum {
+		return fmt.Errorf("removing %s leaves %d ready etcd members of %d, below the quorum of %d", removing, ready, remaining, quorum)
+	}
+	return nil
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Asks the surviving servers to remove the etcd member of the node and
+// waits until they report it removed. The finalizer k3s puts on the Node
+// is only cleared once the member is gone, so a Node that disappeared in
+// the meantime was removed as well.
+func removeEtcdMember(ctx context.Context, client *kubeClient, node string, timeout time.Duration) error {
+	current, err := client.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("reading node %s: %s", node, err.Error())
+	}
+	member := current.Annotations[etcdNodeNameAnnotation]
+	if member == "" {
+		return fmt.Errorf("node %s has no %s annotation, its etcd member cannot be identified", node, etcdNodeNameAnnotation)
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Removing etcd member %s of node %s", member, node))
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, etcdRemoveAnnotation))
+	if _, err := client.core.CoreV1().Nodes().Patch(ctx, node, k8stypes.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("requesting removal of etcd member %s: %s", member, err.Error())
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, drainPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := client.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("Could not read node %s: %s", node, err.Error()))
+			return false, nil
+		}
+		return current.Annotations[etcdRemovedNodeNameAnnotation] == member, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("etcd member %s of node %s was not removed within %s", member, node, timeout)
+	}
+	return err
+}
+
+// Waits for the finalizers of a deleted Node to finish.
+func waitForNodeRemoved(ctx context.Context, client *kubeClient, node string, timeout time.Duration) error {
+	var finalizers []string
+	err := wait.PollUntilContextTimeout(ctx, drainPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		current, err := client.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("reading node %s: %s", node, err.Error())
+		}
+		finalizers = current.Finalizers
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("node %s was not removed within %s, node finalizers: %v", node, timeout, finalizers)
+	}
+	return err
+}
+
+// Whether the API server URL points at the host.
+func sameHost(server string, host string) bool {
+	parsed, err := url.Parse(server)
+	if err != nil {
+		return false
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return parsed.Hostname() == host
+}
@@ -0,0 +1,133 @@
+package k3s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func etcdTestNode(name string, ready bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{etcdRoleLabel: "true"}},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+	}
+}
+
+func TestCheckEtcdQuorum(t *testing.T) {
+	tests := []struct {
+		name      string
+		members   []corev1.Node
+		wantError string
+	}{
+		{name: "three healthy", members: []corev1.Node{etcdTestNode("a", true), etcdTestNode("b", true), etcdTestNode("c", true)}},
+		{name: "removing the unhealthy member", members: []corev1.Node{etcdTestNode("a", false), etcdTestNode("b", true), etcdTestNode("c", true)}},
+		{name: "peer not ready", members: []corev1.Node{etcdTestNode("a", true), etcdTestNode("b", false), etcdTestNode("c", true)}, wantError: "below the quorum of 2"},
+		{name: "two members", members: []corev1.Node{etcdTestNode("a", true), etcdTestNode("b", true)}},
+		{name: "five with two down", members: []corev1.Node{etcdTestNode("a", true), etcdTestNode("b", false), etcdTestNode("c", false), etcdTestNode("d", true), etcdTestNode("e", true)}, wantError: "leaves 2 ready etcd members of 4"},
+		{name: "last member", members: []corev1.Node{etcdTestNode("a", true)}, wantError: "last etcd member"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEtcdQuorum(tt.members, "a")
+			if tt.wantError == "" && err != nil {
+				t.Fatalf("checkEtcdQuorum() error = %v", err)
+			}
+			if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+				t.Fatalf("checkEtcdQuorum() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	tests := []struct {
+		server string
+		host   string
+		want   bool
+	}{
+		{"https://10.0.0.1:6443", "10.0.0.1", true},
+		{"https://10.0.0.1:6443", "10.0.0.1:22", true},
+		{"https://10.0.0.2:6443", "10.0.0.1", false},
+		{"https://server-1.example.com:6443", "server-1.example.com", true},
+		{"https://[fd00::1]:6443", "fd00::1", true},
+	}
+	for _, tt := range tests {
+		if got := sameHost(tt.server, tt.host); got != tt.want {
+			t.Errorf("sameHost(%q, %q) = %v, want %v", tt.server, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestWaitForNodeRemoved(t *testing.T) {
+	previous := drainPollInterval
+	drainPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainPollInterval = previous })
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "server-2", Finalizers: []string{"wrangler.cattle.io/managed-etcd-controller"}}}
+	client := &kubeClient{core: fake.NewClientset(node)}
+	err := waitForNodeRemoved(context.Background(), client, "server-2", 30*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "managed-etcd-controller") {
+		t.Fatalf("waitForNodeRemoved() error = %v, want the pending finalizer", err)
+	}
+
+	if err := client.core.CoreV1().Nodes().Delete(context.Background(), "server-2", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForNodeRemoved(context.Background(), client, "server-2", 30*time.Millisecond); err != nil {
+		t.Errorf("waitForNodeRemoved() error = %v after the node was removed", err)
+	}
+}
+
+func TestRemoveEtcdMember(t *testing.T) {
+	previous := drainPollInterval
+	drainPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { drainPollInterval = previous })
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "server-2", Annotations: map[string]string{etcdNodeNameAnnotation: "server-2-1a2b3c4d"}}}
+	core := fake.NewClientset(node)
+	client := &kubeClient{core: core}
+
+	// No surviving server acts on the annotation
+	err := removeEtcdMember(context.Background(), client, "server-2", 30*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "server-2-1a2b3c4d") {
+		t.Fatalf("removeEtcdMember() error = %v, want the member that was not removed", err)
+	}
+
+	// The member controller of a surviving server records the removal
+	nodes := schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+	core.PrependReactor("get", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		obj, err := core.Tracker().Get(nodes, "", "server-2")
+		if err != nil {
+			return false, nil, nil
+		}
+		current := obj.(*corev1.Node)
+		if current.Annotations[etcdRemoveAnnotation] == "true" && current.Annotations[etcdRemovedNodeNameAnnotation] == "" {
+			current.Annotations[etcdRemovedNodeNameAnnotation] = current.Annotations[etcdNodeNameAnnotation]
+			if err := core.Tracker().Update(nodes, current, ""); err != nil {
+				return true, nil, err
+			}
+		}
+		return false, nil, nil
+	})
+	if err := removeEtcdMember(context.Background(), client, "server-2", time.Second); err != nil {
+		t.Errorf("removeEtcdMember() error = %v after the member was removed", err)
+	}
+
+	unnamed := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "server-3"}}
+	client = &kubeClient{core: fake.NewClientset(unnamed)}
+	if err := removeEtcdMember(context.Background(), client, "server-3", time.Second); err == nil {
+		t.Errorf("removeEtcdMember() error = nil for a node without an etcd member name")
+	}
+}
@@ -0,0 +1,510 @@
+package k3s
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.yaml.in/yaml/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+const HELM_CHART_NAMESPACE string = "kube-system"
+
+var (
+	helmChartResource       = schema.GroupVersionResource{Group: "helm.cattle.io", Version: "v1", Resource: "helmcharts"}
+	helmChartConfigResource = schema.GroupVersionResource{Group: "helm.cattle.io", Version: "v1", Resource: "helmchartconfigs"}
+	helmFieldManager        = "terraform-provider-k3s"
+	helmPollInterval        = 5 * time.Second
+	dnsLabelPattern         = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// HelmTarget is where helm controller objects are installed: the manifests
+// directory of a server reached over ssh, or the API of a cluster.
+type HelmTarget struct {
+	// Server to write manifests to, nil when ClusterAuth is set
+	SSH *ssh_client.SSHClient
+	// Data dir of the server, DATA_DIR when empty
+	DataDir     string
+	ClusterAuth *ClusterAuth
+
+	client *kubeClient
+}
+
+func (t *HelmTarget) dataDir() string {
+	if t.DataDir == "" {
+		return DATA_DIR
+	}
+	return t.DataDir
+}
+
+// API clients for the target. Over ssh the kubeconfig of the server is read
+// and pointed at the ssh host.
+func (t *HelmTarget) kube() (*kubeClient, error) {
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	var err error
+	switch {
+	case t.ClusterAuth != nil:
+		t.client, err = newKubeClientForConfig(t.ClusterAuth.restConfig())
+	case t.SSH != nil:
+		var kubeconfig string
+		kubeconfig, err = readKubeConfig(*t.SSH)
+		if err != nil {
+			return nil, err
+		}
+		t.client, err = newKubeClient(kubeconfig)
+	default:
+		err = fmt.Errorf("either ssh auth or cluster_auth is required")
+	}
+	return t.client, err
+}
+
+// HelmChart is a helm.cattle.io/v1 HelmChart installed by the k3s helm
+// controller.
+type HelmChart struct {
+	Name            string
+	Namespace       string
+	Chart           string
+	Repo            string
+	Version         string
+	TargetNamespace string
+	// Values passed to helm with --set
+	Set           map[string]string
+	ValuesContent string
+	// How long to wait for the install job, no waiting when zero
+	Timeout time.Duration
+}
+
+// HelmChartConfig is a helm.cattle.io/v1 HelmChartConfig that overrides the
+// values of the HelmChart with the same name and namespace.
+type HelmChartConfig struct {
+	Name          string
+	Namespace     string
+	ValuesContent string
+	// How long to wait for the chart to be reinstalled, no waiting when zero
+	Timeout time.Duration
+}
+
+// Validate checks the names and values of the chart.
+func (c *HelmChart) Validate() error {
+	// The install job is named helm-install-<name> and job names are
+	// limited to 63 characters
+	if err := validateHelmNames(c.Namespace, c.Name, 63-len(helmJobName(""))); err != nil {
+		return err
+	}
+	if c.Chart == "" {
+		return fmt.Errorf("chart cannot be empty")
+	}
+	return validateValuesContent(c.ValuesContent)
+}
+
+// Validate checks the names and values of the config.
+func (c *HelmChartConfig) Validate() error {
+	if err := validateHelmNames(c.Namespace, c.Name, 63); err != nil {
+		return err
+	}
+	return validateValuesContent(c.ValuesContent)
+}
+
+func validateHelmNames(namespace string, name string, maxName int) error {
+	if len(namespace) > 63 || !dnsLabelPattern.MatchString(namespace) {
+		return fmt.Errorf("namespace %q must be a lowercase DNS label", namespace)
+	}
+	if len(name) > maxName || !dnsLabelPattern.MatchString(name) {
+		return fmt.Errorf("name %q must be a lowercase DNS label of at most %d characters", name, maxName)
+	}
+	return nil
+}
+
+func validateValuesContent(content string) error {
+	var values map[string]any
+	if err := yaml.Unmarshal([]byte(content), &values); err != nil {
+		return fmt.Errorf("values_content must be a YAML mapping: %s", err.Error())
+	}
+	return nil
+}
+
+// A helm controller object and the spec fields the provider manages.
+type helmObject interface {
+	resource() schema.GroupVersionResource
+	object() *unstructured.Unstructured
+	specFields() []string
+	// Manifest name when written to the manifests directory
+	manifestName() string
+}
+
+func (c *HelmChart) resource() schema.GroupVersionResource {
+	return helmChartResource
+}
+
+func (c *HelmChart) object() *unstructured.Unstructured {
+	spec := map[string]any{"chart": c.Chart}
+	optional := map[string]string{
+		"repo":            c.Repo,
+		"version":         c.Version,
+		"targetNamespace": c.TargetNamespace,
+		"valuesContent":   c.ValuesContent,
+	}
+	for key, value := range optional {
+		if value != "" {
+			spec[key] = value
+		}
+	}
+	if len(c.Set) > 0 {
+		set := make(map[string]any, len(c.Set))
+		for key, value := range c.Set {
+			set[key] = value
+		}
+		spec["set"] = set
+	}
+
+	return helmUnstructured("HelmChart", c.Namespace, c.Name, spec)
+}
+
+func (c *HelmChart) specFields() []string {
+	return []string{"chart", "repo", "version", "targetNamespace", "set", "valuesContent"}
+}
+
+func (c *HelmChart) manifestName() string {
+	return fmt.Sprintf("helm-chart.%s.%s", c.Namespace, c.Name)
+}
+
+func (c *HelmChartConfig) resource() schema.GroupVersionResource {
+	return helmChartConfigResource
+}
+
+func (c *HelmChartConfig) object() *unstructured.Unstructured {
+	return helmUnstructured("HelmChartConfig", c.Namespace, c.Name, map[string]any{"valuesContent": c.ValuesContent})
+}
+
+func (c *HelmChartConfig) specFields() []string {
+	return []string{"valuesContent"}
+}
+
+func (c *HelmChartConfig) manifestName() string {
+	return fmt.Sprintf("helm-chart-config.%s.%s", c.Namespace, c.Name)
+}
+
+func helmUnstructured(kind string, namespace string, name string, spec map[string]any) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "helm.cattle.io/v1",
+		"kind":       kind,
+		"metadata":   map[string]any{"name": name, "namespace": namespace},
+		"spec":       spec,
+	}}
+}
+
+// YAML written to the manifests directory.
+func helmManifest(obj helmObject) (string, error) {
+	content, err := yaml.Marshal(obj.object().Object)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Apply installs or updates the chart and waits for its install job when a
+// timeout is set.
+func (c *HelmChart) Apply(ctx context.Context, target *HelmTarget) error {
+	return applyAndWait(ctx, target, c, c.Namespace, c.Name, c.Timeout)
+}
+
+// Apply installs or updates the config and waits for the chart it overrides
+// to be reinstalled when a timeout is set.
+func (c *HelmChartConfig) Apply(ctx context.Context, target *HelmTarget) error {
+	if c.Timeout > 0 {
+		client, err := target.kube()
+		if err != nil {
+			return err
+		}
+		_, err = client.dynamic.Resource(helmChartResource).Namespace(c.Namespace).Get(ctx, c.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			tflog.Info(ctx, fmt.Sprintf("No HelmChart %s/%s to wait for", c.Namespace, c.Name))
+			_, err := applyHelmObject(ctx, target, c)
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("reading helm chart %s/%s: %s", c.Namespace, c.Name, err.Error())
+		}
+	}
+
+	return applyAndWait(ctx, target, c, c.Namespace, c.Name, c.Timeout)
+}
+
+// Applies the object, then waits for the install job of the chart. When the
+// managed spec changed, the job that existed before the change does not
+// count as completed.
+func applyAndWait(ctx context.Context, target *HelmTarget, obj helmObject, namespace string, chart string, timeout time.Duration) error {
+	client, err := target.kube()
+	if err != nil {
+		return err
+	}
+
+	var prior k8stypes.UID
+	job, err := client.core.BatchV1().Jobs(namespace).Get(ctx, helmJobName(chart), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("reading helm job %s/%s: %s", namespace, helmJobName(chart), err.Error())
+	}
+	if err == nil {
+		prior = job.UID
+	}
+
+	changed, err := applyHelmObject(ctx, target, obj)
+	if err != nil {
+		return err
+	}
+	if timeout == 0 {
+		return nil
+	}
+	if !changed {
+		prior = ""
+	}
+
+	return waitForHelmJob(ctx, client, namespace, chart, prior, timeout)
+}
+
+// Writes the object to the manifests directory or applies it through the
+// API, and reports whether its managed spec changed.
+func applyHelmObject(ctx context.Context, target *HelmTarget, obj helmObject) (bool, error) {
+	client, err := target.kube()
+	if err != nil {
+		return false, err
+	}
+
+	desired := obj.object()
+	resource := client.dynamic.Resource(obj.resource()).Namespace(desired.GetNamespace())
+	existing, err := resource.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, fmt.Errorf("reading %s %s/%s: %s", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err.Error())
+	}
+	changed := err != nil || helmSpecChanged(existing, desired, obj.specFields())
+
+	if target.SSH != nil {
+		manifest, err := helmManifest(obj)
+		if err != nil {
+			return false, err
+		}
+		commands := append(
+			[]string{sudo("mkdir", "-p").Arg(target.dataDir() + "/server/manifests").String()},
+			WriteFileCommands(manifestPath(target.dataDir(), obj.manifestName()), base64.StdEncoding.EncodeToString([]byte(manifest)))...,
+		)
+		if err := target.SSH.RunStream(commands); err != nil {
+			return false, fmt.Errorf("writing %s manifest: %s", desired.GetKind(), err.Error())
+		}
+		return changed, nil
+	}
+
+	data, err := json.Marshal(desired.Object)
+	if err != nil {
+		return false, err
+	}
+	force := true
+	if _, err := resource.Patch(ctx, desired.GetName(), k8stypes.ApplyPatchType, data, metav1.PatchOptions{FieldManager: helmFieldManager, Force: &force}); err != nil {
+		return false, fmt.Errorf("applying %s %s/%s: %s", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err.Error())
+	}
+	return changed, nil
+}
+
+// Whether any of the managed spec fields differ between the objects.
+func helmSpecChanged(existing *unstructured.Unstructured, desired *unstructured.Unstructured, fields []string) bool {
+	existingSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	desiredSpec, _, _ := unstructured.NestedMap(desired.Object, "spec")
+	for _, field := range fields {
+		a, _ := json.Marshal(existingSpec[field])
+		b, _ := json.Marshal(desiredSpec[field])
+		if !bytes.Equal(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// JobName returns the name of the install job the helm controller runs for
+// the chart.
+func (c *HelmChart) JobName() string {
+	return helmJobName(c.Name)
+}
+
+func helmJobName(chart string) string {
+	return "helm-install-" + chart
+}
+
+// Waits for the install job of a chart to succeed. A job with the stale UID
+// is one that ran before the latest change and is ignored.
+func waitForHelmJob(ctx context.Context, client *kubeClient, namespace string, chart string, stale k8stypes.UID, timeout time.Duration) error {
+	name := helmJobName(chart)
+	tflog.Info(ctx, fmt.Sprintf("Waiting up to %s for helm job %s/%s", timeout, namespace, name))
+
+	err := wait.PollUntilContextTimeout(ctx, helmPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		job, err := client.core.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("Could not read helm job %s/%s: %s", namespace, name, err.Error()))
+			return false, nil
+		}
+		if stale != "" && job.UID == stale {
+			return false, nil
+		}
+		if job.Status.Succeeded > 0 {
+			return true, nil
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				return false, fmt.Errorf("helm job %s/%s failed: %s%s", namespace, name, condition.Message, helmJobFailure(ctx, client, namespace, name))
+			}
+		}
+		return false, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("helm job %s/%s did not complete within %s%s", namespace, name, timeout, helmJobFailure(context.Background(), client, namespace, name))
+	}
+	return err
+}
+
+// Termination message of a failed pod of the job, to explain why the job
+// has not completed.
+func helmJobFailure(ctx context.Context, client *kubeClient, namespace string, job string) string {
+	pods, err := client.core.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job})
+	if err != nil {
+		return ""
+	}
+
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+				if state.Terminated == nil || state.Terminated.ExitCode == 0 {
+					continue
+				}
+				message := state.Terminated.Message
+				if message == "" {
+					message = state.Terminated.Reason
+				}
+				return fmt.Sprintf(": pod %s exited with code %d: %s", pod.Name, state.Terminated.ExitCode, message)
+			}
+		}
+	}
+	return ""
+}
+
+// Refresh reads the chart from the cluster. Fields the provider does not set
+// are left empty.
+func (c *HelmChart) Refresh(ctx context.Context, target *HelmTarget) (bool, error) {
+	spec, exists, err := readHelmSpec(ctx, target, c)
+	if err != nil || !exists {
+		return exists, err
+	}
+
+	c.Chart, _, _ = unstructured.NestedString(spec, "chart")
+	c.Repo, _, _ = unstructured.NestedString(spec, "repo")
+	c.Version, _, _ = unstructured.NestedString(spec, "version")
+	c.TargetNamespace, _, _ = unstructured.NestedString(spec, "targetNamespace")
+	c.ValuesContent, _, _ = unstructured.NestedString(spec, "valuesContent")
+	c.Set = nil
+	if set, ok := spec["set"].(map[string]any); ok && len(set) > 0 {
+		c.Set = make(map[string]string, len(set))
+		for key, value := range set {
+			c.Set[key] = fmt.Sprint(value)
+		}
+	}
+
+	return true, nil
+}
+
+// Refresh reads the config from the cluster.
+func (c *HelmChartConfig) Refresh(ctx context.Context, target *HelmTarget) (bool, error) {
+	spec, exists, err := readHelmSpec(ctx, target, c)
+	if err != nil || !exists {
+		return exists, err
+	}
+
+	c.ValuesContent, _, _ = unstructured.NestedString(spec, "valuesContent")
+	return true, nil
+}
+
+func readHelmSpec(ctx context.Context, target *HelmTarget, obj helmObject) (map[string]any, bool, error) {
+	client, err := target.kube()
+	if err != nil {
+		return nil, false, err
+	}
+
+	desired := obj.object()
+	existing, err := client.dynamic.Resource(obj.resource()).Namespace(desired.GetNamespace()).Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s %s/%s: %s", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err.Error())
+	}
+
+	spec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+	return spec, true, nil
+}
+
+// Delete removes the chart. The helm controller uninstalls the release
+// before the chart is gone, which is waited for when a timeout is set.
+func (c *HelmChart) Delete(ctx context.Context, target *HelmTarget) error {
+	return deleteHelmObject(ctx, target, c, c.Timeout)
+}
+
+// Delete removes the config. The chart it overrides is reinstalled with its
+// own values.
+func (c *HelmChartConfig) Delete(ctx context.Context, target *HelmTarget) error {
+	return deleteHelmObject(ctx, target, c, 0)
+}
+
+// Removes the manifest and its Addon first when installed over ssh, so the
+// deploy controller does not apply the object again.
+func deleteHelmObject(ctx context.Context, target *HelmTarget, obj helmObject, timeout time.Duration) error {
+	client, err := target.kube()
+	if err != nil {
+		return err
+	}
+
+	desired := obj.object()
+	if target.SSH != nil {
+		if err := target.SSH.RunStream([]string{sudo("rm", "-f").Arg(manifestPath(target.dataDir(), obj.manifestName())).String()}); err != nil {
+			return fmt.Errorf("removing %s manifest: %s", desired.GetKind(), err.Error())
+		}
+		err := client.dynamic.Resource(addonResource).Namespace("kube-system").Delete(ctx, obj.manifestName(), metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("deleting addon %s: %s", obj.manifestName(), err.Error())
+		}
+	}
+
+	resource := client.dynamic.Resource(obj.resource()).Namespace(desired.GetNamespace())
+	err = resource.Delete(ctx, desired.GetName(), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && timeout == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting %s %s/%s: %s", desired.GetKind(), desired.GetNamespace(), desired.GetName(), err.Error())
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, helmPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, err := resource.Get(ctx, desired.GetName(), metav1.GetOptions{})
+		return apierrors.IsNotFound(err), nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("%s %s/%s was not removed within %s", desired.GetKind(), desired.GetNamespace(), desired.GetName(), timeout)
+	}
+	return err
+}
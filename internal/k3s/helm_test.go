@@ -0,0 +1,175 @@
+package k3s
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHelmChartValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		chart       HelmChart
+		expectError bool
+	}{
+		{name: "valid", chart: HelmChart{Name: "grafana", Namespace: "kube-system", Chart: "grafana", ValuesContent: "replicas: 2\n"}},
+		{name: "missing chart", chart: HelmChart{Name: "grafana", Namespace: "kube-system"}, expectError: true},
+		{name: "uppercase name", chart: HelmChart{Name: "Grafana", Namespace: "kube-system", Chart: "grafana"}, expectError: true},
+		{name: "name too long for job", chart: HelmChart{Name: strings.Repeat("a", 51), Namespace: "kube-system", Chart: "grafana"}, expectError: true},
+		{name: "invalid namespace", chart: HelmChart{Name: "grafana", Namespace: "kube_system", Chart: "grafana"}, expectError: true},
+		{name: "values not a mapping", chart: HelmChart{Name: "grafana", Namespace: "kube-system", Chart: "grafana", ValuesContent: "- a\n"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.chart.Validate()
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestHelmChartManifest(t *testing.T) {
+	chart := &HelmChart{
+		Name:      "grafana",
+		Namespace: "monitoring",
+		Chart:     "grafana",
+		Repo:      "https://grafana.github.io/helm-charts",
+		Set:       map[string]string{"replicas": "2"},
+	}
+
+	manifest, err := helmManifest(chart)
+	if err != nil {
+		t.Fatalf("helmManifest() error = %v", err)
+	}
+	for _, want := range []string{"apiVersion: helm.cattle.io/v1", "kind: HelmChart", "namespace: monitoring", "repo: https://grafana.github.io/helm-charts", `replicas: "2"`} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("helmManifest() = %q, want %q", manifest, want)
+		}
+	}
+	for _, unwanted := range []string{"version", "targetNamespace", "valuesContent"} {
+		if strings.Contains(manifest, unwanted) {
+			t.Errorf("helmManifest() = %q, want no %s", manifest, unwanted)
+		}
+	}
+	if got, want := chart.manifestName(), "helm-chart.monitoring.grafana"; got != want {
+		t.Errorf("manifestName() = %q, want %q", got, want)
+	}
+}
+
+func TestHelmSpecChanged(t *testing.T) {
+	chart := &HelmChart{Name: "grafana", Namespace: "kube-system", Chart: "grafana", Set: map[string]string{"replicas": "2"}}
+	existing := chart.object()
+	// Fields set by other controllers are not managed
+	existing.Object["spec"].(map[string]any)["failurePolicy"] = "reinstall"
+
+	if helmSpecChanged(existing, chart.object(), chart.specFields()) {
+		t.Errorf("helmSpecChanged() = true for an unchanged chart")
+	}
+
+	chart.Version = "8.0.0"
+	if !helmSpecChanged(existing, chart.object(), chart.specFields()) {
+		t.Errorf("helmSpecChanged() = false after setting a version")
+	}
+}
+
+func TestHelmChartRefresh(t *testing.T) {
+	existing := (&HelmChart{
+		Name:          "grafana",
+		Namespace:     "kube-system",
+		Chart:         "grafana",
+		Version:       "8.0.0",
+		Set:           map[string]string{"replicas": "2"},
+		ValuesContent: "a: b\n",
+	}).object()
+	dynamic := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		helmChartResource: "HelmChartList",
+	}, existing)
+	target := &HelmTarget{client: &kubeClient{core: fake.NewClientset(), dynamic: dynamic}}
+
+	chart := HelmChart{Name: "grafana", Namespace: "kube-system"}
+	exists, err := chart.Refresh(context.Background(), target)
+	if err != nil || !exists {
+		t.Fatalf("Refresh() = %v, %v, want true", exists, err)
+	}
+	if chart.Chart != "grafana" || chart.Version != "8.0.0" || chart.Set["replicas"] != "2" || chart.ValuesContent != "a: b\n" {
+		t.Errorf("Refresh() read %+v", chart)
+	}
+
+	missing := HelmChart{Name: "loki", Namespace: "kube-system"}
+	if exists, err := missing.Refresh(context.Background(), target); err != nil || exists {
+		t.Errorf("Refresh() = %v, %v for a missing chart, want false", exists, err)
+	}
+}
+
+func TestWaitForHelmJob(t *testing.T) {
+	previous := helmPollInterval
+	helmPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { helmPollInterval = previous })
+
+	job := func(uid string, status batchv1.JobStatus) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "helm-install-grafana", Namespace: "kube-system", UID: k8stypes.UID(uid)},
+			Status:     status,
+		}
+	}
+	failed := batchv1.JobStatus{Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}}
+
+	tests := []struct {
+		name      string
+		job       *batchv1.Job
+		stale     string
+		wantError string
+	}{
+		{name: "succeeded", job: job("a", batchv1.JobStatus{Succeeded: 1})},
+		{name: "stale job", job: job("a", batchv1.JobStatus{Succeeded: 1}), stale: "a", wantError: "did not complete"},
+		{name: "new job", job: job("b", batchv1.JobStatus{Succeeded: 1}), stale: "a"},
+		{name: "failed", job: job("a", failed), wantError: "BackoffLimitExceeded"},
+		{name: "missing", wantError: "did not complete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if tt.job != nil {
+				objects = append(objects, tt.job)
+			}
+			client := &kubeClient{core: fake.NewClientset(objects...)}
+
+			err := waitForHelmJob(context.Background(), client, "kube-system", "grafana", k8stypes.UID(tt.stale), 50*time.Millisecond)
+			if tt.wantError == "" && err != nil {
+				t.Fatalf("waitForHelmJob() error = %v", err)
+			}
+			if tt.wantError != "" && (err == nil || !strings.Contains(err.Error(), tt.wantError)) {
+				t.Fatalf("waitForHelmJob() error = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestHelmChartConfigObject(t *testing.T) {
+	config := &HelmChartConfig{Name: "traefik", Namespace: "kube-system", ValuesContent: "ports: {}\n"}
+	object := config.object()
+
+	if object.GetKind() != "HelmChartConfig" {
+		t.Errorf("object() kind = %q, want HelmChartConfig", object.GetKind())
+	}
+	if values, _, _ := unstructured.NestedString(object.Object, "spec", "valuesContent"); values != config.ValuesContent {
+		t.Errorf("object() valuesContent = %q, want %q", values, config.ValuesContent)
+	}
+}
@@ -0,0 +1,60 @@
+package k3s
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Kubernetes API clients for a server, built from its rewritten kubeconfig.
+type kubeClient struct {
+	core    kubernetes.Interface
+	dynamic dynamic.Interface
+}
+
+// ClusterAuth is the API endpoint and PEM encoded client credentials of a
+// cluster, as exposed by the cluster_auth attribute of k3s_server.
+type ClusterAuth struct {
+	Server                   string
+	CertificateAuthorityData string
+	ClientCertificateData    string
+	ClientKeyData            string
+}
+
+func (a ClusterAuth) restConfig() *rest.Config {
+	return &rest.Config{
+		Host: a.Server,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData:   []byte(a.CertificateAuthorityData),
+			CertData: []byte(a.ClientCertificateData),
+			KeyData:  []byte(a.ClientKeyData),
+		},
+	}
+}
+
+func newKubeClient(kubeconfig string) (*kubeClient, error) {
+	config, err := clientcmd.RESTConfigFromKubeConfig([]byte(kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig: %s", err.Error())
+	}
+	return newKubeClientForConfig(config)
+}
+
+func newKubeClientForConfig(config *rest.Config) (*kubeClient, error) {
+	config.Timeout = 30 * time.Second
+
+	core, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %s", err.Error())
+	}
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("creating kubernetes client: %s", err.Error())
+	}
+
+	return &kubeClient{core: core, dynamic: dyn}, nil
+}
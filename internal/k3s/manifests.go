@@ -0,0 +1,203 @@
+package k3s
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.yaml.in/yaml/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	MANIFEST_APPLIED string = "applied"
+	MANIFEST_PENDING string = "pending"
+	MANIFEST_FAILED  string = "failed"
+	MANIFEST_UNKNOWN string = "unknown"
+)
+
+var (
+	manifestNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	addonResource       = schema.GroupVersionResource{Group: "k3s.cattle.io", Version: "v1", Resource: "addons"}
+	// Manifests k3s writes itself on every start.
+	packagedManifests = []string{"ccm", "coredns", "local-storage", "metrics-server", "rolebindings", "runtimes", "traefik"}
+)
+
+// ManifestStatus is the apply status of an auto-deploy manifest, as recorded
+// by the k3s deploy controller on its Addon object.
+type ManifestStatus struct {
+	Status  string
+	Message string
+}
+
+// ValidateManifests checks that every manifest has a usable addon name and
+// contains Kubernetes objects.
+func ValidateManifests(manifests map[string]string) error {
+	for _, name := range sortedKeys(manifests) {
+		if len(name) > 253 || !manifestNamePattern.MatchString(name) || strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+			return fmt.Errorf("manifest name %q must be a lowercase DNS subdomain without the .yaml extension", name)
+		}
+		if slices.Contains(packagedManifests, name) {
+			return fmt.Errorf("manifest name %q is written by k3s on every start, use a different name", name)
+		}
+		if err := validateManifestDocuments(manifests[name]); err != nil {
+			return fmt.Errorf("manifest %q: %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Every document must be a Kubernetes object with an apiVersion, a kind and
+// a name.
+func validateManifestDocuments(content string) error {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	objects := 0
+	for i := 1; ; i++ {
+		var document map[string]any
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("parsing document %d: %s", i, err.Error())
+		}
+		if document == nil {
+			continue
+		}
+
+		for _, key := range []string{"apiVersion", "kind"} {
+			if value, ok := document[key].(string); !ok || value == "" {
+				return fmt.Errorf("document %d is missing %s", i, key)
+			}
+		}
+		metadata, _ := document["metadata"].(map[any]any)
+		if name, ok := metadata["name"].(string); !ok || name == "" {
+			return fmt.Errorf("document %d is missing metadata.name", i)
+		}
+		objects++
+	}
+
+	if objects == 0 {
+		return fmt.Errorf("no Kubernetes objects found")
+	}
+	return nil
+}
+
+func manifestPath(dataDir string, name string) string {
+	return fmt.Sprintf("%s/server/manifests/%s.yaml", dataDir, name)
+}
+
+func manifestPaths(dataDir string, names ...[]string) (paths []string) {
+	for _, list := range names {
+		for _, name := range list {
+			paths = append(paths, manifestPath(dataDir, name))
+		}
+	}
+	return
+}
+
+// Commands for writing managed manifests and removing the ones that are no
+// longer declared. k3s picks up changes in the directory on its own.
+func manifestCommands(dataDir string, manifests map[string]string, stale []string) []string {
+	if len(manifests) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	commands := []string{sudo("mkdir", "-p").Arg(dataDir + "/server/manifests").String()}
+	for _, name := range sortedKeys(manifests) {
+		commands = append(commands, WriteFileCommands(manifestPath(dataDir, name), base64.StdEncoding.EncodeToString([]byte(manifests[name])))...)
+	}
+	for _, name := range stale {
+		commands = append(commands, sudo("rm", "-f").Arg(manifestPath(dataDir, name)).String())
+	}
+
+	return commands
+}
+
+// Reads the apply status of every manifest from its Addon object. The deploy
+// controller stores the checksum of the last applied content on the Addon
+// and records a warning event when applying fails.
+func readManifestStatus(ctx context.Context, kubeconfig string, manifests map[string]string) (map[string]ManifestStatus, error) {
+	client, err := newKubeClient(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]ManifestStatus, len(manifests))
+	for _, name := range sortedKeys(manifests) {
+		addon, err := client.dynamic.Resource(addonResource).Namespace("kube-system").Get(ctx, name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("reading addon %s: %s", name, err.Error())
+		}
+		if err == nil {
+			checksum, _, _ := unstructured.NestedString(addon.Object, "spec", "checksum")
+			if checksum == sha256Hex(manifests[name]) {
+				statuses[name] = ManifestStatus{Status: MANIFEST_APPLIED}
+				continue
+			}
+		}
+
+		events, err := client.core.CoreV1().Events("kube-system").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.Set{"involvedObject.kind": "Addon", "involvedObject.name": name}.String(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading events for addon %s: %s", name, err.Error())
+		}
+		statuses[name] = manifestStatusFromEvents(events.Items)
+	}
+
+	return statuses, nil
+}
+
+// A manifest that is not applied yet is failed when the most recent deploy
+// event is a failure, and pending otherwise.
+func manifestStatusFromEvents(events []corev1.Event) ManifestStatus {
+	if len(events) == 0 {
+		return ManifestStatus{Status: MANIFEST_PENDING}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventTime(events[i]).Before(eventTime(events[j]))
+	})
+	latest := events[len(events)-1]
+	if latest.Reason == "ApplyManifestFailed" {
+		return ManifestStatus{Status: MANIFEST_FAILED, Message: latest.Message}
+	}
+
+	return ManifestStatus{Status: MANIFEST_PENDING, Message: latest.Message}
+}
+
+func eventTime(event corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// Status for every manifest when the API could not be reached.
+func unknownManifestStatus(ctx context.Context, manifests map[string]string, err error) map[string]ManifestStatus {
+	tflog.Warn(ctx, fmt.Sprintf("Could not read manifest status: %s", err.Error()))
+
+	statuses := make(map[string]ManifestStatus, len(manifests))
+	for name := range manifests {
+		statuses[name] = ManifestStatus{Status: MANIFEST_UNKNOWN, Message: err.Error()}
+	}
+	return statuses
+}
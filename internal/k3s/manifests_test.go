@@ -0,0 +1,97 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateManifests(t *testing.T) {
+	configMap := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n  namespace: kube-system\n"
+	tests := []struct {
+		name        string
+		manifest    string
+		content     string
+		expectError bool
+	}{
+		{name: "single document", manifest: "coredns-custom", content: configMap},
+		{name: "multiple documents", manifest: "rbac", content: "---\n" + configMap + "---\n" + configMap + "---\n"},
+		{name: "uppercase name", manifest: "CoreDNS", content: configMap, expectError: true},
+		{name: "yaml extension", manifest: "rbac.yaml", content: configMap, expectError: true},
+		{name: "path in name", manifest: "../rbac", content: configMap, expectError: true},
+		{name: "packaged manifest", manifest: "traefik", content: configMap, expectError: true},
+		{name: "empty", manifest: "empty", content: "---\n", expectError: true},
+		{name: "invalid yaml", manifest: "broken", content: "kind: [", expectError: true},
+		{name: "missing kind", manifest: "nokind", content: "apiVersion: v1\nmetadata:\n  name: a\n", expectError: true},
+		{name: "missing name", manifest: "noname", content: "apiVersion: v1\nkind: ConfigMap\n", expectError: true},
+		{name: "second document invalid", manifest: "second", content: configMap + "---\nkind: ConfigMap\n", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateManifests(map[string]string{tt.manifest: tt.content})
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestManifestCommands(t *testing.T) {
+	if commands := manifestCommands(DATA_DIR, nil, nil); len(commands) != 0 {
+		t.Fatalf("manifestCommands() = %v, want none", commands)
+	}
+
+	joined := strings.Join(manifestCommands(DATA_DIR, map[string]string{"rbac": "a"}, []string{"old"}), "\n")
+	for _, want := range []string{
+		"sudo mkdir -p '/var/lib/rancher/k3s/server/manifests'",
+		"'/var/lib/rancher/k3s/server/manifests/rbac.yaml'",
+		"sudo rm -f '/var/lib/rancher/k3s/server/manifests/old.yaml'",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("manifestCommands() = %q, want %q", joined, want)
+		}
+	}
+}
+
+func TestManifestStatusFromEvents(t *testing.T) {
+	at := func(seconds int64, reason string, message string) corev1.Event {
+		return corev1.Event{
+			Reason:        reason,
+			Message:       message,
+			LastTimestamp: metav1.NewTime(time.Unix(seconds, 0)),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		events []corev1.Event
+		want   ManifestStatus
+	}{
+		{name: "no events", want: ManifestStatus{Status: MANIFEST_PENDING}},
+		{
+			name:   "latest failed",
+			events: []corev1.Event{at(2, "ApplyManifestFailed", "bad"), at(1, "ApplyingManifest", "applying")},
+			want:   ManifestStatus{Status: MANIFEST_FAILED, Message: "bad"},
+		},
+		{
+			name:   "retried after failure",
+			events: []corev1.Event{at(1, "ApplyManifestFailed", "bad"), at(2, "ApplyingManifest", "applying")},
+			want:   ManifestStatus{Status: MANIFEST_PENDING, Message: "applying"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manifestStatusFromEvents(tt.events); got != tt.want {
+				t.Errorf("manifestStatusFromEvents() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+
+	"go.yaml.in/yaml/v2"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Name of the node as registered by k3s. With with-node-id k3s appends a
+// random id to the name, which only the registered node knows, so the name
+// is read from the kubelet client certificate instead.
+func nodeName(client ssh_client.SSHClient, config map[any]any) (string, error) {
+	if configBool(config, "with-node-id") {
+		content, err := client.ReadFile(configDataDir(config)+"/agent/client-kubelet.crt", false, true)
+		if err != nil {
+			return "", fmt.Errorf("reading the kubelet certificate for the name of a with-node-id node: %s", err.Error())
+		}
+		return kubeletNodeName(content)
+	}
+	if name, ok := config["node-name"].(string); ok && name != "" {
+		return name, nil
+	}
+
+	hostname, err := client.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("reading hostname: %s", err.Error())
+	}
+	return strings.ToLower(hostname), nil
+}
+
+// Node name of a kubelet client certificate, issued to system:node:<name>.
+func kubeletNodeName(content string) (string, error) {
+	cert, err := firstCertificate(content)
+	if err != nil {
+		return "", fmt.Errorf("parsing the kubelet certificate: %s", err.Error())
+	}
+	name, ok := strings.CutPrefix(cert.Subject.CommonName, "system:node:")
+	if !ok || name == "" {
+		return "", fmt.Errorf("kubelet certificate is issued to %q, not to a node", cert.Subject.CommonName)
+	}
+	return name, nil
+}
+
+// Config as k3s sees it, merged from config.yaml and every drop-in.
+func readObservedConfig(client ssh_client.SSHClient) (map[any]any, error) {
+	merged, err := readMergedConfig(client)
+	if err != nil {
+		return nil, err
+	}
+	return parseMergedConfig(merged)
+}
+
+func parseMergedConfig(merged string) (map[any]any, error) {
+	config := make(map[any]any)
+	if err := yaml.Unmarshal([]byte(merged), &config); err != nil {
+		return nil, fmt.Errorf("parsing merged config: %s", err.Error())
+	}
+	return config, nil
+}
+
+// Whether the server runs without an agent, and so without a Node.
+func configDisablesAgent(config map[any]any) bool {
+	return configBool(config, "disable-agent")
+}
@@ -0,0 +1,107 @@
+package k3s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// NodeInfo identifies the Kubernetes node running on a host.
+type NodeInfo struct {
+	NodeName string
+	// InternalIP and ExternalIP addresses of the Node, empty until it
+	// registered
+	NodeIPs     []string
+	ExternalIPs []string
+	// As reported by the Node, or by uname before it registered
+	OS     string
+	Arch   string
+	Kernel string
+}
+
+// Architectures reported by uname, as Go and Kubernetes name them.
+var unameArchitectures = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i686":    "386",
+}
+
+// Reads the node name and host facts over ssh, then the addresses and
+// system info of the Node through the kubelet kubeconfig. A Node that
+// cannot be read leaves the host facts in place.
+func readNodeInfo(ctx context.Context, client ssh_client.SSHClient, binDir string) (*NodeInfo, error) {
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return nil, err
+	}
+	name, err := nodeName(client, config)
+	if err != nil {
+		return nil, err
+	}
+	info := &NodeInfo{NodeName: name}
+
+	res, err := client.Run("uname -srm")
+	if err != nil {
+		return nil, fmt.Errorf("reading host system info: %s", err.Error())
+	}
+	if len(res) == 1 {
+		info.parseUname(res[0])
+	}
+
+	kubeconfig := configDataDir(config) + "/agent/kubelet.kubeconfig"
+	command := kubeletKubectl(binDir, kubeconfig).Literal("get", "node").Arg(name).Literal("-o", "json", "2>&1", "||", "true")
+	res, err = client.Run(command.String())
+	if err != nil {
+		return nil, err
+	}
+	var node corev1.Node
+	if len(res) != 1 || json.Unmarshal([]byte(res[0]), &node) != nil {
+		tflog.Debug(ctx, fmt.Sprintf("Could not read node %s, using host facts only", name))
+		return info, nil
+	}
+	info.applyNode(node)
+	return info, nil
+}
+
+// Parses `uname -srm`, which prints the kernel name, release and machine.
+func (i *NodeInfo) parseUname(output string) {
+	fields := strings.Fields(output)
+	if len(fields) != 3 {
+		return
+	}
+	i.OS = strings.ToLower(fields[0])
+	i.Kernel = fields[1]
+	i.Arch = fields[2]
+	if arch, ok := unameArchitectures[fields[2]]; ok {
+		i.Arch = arch
+	}
+}
+
+func (i *NodeInfo) applyNode(node corev1.Node) {
+	for _, address := range node.Status.Addresses {
+		switch address.Type {
+		case corev1.NodeInternalIP:
+			i.NodeIPs = append(i.NodeIPs, address.Address)
+		case corev1.NodeExternalIP:
+			i.ExternalIPs = append(i.ExternalIPs, address.Address)
+		}
+	}
+	system := node.Status.NodeInfo
+	if system.OperatingSystem != "" {
+		i.OS = system.OperatingSystem
+	}
+	if system.Architecture != "" {
+		i.Arch = system.Architecture
+	}
+	if system.KernelVersion != "" {
+		i.Kernel = system.KernelVersion
+	}
+}
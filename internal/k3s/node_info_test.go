@@ -0,0 +1,61 @@
+package k3s
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNodeInfoParseUname(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   NodeInfo
+	}{
+		{name: "x86_64", output: "Linux 6.8.0-45-generic x86_64\n", want: NodeInfo{OS: "linux", Kernel: "6.8.0-45-generic", Arch: "amd64"}},
+		{name: "aarch64", output: "Linux 6.1.0-rpi7-rpi-v8 aarch64", want: NodeInfo{OS: "linux", Kernel: "6.1.0-rpi7-rpi-v8", Arch: "arm64"}},
+		{name: "unmapped machine", output: "Linux 6.6.0 riscv64", want: NodeInfo{OS: "linux", Kernel: "6.6.0", Arch: "riscv64"}},
+		{name: "unexpected output", output: "uname: command not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info NodeInfo
+			info.parseUname(tt.output)
+			if !reflect.DeepEqual(info, tt.want) {
+				t.Errorf("parseUname() = %+v, want %+v", info, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeInfoApplyNode(t *testing.T) {
+	output := `{"kind":"Node","status":{
+		"addresses":[
+			{"type":"InternalIP","address":"10.0.0.5"},
+			{"type":"InternalIP","address":"fd00::5"},
+			{"type":"ExternalIP","address":"203.0.113.5"},
+			{"type":"Hostname","address":"server-1"}
+		],
+		"nodeInfo":{"operatingSystem":"linux","architecture":"arm64","kernelVersion":"6.8.0-45-generic"}
+	}}`
+	var node corev1.Node
+	if err := json.Unmarshal([]byte(output), &node); err != nil {
+		t.Fatal(err)
+	}
+
+	info := NodeInfo{NodeName: "server-1", OS: "linux", Arch: "amd64", Kernel: "6.8.0"}
+	info.applyNode(node)
+	want := NodeInfo{NodeName: "server-1", NodeIPs: []string{"10.0.0.5", "fd00::5"}, ExternalIPs: []string{"203.0.113.5"}, OS: "linux", Arch: "arm64", Kernel: "6.8.0-45-generic"}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("applyNode() = %+v, want %+v", info, want)
+	}
+
+	// A Node without addresses or system info keeps the host facts
+	info = NodeInfo{NodeName: "server-1", OS: "linux", Arch: "amd64", Kernel: "6.8.0"}
+	info.applyNode(corev1.Node{})
+	if want := (NodeInfo{NodeName: "server-1", OS: "linux", Arch: "amd64", Kernel: "6.8.0"}); !reflect.DeepEqual(info, want) {
+		t.Errorf("applyNode() = %+v, want %+v", info, want)
+	}
+}
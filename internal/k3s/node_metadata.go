@@ -0,0 +1,296 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Taint is a taint of a Node, identified by its key and effect.
+type Taint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+func (t Taint) matches(taint corev1.Taint) bool {
+	return taint.Key == t.Key && string(taint.Effect) == t.Effect
+}
+
+var taintEffects = []string{
+	string(corev1.TaintEffectNoSchedule),
+	string(corev1.TaintEffectPreferNoSchedule),
+	string(corev1.TaintEffectNoExecute),
+}
+
+// NodeMetadata are the labels and taints the provider owns on the Node of a
+// host. They are reconciled through the API after the node registered, so
+// unlike node-label and node-taint in config they change on existing nodes.
+// Labels and taints set by anything else are left alone.
+type NodeMetadata struct {
+	Labels map[string]string
+	Taints []Taint
+	// Previously owned labels and taints that should be removed
+	StaleLabels []string
+	StaleTaints []Taint
+	// API credentials, the kubeconfig of the server itself when nil
+	ClusterAuth *ClusterAuth
+
+	client *kubeClient
+}
+
+func (m *NodeMetadata) Validate() error {
+	for _, key := range sortedKeys(m.Labels) {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("label key %q is invalid: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(m.Labels[key]); len(errs) > 0 {
+			return fmt.Errorf("value of label %s is invalid: %s", key, strings.Join(errs, ", "))
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, taint := range m.Taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) > 0 {
+			return fmt.Errorf("taint key %q is invalid: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) > 0 {
+			return fmt.Errorf("value of taint %s is invalid: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if !slices.Contains(taintEffects, taint.Effect) {
+			return fmt.Errorf("effect of taint %s must be one of %s", taint.Key, strings.Join(taintEffects, ", "))
+		}
+		id := taint.Key + ":" + taint.Effect
+		if seen[id] {
+			return fmt.Errorf("taint %s is declared more than once", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// Whether there is anything to reconcile.
+func (m *NodeMetadata) managed() bool {
+	return m != nil && len(m.Labels)+len(m.Taints)+len(m.StaleLabels)+len(m.StaleTaints) > 0
+}
+
+func (m *NodeMetadata) kube(kubeconfig string) (*kubeClient, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	var client *kubeClient
+	var err error
+	if m.ClusterAuth != nil {
+		client, err = newKubeClientForConfig(m.ClusterAuth.restConfig())
+	} else {
+		client, err = newKubeClient(kubeconfig)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.client = client
+	return client, nil
+}
+
+// Apply sets the owned labels and taints on the Node of the host and removes
+// the stale ones, waiting up to timeout for the node to register.
+func (m *NodeMetadata) Apply(ctx context.Context, client ssh_client.SSHClient, kubeconfig string, timeout time.Duration) error {
+	if !m.managed() {
+		return nil
+	}
+	node, err := hostNodeName(client)
+	if err != nil {
+		return err
+	}
+	kube, err := m.kube(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if timeout == 0 {
+		timeout = NODE_READY_TIMEOUT
+	}
+	err = wait.PollUntilContextTimeout(ctx, nodeReadyPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		_, err := kube.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			tflog.Debug(ctx, fmt.Sprintf("Could not read node %s: %s", node, err.Error()))
+			return false, nil
+		}
+		return true, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("node %s did not register within %s", node, timeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Reconciling labels and taints of node %s", node))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := kube.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if !m.apply(current) {
+			return nil
+		}
+		_, err = kube.core.CoreV1().Nodes().Update(ctx, current, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// Refresh reads the owned labels and taints back from the Node, dropping
+// those that were removed. A Node that is gone owns nothing.
+func (m *NodeMetadata) Refresh(ctx context.Context, client ssh_client.SSHClient, kubeconfig string) error {
+	if m == nil || len(m.Labels)+len(m.Taints) == 0 {
+		return nil
+	}
+	node, err := hostNodeName(client)
+	if err != nil {
+		return err
+	}
+	kube, err := m.kube(kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	current, err := kube.core.CoreV1().Nodes().Get(ctx, node, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		m.observe(corev1.Node{})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading node %s: %s", node, err.Error())
+	}
+	m.observe(*current)
+	return nil
+}
+
+// ApplyNodeMetadata reconciles the owned labels and taints of the server
+// node through its own API.
+func (s *Server) ApplyNodeMetadata(ctx context.Context, client ssh_client.SSHClient) error {
+	if !s.NodeMetadata.managed() {
+		return nil
+	}
+	if s.KubeConfig == "" {
+		kubeConfig, err := readKubeConfig(client)
+		if err != nil {
+			return err
+		}
+		s.KubeConfig = kubeConfig
+	}
+	return s.NodeMetadata.Apply(ctx, client, s.KubeConfig, s.ReadyTimeout)
+}
+
+// ApplyNodeMetadata reconciles the owned labels and taints of the agent
+// node through the API of a server.
+func (a *Agent) ApplyNodeMetadata(ctx context.Context, client ssh_client.SSHClient) error {
+	return a.NodeMetadata.Apply(ctx, client, "", a.ReadyTimeout)
+}
+
+func hostNodeName(client ssh_client.SSHClient) (string, error) {
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return "", err
+	}
+	return nodeName(client, config)
+}
+
+// Sets the owned labels and taints on the node, removing the stale ones.
+// Reports whether the node changed.
+func (m *NodeMetadata) apply(node *corev1.Node) bool {
+	changed := false
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for _, key := range m.StaleLabels {
+		if _, owned := m.Labels[key]; owned {
+			continue
+		}
+		if _, ok := node.Labels[key]; ok {
+			delete(node.Labels, key)
+			changed = true
+		}
+	}
+	for key, value := range m.Labels {
+		if current, ok := node.Labels[key]; !ok || current != value {
+			node.Labels[key] = value
+			changed = true
+		}
+	}
+
+	owned := append(append([]Taint{}, m.Taints...), m.StaleTaints...)
+	taints := make([]corev1.Taint, 0, len(node.Spec.Taints)+len(m.Taints))
+	for _, taint := range node.Spec.Taints {
+		if !matchesAny(owned, taint) {
+			taints = append(taints, taint)
+		}
+	}
+	for _, taint := range m.Taints {
+		taints = append(taints, corev1.Taint{Key: taint.Key, Value: taint.Value, Effect: corev1.TaintEffect(taint.Effect)})
+	}
+	if !sameTaints(node.Spec.Taints, taints) {
+		node.Spec.Taints = taints
+		changed = true
+	}
+	return changed
+}
+
+// Replaces the owned labels and taints with what the node carries.
+func (m *NodeMetadata) observe(node corev1.Node) {
+	labels := map[string]string{}
+	for key := range m.Labels {
+		if value, ok := node.Labels[key]; ok {
+			labels[key] = value
+		}
+	}
+	m.Labels = labels
+
+	var taints []Taint
+	for _, owned := range m.Taints {
+		for _, taint := range node.Spec.Taints {
+			if owned.matches(taint) {
+				taints = append(taints, Taint{Key: taint.Key, Value: taint.Value, Effect: string(taint.Effect)})
+				break
+			}
+		}
+	}
+	m.Taints = taints
+}
+
+func matchesAny(owned []Taint, taint corev1.Taint) bool {
+	for _, t := range owned {
+		if t.matches(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether both lists hold the same taints, in any order.
+func sameTaints(a []corev1.Taint, b []corev1.Taint) bool {
+	keys := func(taints []corev1.Taint) []string {
+		keys := make([]string, 0, len(taints))
+		for _, t := range taints {
+			keys = append(keys, t.Key+"="+t.Value+":"+string(t.Effect))
+		}
+		slices.Sort(keys)
+		return keys
+	}
+	return slices.Equal(keys(a), keys(b))
+}
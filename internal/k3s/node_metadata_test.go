@@ -0,0 +1,123 @@
+package k3s
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodeMetadataValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		metadata    NodeMetadata
+		expectError bool
+	}{
+		{
+			name: "valid",
+			metadata: NodeMetadata{
+				Labels: map[string]string{"node-role.kubernetes.io/worker": "true", "zone": ""},
+				Taints: []Taint{{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}, {Key: "dedicated", Value: "gpu", Effect: "NoExecute"}},
+			},
+		},
+		{name: "invalid label key", metadata: NodeMetadata{Labels: map[string]string{"-zone": "a"}}, expectError: true},
+		{name: "invalid label value", metadata: NodeMetadata{Labels: map[string]string{"zone": "a b"}}, expectError: true},
+		{name: "invalid taint effect", metadata: NodeMetadata{Taints: []Taint{{Key: "dedicated", Effect: "Never"}}}, expectError: true},
+		{name: "invalid taint key", metadata: NodeMetadata{Taints: []Taint{{Key: "a/b/c", Effect: "NoSchedule"}}}, expectError: true},
+		{
+			name:        "duplicate taint",
+			metadata:    NodeMetadata{Taints: []Taint{{Key: "dedicated", Value: "a", Effect: "NoSchedule"}, {Key: "dedicated", Value: "b", Effect: "NoSchedule"}}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.metadata.Validate()
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNodeMetadataApply(t *testing.T) {
+	node := func() *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent-1", Labels: map[string]string{
+				"kubernetes.io/hostname": "agent-1",
+				"team":                   "data",
+				"zone":                   "a",
+			}},
+			Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+				{Key: "node.kubernetes.io/unreachable", Effect: corev1.TaintEffectNoExecute},
+				{Key: "dedicated", Value: "cpu", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+			}},
+		}
+	}
+
+	metadata := NodeMetadata{
+		Labels:      map[string]string{"zone": "b", "tier": "gpu"},
+		Taints:      []Taint{{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}},
+		StaleLabels: []string{"team", "removed"},
+		StaleTaints: []Taint{{Key: "spot", Effect: "PreferNoSchedule"}},
+	}
+	current := node()
+	if !metadata.apply(current) {
+		t.Fatalf("apply() reported no change")
+	}
+
+	wantLabels := map[string]string{"kubernetes.io/hostname": "agent-1", "zone": "b", "tier": "gpu"}
+	if !reflect.DeepEqual(current.Labels, wantLabels) {
+		t.Errorf("apply() labels = %v, want %v", current.Labels, wantLabels)
+	}
+	wantTaints := []corev1.Taint{
+		{Key: "node.kubernetes.io/unreachable", Effect: corev1.TaintEffectNoExecute},
+		{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule},
+	}
+	if !reflect.DeepEqual(current.Spec.Taints, wantTaints) {
+		t.Errorf("apply() taints = %v, want %v", current.Spec.Taints, wantTaints)
+	}
+
+	if metadata.apply(current) {
+		t.Errorf("apply() reported a change on a reconciled node")
+	}
+
+	// Labels that are not owned are never touched
+	unowned := NodeMetadata{Labels: map[string]string{"zone": "a"}}
+	current = node()
+	if unowned.apply(current) {
+		t.Errorf("apply() reported a change for labels already set")
+	}
+	if current.Labels["team"] != "data" || len(current.Spec.Taints) != 3 {
+		t.Errorf("apply() changed unowned labels or taints: %v, %v", current.Labels, current.Spec.Taints)
+	}
+}
+
+func TestNodeMetadataObserve(t *testing.T) {
+	node := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"zone": "c", "team": "data"}},
+		Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+			{Key: "dedicated", Value: "cpu", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "spot", Effect: corev1.TaintEffectNoSchedule},
+		}},
+	}
+
+	metadata := NodeMetadata{
+		Labels: map[string]string{"zone": "b", "tier": "gpu"},
+		Taints: []Taint{{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}, {Key: "dedicated", Value: "gpu", Effect: "NoExecute"}},
+	}
+	metadata.observe(node)
+
+	if want := map[string]string{"zone": "c"}; !reflect.DeepEqual(metadata.Labels, want) {
+		t.Errorf("observe() labels = %v, want %v", metadata.Labels, want)
+	}
+	if want := []Taint{{Key: "dedicated", Value: "cpu", Effect: "NoSchedule"}}; !reflect.DeepEqual(metadata.Taints, want) {
+		t.Errorf("observe() taints = %v, want %v", metadata.Taints, want)
+	}
+}
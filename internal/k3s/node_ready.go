@@ -0,0 +1,189 @@
+package k3s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Default time a node may take to become Ready after k3s started.
+const NODE_READY_TIMEOUT = 5 * time.Minute
+
+// Last line of /readyz?verbose once every check passed.
+const readyzPassed = "readyz check passed"
+
+var nodeReadyPollInterval = 5 * time.Second
+
+// Waits until the API server of a server passes /readyz and the Node of the
+// host reports Ready. The kubelet kubeconfig is used, so this works on
+// agents too. A server without an agent has neither a kubelet kubeconfig
+// nor a Node, so only /readyz is checked, through the admin kubeconfig.
+// A zero timeout skips the wait.
+func waitForNodeReady(ctx context.Context, client ssh_client.SSHClient, binDir string, apiServer bool, timeout time.Duration) error {
+	if timeout == 0 {
+		return nil
+	}
+
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return err
+	}
+	if apiServer && configDisablesAgent(config) {
+		tflog.Info(ctx, "Waiting for the API server to become ready")
+		return waitForAdminReadyz(ctx, client, timeout)
+	}
+
+	kubeconfig := configDataDir(config) + "/agent/kubelet.kubeconfig"
+	node, err := nodeName(client, config)
+	if err != nil {
+		return err
+	}
+	// The timeout covers both waits
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if apiServer {
+		tflog.Info(ctx, "Waiting for the API server to become ready")
+		command := kubeletKubectl(binDir, kubeconfig).Literal("get", "--raw").Arg("/readyz?verbose").Literal("2>&1", "||", "true")
+		var output string
+		err := wait.PollUntilContextTimeout(ctx, nodeReadyPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+			res, err := client.Run(command.String())
+			if err != nil {
+				return false, err
+			}
+			if len(res) == 1 {
+				output = strings.TrimSpace(res[0])
+			}
+			return strings.HasSuffix(output, readyzPassed), nil
+		})
+		if wait.Interrupted(err) {
+			return fmt.Errorf("API server is not ready after %s:\n%s", timeout, failedReadyzChecks(output))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	tflog.Info(ctx, fmt.Sprintf("Waiting for node %s to become Ready", node))
+	command := kubeletKubectl(binDir, kubeconfig).Literal("get", "node").Arg(node).Literal("-o", "json", "2>&1", "||", "true")
+	var status string
+	err = wait.PollUntilContextTimeout(ctx, nodeReadyPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		res, err := client.Run(command.String())
+		if err != nil {
+			return false, err
+		}
+		var ready bool
+		if len(res) == 1 {
+			ready, status = parseNodeStatus(res[0])
+		}
+		return ready, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("node %s is not Ready after %s: %s", node, timeout, status)
+	}
+	return err
+}
+
+// Waits for /readyz through the admin kubeconfig, which k3s only writes
+// once it started.
+func waitForAdminReadyz(ctx context.Context, client ssh_client.SSHClient, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var kube *kubeClient
+	var output string
+	err := wait.PollUntilContextTimeout(ctx, nodeReadyPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		if kube == nil {
+			kubeconfig, err := readKubeConfig(client)
+			if err != nil {
+				output = err.Error()
+				return false, nil
+			}
+			if kube, err = newKubeClient(kubeconfig); err != nil {
+				return false, err
+			}
+		}
+		var ready bool
+		ready, output = readyzStatus(ctx, kube)
+		return ready, nil
+	})
+	if wait.Interrupted(err) {
+		return fmt.Errorf("API server is not ready after %s:\n%s", timeout, failedReadyzChecks(output))
+	}
+	return err
+}
+
+// Whether /readyz?verbose passes, and its output or the request error.
+func readyzStatus(ctx context.Context, kube *kubeClient) (bool, string) {
+	body, err := kube.core.Discovery().RESTClient().Get().AbsPath("/readyz").Param("verbose", "").DoRaw(ctx)
+	output := strings.TrimSpace(string(body))
+	if err != nil && output == "" {
+		output = err.Error()
+	}
+	return err == nil && strings.HasSuffix(output, readyzPassed), output
+}
+
+func kubeletKubectl(binDir string, kubeconfig string) *shellCommand {
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+	return sudo().Arg(binDir+"/k3s").Literal("kubectl", "--kubeconfig").Arg(kubeconfig)
+}
+
+// Whether `kubectl get node -o json` shows a Ready node, and a summary of
+// its conditions or the kubectl error otherwise.
+func parseNodeStatus(output string) (bool, string) {
+	var node corev1.Node
+	if err := json.Unmarshal([]byte(output), &node); err != nil {
+		return false, strings.TrimSpace(output)
+	}
+	if len(node.Status.Conditions) == 0 {
+		return false, "no conditions reported"
+	}
+	return nodeReady(node), nodeConditions(node)
+}
+
+// Conditions of the node as Type=Status, with the reason and message of
+// those that are not in their healthy state.
+func nodeConditions(node corev1.Node) string {
+	conditions := append([]corev1.NodeCondition{}, node.Status.Conditions...)
+	sort.Slice(conditions, func(i, j int) bool { return conditions[i].Type < conditions[j].Type })
+
+	parts := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		part := fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+		healthy := corev1.ConditionFalse
+		if condition.Type == corev1.NodeReady {
+			healthy = corev1.ConditionTrue
+		}
+		if condition.Status != healthy && (condition.Reason != "" || condition.Message != "") {
+			part += fmt.Sprintf(" (%s: %s)", condition.Reason, condition.Message)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// The failing checks of /readyz?verbose, or the whole output when it does
+// not list checks.
+func failedReadyzChecks(output string) string {
+	var failed []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "[-]") {
+			failed = append(failed, line)
+		}
+	}
+	if len(failed) == 0 {
+		return output
+	}
+	return strings.Join(failed, "\n")
+}
@@ -0,0 +1,100 @@
+package k3s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestParseNodeStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantReady  bool
+		wantStatus string
+	}{
+		{
+			name:       "ready",
+			output:     `{"kind":"Node","status":{"conditions":[{"type":"MemoryPressure","status":"False"},{"type":"Ready","status":"True","reason":"KubeletReady"}]}}`,
+			wantReady:  true,
+			wantStatus: "MemoryPressure=False, Ready=True",
+		},
+		{
+			name:       "network not ready",
+			output:     `{"kind":"Node","status":{"conditions":[{"type":"Ready","status":"False","reason":"KubeletNotReady","message":"container runtime network not ready"},{"type":"DiskPressure","status":"True","reason":"KubeletHasDiskPressure","message":"low disk"}]}}`,
+			wantStatus: "DiskPressure=True (KubeletHasDiskPressure: low disk), Ready=False (KubeletNotReady: container runtime network not ready)",
+		},
+		{
+			name:       "not registered",
+			output:     "Error from server (NotFound): nodes \"agent-1\" not found\n",
+			wantStatus: `Error from server (NotFound): nodes "agent-1" not found`,
+		},
+		{
+			name:       "no conditions",
+			output:     `{"kind":"Node","status":{}}`,
+			wantStatus: "no conditions reported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, status := parseNodeStatus(tt.output)
+			if ready != tt.wantReady || status != tt.wantStatus {
+				t.Errorf("parseNodeStatus() = %v, %q, want %v, %q", ready, status, tt.wantReady, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestFailedReadyzChecks(t *testing.T) {
+	output := strings.Join([]string{
+		"[+]ping ok",
+		"[-]etcd failed: reason withheld",
+		"[+]informer-sync ok",
+		"[-]poststarthook/rbac/bootstrap-roles failed: reason withheld",
+		"readyz check failed",
+	}, "\n")
+	want := "[-]etcd failed: reason withheld\n[-]poststarthook/rbac/bootstrap-roles failed: reason withheld"
+	if got := failedReadyzChecks(output); got != want {
+		t.Errorf("failedReadyzChecks() = %q, want %q", got, want)
+	}
+
+	refused := "The connection to the server 127.0.0.1:6443 was refused"
+	if got := failedReadyzChecks(refused); got != refused {
+		t.Errorf("failedReadyzChecks() = %q, want the whole output", got)
+	}
+}
+
+func TestReadyzStatus(t *testing.T) {
+	ready := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" || !r.URL.Query().Has("verbose") {
+			http.NotFound(w, r)
+			return
+		}
+		if !ready {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("[+]ping ok\n[-]etcd failed: reason withheld\nreadyz check failed\n"))
+			return
+		}
+		_, _ = w.Write([]byte("[+]ping ok\n[+]etcd ok\nreadyz check passed\n"))
+	}))
+	defer server.Close()
+
+	kube, err := newKubeClientForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	passed, output := readyzStatus(context.Background(), kube)
+	if passed || !strings.Contains(failedReadyzChecks(output), "[-]etcd failed") {
+		t.Errorf("readyzStatus() = %v, %q, want the failing etcd check", passed, output)
+	}
+
+	ready = true
+	if passed, output := readyzStatus(context.Background(), kube); !passed {
+		t.Errorf("readyzStatus() = %v, %q, want passed", passed, output)
+	}
+}
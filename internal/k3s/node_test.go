@@ -0,0 +1,34 @@
+package k3s
+
+import (
+	"testing"
+)
+
+func TestKubeletNodeName(t *testing.T) {
+	cert, key := testCertificateFor(t, "system:node:agent-1-5f3c2a1b")
+	name, err := kubeletNodeName(key + cert)
+	if err != nil || name != "agent-1-5f3c2a1b" {
+		t.Errorf("kubeletNodeName() = %q, %v, want agent-1-5f3c2a1b", name, err)
+	}
+
+	cert, _ = testCertificateFor(t, "system:admin")
+	if _, err := kubeletNodeName(cert); err == nil {
+		t.Errorf("kubeletNodeName() accepted a certificate that is not issued to a node")
+	}
+}
+
+func TestConfigDisablesAgent(t *testing.T) {
+	for _, tt := range []struct {
+		config map[any]any
+		want   bool
+	}{
+		{map[any]any{}, false},
+		{map[any]any{"disable-agent": true}, true},
+		{map[any]any{"disable-agent": false}, false},
+		{map[any]any{"disable-agent": "true"}, true},
+	} {
+		if got := configDisablesAgent(tt.config); got != tt.want {
+			t.Errorf("configDisablesAgent(%v) = %v, want %v", tt.config, got, tt.want)
+		}
+	}
+}
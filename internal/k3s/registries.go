@@ -0,0 +1,251 @@
+package k3s
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Directory holding registry TLS material uploaded from inline content, one
+// subdirectory per registry host.
+const REGISTRY_TLS_DIR string = CONFIG_DIR + "/registries-tls"
+
+var registryDirPattern = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// Registries is the typed form of registries.yaml.
+type Registries struct {
+	// Mirrors keyed by registry host, or * for every registry
+	Mirrors map[string]RegistryMirror
+	// Auth and TLS settings keyed by registry host
+	Configs map[string]RegistryConfig
+}
+
+type RegistryMirror struct {
+	Endpoints []string
+	// Image name rewrites, regular expression to replacement
+	Rewrites map[string]string
+}
+
+type RegistryConfig struct {
+	Username      string
+	Password      string
+	Auth          string
+	IdentityToken string
+	// Inline PEM content uploaded to REGISTRY_TLS_DIR
+	CA   string
+	Cert string
+	Key  string
+	// Paths of TLS material already present on the host
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// Validate checks endpoints, rewrites, credentials and TLS material of every
+// registry.
+func (r *Registries) Validate() error {
+	for _, host := range sortedKeys(r.Mirrors) {
+		mirror := r.Mirrors[host]
+		if err := validateRegistryHost(host); err != nil {
+			return err
+		}
+		for _, endpoint := range mirror.Endpoints {
+			u, err := url.Parse(endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("registry mirror %q endpoint %q must be an http or https URL", host, endpoint)
+			}
+		}
+		for _, pattern := range sortedKeys(mirror.Rewrites) {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("registry mirror %q rewrite %q: %s", host, pattern, err.Error())
+			}
+		}
+	}
+
+	dirs := make(map[string]string)
+	for _, host := range sortedKeys(r.Configs) {
+		config := r.Configs[host]
+		if err := validateRegistryHost(host); err != nil {
+			return err
+		}
+		if other, ok := dirs[registryTLSDir(host)]; ok {
+			return fmt.Errorf("registry configs %q and %q would share the TLS directory %s", other, host, registryTLSDir(host))
+		}
+		dirs[registryTLSDir(host)] = host
+
+		if config.Password != "" && config.Username == "" {
+			return fmt.Errorf("registry config %q sets a password without a username", host)
+		}
+		if config.Username != "" && (config.Auth != "" || config.IdentityToken != "") {
+			return fmt.Errorf("registry config %q must use only one of username, auth or identity_token", host)
+		}
+		if config.CA != "" && config.CAFile != "" {
+			return fmt.Errorf("registry config %q sets both ca and ca_file", host)
+		}
+		if config.Cert != "" && config.CertFile != "" {
+			return fmt.Errorf("registry config %q sets both cert and cert_file", host)
+		}
+		if config.Key != "" && config.KeyFile != "" {
+			return fmt.Errorf("registry config %q sets both key and key_file", host)
+		}
+		if (config.Cert != "" || config.CertFile != "") != (config.Key != "" || config.KeyFile != "") {
+			return fmt.Errorf("registry config %q requires a client certificate and key together", host)
+		}
+		for name, content := range map[string]string{"ca": config.CA, "cert": config.Cert} {
+			if content != "" && !validCertificates(content) {
+				return fmt.Errorf("registry config %q %s must contain PEM encoded certificates", host, name)
+			}
+		}
+		if config.Key != "" {
+			if block, _ := pem.Decode([]byte(config.Key)); block == nil || !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				return fmt.Errorf("registry config %q key must be a PEM encoded private key", host)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateRegistryHost(host string) error {
+	if host == "" || strings.ContainsAny(host, "/ \n\x00") {
+		return fmt.Errorf("registry %q must be a host with an optional port, or *", host)
+	}
+	return nil
+}
+
+func validCertificates(content string) bool {
+	rest := []byte(content)
+	found := false
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return found && strings.TrimSpace(string(rest)) == ""
+		}
+		if block.Type != "CERTIFICATE" {
+			return false
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return false
+		}
+		found = true
+	}
+}
+
+func registryTLSDir(host string) string {
+	return fmt.Sprintf("%s/%s", REGISTRY_TLS_DIR, registryDirPattern.ReplaceAllString(host, "_"))
+}
+
+// Every path TLS material of a registry host can be uploaded to.
+func registryTLSPaths(hosts ...[]string) (paths []string) {
+	for _, list := range hosts {
+		for _, host := range list {
+			dir := registryTLSDir(host)
+			paths = append(paths, dir+"/ca.crt", dir+"/client.crt", dir+"/client.key")
+		}
+	}
+	return
+}
+
+// Content of registries.yaml, with inline TLS material replaced by the
+// paths it is uploaded to.
+func (r *Registries) registry() map[any]any {
+	registry := make(map[any]any)
+
+	if len(r.Mirrors) > 0 {
+		mirrors := make(map[any]any, len(r.Mirrors))
+		for host, mirror := range r.Mirrors {
+			entry := map[any]any{"endpoint": mirror.Endpoints}
+			if len(mirror.Rewrites) > 0 {
+				entry["rewrite"] = mirror.Rewrites
+			}
+			mirrors[host] = entry
+		}
+		registry["mirrors"] = mirrors
+	}
+
+	if len(r.Configs) > 0 {
+		configs := make(map[any]any, len(r.Configs))
+		for host, config := range r.Configs {
+			entry := make(map[any]any)
+
+			auth := make(map[any]any)
+			for key, value := range map[string]string{
+				"username":       config.Username,
+				"password":       config.Password,
+				"auth":           config.Auth,
+				"identity_token": config.IdentityToken,
+			} {
+				if value != "" {
+					auth[key] = value
+				}
+			}
+			if len(auth) > 0 {
+				entry["auth"] = auth
+			}
+
+			dir := registryTLSDir(host)
+			tls := make(map[any]any)
+			for key, file := range map[string]struct{ content, path, upload string }{
+				"ca_file":   {config.CA, config.CAFile, dir + "/ca.crt"},
+				"cert_file": {config.Cert, config.CertFile, dir + "/client.crt"},
+				"key_file":  {config.Key, config.KeyFile, dir + "/client.key"},
+			} {
+				if file.content != "" {
+					tls[key] = file.upload
+				} else if file.path != "" {
+					tls[key] = file.path
+				}
+			}
+			if config.InsecureSkipVerify {
+				tls["insecure_skip_verify"] = true
+			}
+			if len(tls) > 0 {
+				entry["tls"] = tls
+			}
+
+			configs[host] = entry
+		}
+		registry["configs"] = configs
+	}
+
+	return registry
+}
+
+// Commands for uploading inline TLS material and removing material that is
+// no longer declared, including that of registries listed in stale.
+func registryTLSCommands(registries *Registries, stale []string) []string {
+	files := make(map[string]ExtraFile)
+	remove := registryTLSPaths(stale)
+
+	if registries != nil {
+		for _, host := range sortedKeys(registries.Configs) {
+			config := registries.Configs[host]
+			dir := registryTLSDir(host)
+			for _, file := range []struct{ content, path, mode string }{
+				{config.CA, dir + "/ca.crt", "0644"},
+				{config.Cert, dir + "/client.crt", "0644"},
+				{config.Key, dir + "/client.key", "0600"},
+			} {
+				if file.content != "" {
+					files[file.path] = ExtraFile{Content: file.content, Mode: file.mode}
+				} else {
+					remove = append(remove, file.path)
+				}
+			}
+		}
+	}
+
+	return extraFileCommands(files, remove)
+}
+
+func (r *Registries) hosts() []string {
+	if r == nil {
+		return nil
+	}
+	return sortedKeys(r.Configs)
+}
@@ -0,0 +1,121 @@
+package k3s
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T) (string, string) {
+	t.Helper()
+	return testCertificateFor(t, "registry")
+}
+
+func testCertificateFor(t *testing.T, commonName string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key: %v", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestRegistriesValidate(t *testing.T) {
+	cert, key := testCertificate(t)
+	tests := []struct {
+		name        string
+		registries  Registries
+		expectError bool
+	}{
+		{name: "mirror", registries: Registries{Mirrors: map[string]RegistryMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}, Rewrites: map[string]string{"^library/(.*)": "hub/$1"}}}}},
+		{name: "inline tls", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com:5000": {Username: "user", Password: "pass", CA: cert, Cert: cert, Key: key}}}},
+		{name: "tls files", registries: Registries{Configs: map[string]RegistryConfig{"*": {CAFile: "/etc/ssl/ca.crt", CertFile: "/etc/ssl/client.crt", KeyFile: "/etc/ssl/client.key"}}}},
+		{name: "endpoint without scheme", registries: Registries{Mirrors: map[string]RegistryMirror{"docker.io": {Endpoints: []string{"mirror.example.com"}}}}, expectError: true},
+		{name: "invalid rewrite", registries: Registries{Mirrors: map[string]RegistryMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}, Rewrites: map[string]string{"(": "a"}}}}, expectError: true},
+		{name: "password without username", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {Password: "pass"}}}, expectError: true},
+		{name: "username and token", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {Username: "user", IdentityToken: "token"}}}, expectError: true},
+		{name: "ca and ca file", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {CA: cert, CAFile: "/etc/ssl/ca.crt"}}}, expectError: true},
+		{name: "cert without key", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {Cert: cert}}}, expectError: true},
+		{name: "invalid ca", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {CA: "not a certificate"}}}, expectError: true},
+		{name: "certificate as key", registries: Registries{Configs: map[string]RegistryConfig{"registry.example.com": {Cert: cert, Key: cert}}}, expectError: true},
+		{name: "shared tls directory", registries: Registries{Configs: map[string]RegistryConfig{"registry:5000": {}, "registry_5000": {}}}, expectError: true},
+		{name: "path in host", registries: Registries{Configs: map[string]RegistryConfig{"../registry": {}}}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.registries.Validate()
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRegistriesRegistry(t *testing.T) {
+	registries := Registries{
+		Mirrors: map[string]RegistryMirror{"docker.io": {Endpoints: []string{"https://mirror.example.com"}}},
+		Configs: map[string]RegistryConfig{
+			"registry.example.com:5000": {Username: "user", Password: "pass", CA: "ca", KeyFile: "/etc/ssl/client.key", CertFile: "/etc/ssl/client.crt"},
+		},
+	}
+
+	registry := registries.registry()
+	mirror := registry["mirrors"].(map[any]any)["docker.io"].(map[any]any)
+	if _, ok := mirror["rewrite"]; ok {
+		t.Errorf("registry() mirror = %v, want no rewrite", mirror)
+	}
+	config := registry["configs"].(map[any]any)["registry.example.com:5000"].(map[any]any)
+	if got := config["auth"].(map[any]any)["password"]; got != "pass" {
+		t.Errorf("registry() password = %v, want pass", got)
+	}
+	tls := config["tls"].(map[any]any)
+	if got, want := tls["ca_file"], REGISTRY_TLS_DIR+"/registry.example.com_5000/ca.crt"; got != want {
+		t.Errorf("registry() ca_file = %v, want %s", got, want)
+	}
+	if got := tls["key_file"]; got != "/etc/ssl/client.key" {
+		t.Errorf("registry() key_file = %v, want the host path", got)
+	}
+}
+
+func TestRegistryTLSCommands(t *testing.T) {
+	registries := &Registries{Configs: map[string]RegistryConfig{"registry.example.com": {CA: "ca", Key: "key"}}}
+
+	joined := strings.Join(registryTLSCommands(registries, []string{"old.example.com"}), "\n")
+	for _, want := range []string{
+		"sudo install -m '0644' /dev/null '/etc/rancher/k3s/registries-tls/registry.example.com/ca.crt'",
+		"sudo install -m '0600' /dev/null '/etc/rancher/k3s/registries-tls/registry.example.com/client.key'",
+		"sudo rm -f '/etc/rancher/k3s/registries-tls/registry.example.com/client.crt'",
+		"sudo rm -f '/etc/rancher/k3s/registries-tls/old.example.com/ca.crt'",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("registryTLSCommands() = %q, want %q", joined, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// How long re-encrypting every secret after a key rotation may take.
+const SECRETS_REENCRYPT_TIMEOUT = 10 * time.Minute
+
+// Rotation stage reported once every secret was re-encrypted.
+const reencryptFinished = "reencrypt_finished"
+
+var secretsEncryptionPollInterval = 5 * time.Second
+
+var SecretsEncryptionProviders = []string{"aescbc", "secretbox"}
+
+// SecretsEncryption configures encryption of secrets at rest.
+type SecretsEncryption struct {
+	Enabled bool
+	// Empty keeps the k3s default, aescbc
+	Provider string
+}
+
+// SecretsEncryptionStatus is the output of `k3s secrets-encrypt status`.
+type SecretsEncryptionStatus struct {
+	Status        string
+	RotationStage string
+	ServerHashes  string
+	ActiveKeyType string
+	ActiveKey     string
+}
+
+func (e *SecretsEncryption) Validate() error {
+	if e.Provider == "" {
+		return nil
+	}
+	if !e.Enabled {
+		return fmt.Errorf("secrets_encryption provider requires enabled")
+	}
+	for _, provider := range SecretsEncryptionProviders {
+		if e.Provider == provider {
+			return nil
+		}
+	}
+	return fmt.Errorf("secrets_encryption provider %q must be one of %s", e.Provider, strings.Join(SecretsEncryptionProviders, ", "))
+}
+
+func (e *SecretsEncryption) mergeInto(config map[any]any) error {
+	values := map[string]any{"secrets-encryption": e.Enabled}
+	if e.Provider != "" {
+		values["secrets-encryption-provider"] = e.Provider
+	}
+	return mergeConfigValues(config, values, blockLabel("secrets_encryption"))
+}
+
+func secretsEncryptCommand(binDir string, args ...string) string {
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+	return sudo().Arg(binDir + "/k3s").Literal("secrets-encrypt").Literal(args...).String()
+}
+
+func readSecretsEncryptionStatus(client ssh_client.SSHClient, binDir string) (*SecretsEncryptionStatus, error) {
+	res, err := client.Run(secretsEncryptCommand(binDir, "status"))
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets encryption status: %s", err.Error())
+	}
+	if len(res) != 1 {
+		return nil, fmt.Errorf("wrong number of results from secrets encryption status")
+	}
+	return parseSecretsEncryptionStatus(res[0]), nil
+}
+
+// Parses the status header and the row of the active key, marked with "*".
+func parseSecretsEncryptionStatus(output string) *SecretsEncryptionStatus {
+	status := &SecretsEncryptionStatus{}
+	for _, line := range strings.Split(output, "\n") {
+		// Key names may contain timestamps, so look for the key row first
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "*" {
+			status.ActiveKeyType = fields[1]
+			status.ActiveKey = fields[len(fields)-1]
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		switch strings.TrimSpace(name) {
+		case "Encryption Status":
+			status.Status = strings.TrimSpace(value)
+		case "Current Rotation Stage":
+			status.RotationStage = strings.TrimSpace(value)
+		case "Server Encryption Hashes":
+			status.ServerHashes = strings.TrimSpace(value)
+		}
+	}
+	return status
+}
+
+// RotateEncryptionKeys adds a new key, re-encrypts every secret with it and
+// waits for the re-encryption to finish.
+func (s *Server) RotateEncryptionKeys(ctx context.Context, client ssh_client.SSHClient, timeout time.Duration) error {
+	if err := client.WaitForReady(); err != nil {
+		return err
+	}
+
+	tflog.Info(ctx, "Rotating k3s secrets encryption keys")
+	if err := client.RunStream([]string{secretsEncryptCommand(s.BinDir, "rotate-keys")}); err != nil {
+		return fmt.Errorf("rotating secrets encryption keys: %s", err.Error())
+	}
+
+	var last *SecretsEncryptionStatus
+	var lastErr error
+	err := wait.PollUntilContextTimeout(ctx, secretsEncryptionPollInterval, timeout, false, func(ctx context.Context) (bool, error) {
+		status, err := readSecretsEncryptionStatus(client, s.BinDir)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		last = status
+		if status.RotationStage == reencryptFinished {
+			return true, nil
+		}
+		tflog.Debug(ctx, fmt.Sprintf("Waiting for secrets re-encryption, stage %s", status.RotationStage))
+		return false, nil
+	})
+	if err == nil {
+		s.SecretsEncryptionStatus = last
+		return nil
+	}
+	if !wait.Interrupted(err) {
+		return err
+	}
+
+	if last == nil && lastErr != nil {
+		return fmt.Errorf("secrets were not re-encrypted within %s: %w", timeout, lastErr)
+	}
+	stage := ""
+	if last != nil {
+		stage = last.RotationStage
+	}
+	return fmt.Errorf("secrets were not re-encrypted within %s, rotation stage is %q", timeout, stage)
+}
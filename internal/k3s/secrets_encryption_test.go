@@ -0,0 +1,68 @@
+package k3s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSecretsEncryptionValidate(t *testing.T) {
+	valid := []SecretsEncryption{
+		{},
+		{Enabled: true},
+		{Enabled: true, Provider: "secretbox"},
+	}
+	for _, e := range valid {
+		if err := e.Validate(); err != nil {
+			t.Errorf("Validate(%+v) error = %v", e, err)
+		}
+	}
+
+	invalid := []SecretsEncryption{
+		{Provider: "aescbc"},
+		{Enabled: true, Provider: "aesgcm"},
+	}
+	for _, e := range invalid {
+		if err := e.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid config", e)
+		}
+	}
+}
+
+func TestSecretsEncryptionMergeInto(t *testing.T) {
+	config := map[any]any{}
+	e := SecretsEncryption{Enabled: true, Provider: "secretbox"}
+	if err := e.mergeInto(config); err != nil {
+		t.Fatalf("mergeInto() error = %v", err)
+	}
+	want := map[any]any{"secrets-encryption": true, "secrets-encryption-provider": "secretbox"}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("mergeInto() config = %v, want %v", config, want)
+	}
+
+	if err := e.mergeInto(map[any]any{"secrets-encryption": false}); err == nil {
+		t.Error("mergeInto() accepted a key already set in config")
+	}
+}
+
+func TestParseSecretsEncryptionStatus(t *testing.T) {
+	output := `Encryption Status: Enabled
+Current Rotation Stage: reencrypt_finished
+Server Encryption Hashes: All hashes match
+
+Active  Key Type  Name
+------  --------  ----
+ *      AES-CBC   aescbc-key-2026-10-19T08:12:41Z
+        AES-CBC   aescbc-key
+`
+	got := parseSecretsEncryptionStatus(output)
+	want := &SecretsEncryptionStatus{
+		Status:        "Enabled",
+		RotationStage: "reencrypt_finished",
+		ServerHashes:  "All hashes match",
+		ActiveKeyType: "AES-CBC",
+		ActiveKey:     "aescbc-key-2026-10-19T08:12:41Z",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSecretsEncryptionStatus() = %+v, want %+v", got, want)
+	}
+}
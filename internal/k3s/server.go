@@ -2,7 +2,6 @@ package k3s
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"strings"
 	"time"
@@ -24,8 +23,63 @@ type Server struct {
 	KubeConfig string
 	Version    string
 	BinDir     string
-	ExtraFiles map[string]string
+	ExtraFiles map[string]ExtraFile
 	Env        map[string]string
+	// Previously managed extra files that should be removed
+	StaleExtraFiles []string
+	// Observed extra files, keyed by path
+	ObservedExtraFiles map[string]ExtraFileStatus
+	// Managed config.yaml.d drop-ins, keyed by name without extension
+	ConfigFragments map[string]string
+	// Previously managed drop-ins that should be removed
+	StaleConfigFragments []string
+	// Typed registries.yaml, used instead of Registry when set
+	Registries *Registries
+	// Registry hosts whose uploaded TLS material should be removed
+	StaleRegistryHosts []string
+	// Containerd config template, written to the agent data dir when set
+	ContainerdConfigTemplate string
+	// Whether a previously managed containerd config template should be removed
+	RemoveContainerdConfigTemplate bool
+	// Managed static pod manifests, keyed by name without extension
+	StaticPods map[string]string
+	// Previously managed static pods that should be removed
+	StaleStaticPods []string
+	// Observed config.yaml merged with every drop-in
+	MergedConfig string
+	// Typed flags merged into the raw config, keyed by k3s flag name
+	Flags map[string]any
+	// Managed auto-deploy manifests, keyed by name without extension
+	Manifests map[string]string
+	// Previously managed manifests that should be removed
+	StaleManifests []string
+	// Apply status of every managed manifest
+	ManifestStatus map[string]ManifestStatus
+	// Snapshot to restore with a cluster reset before the first start
+	Restore *EtcdRestore
+	// Scheduled snapshots and S3 upload, credentials kept out of config.yaml
+	EtcdSnapshots *EtcdSnapshotConfig
+	// Encryption of secrets at rest
+	SecretsEncryption *SecretsEncryption
+	// Observed secrets encryption status, set when encryption is enabled
+	SecretsEncryptionStatus *SecretsEncryptionStatus
+	// Organisation CA the cluster CAs are issued from before the first start
+	CustomCA *CustomCA
+	// Expiry of every certificate under <data-dir>/server/tls, in RFC 3339
+	CertificateExpiry map[string]string
+	// Drains the node and removes it from the cluster before uninstalling
+	// when set
+	GracefulRemoval *NodeDrain
+	// How long Install and Update wait for /readyz and the Node to be
+	// Ready, no waiting when zero
+	ReadyTimeout time.Duration
+	// How long Install and Update wait for the packaged add-ons and helm
+	// install jobs in kube-system, no waiting when zero
+	AddonTimeout time.Duration
+	// Observed identity of the node, set by Refresh
+	NodeInfo *NodeInfo
+	// Labels and taints managed on the Node through the API
+	NodeMetadata *NodeMetadata
 
 	// Internal fields to check for
 	// correct formatting and config merging
@@ -40,11 +94,85 @@ func (s *Server) Validate(ctx context.Context) error {
 	if err := yaml.Unmarshal([]byte(s.Config), &s.config); err != nil {
 		return fmt.Errorf("parsing config: %s", err.Error())
 	}
+	if err := mergeConfigValues(s.config, s.Flags, flagAttribute); err != nil {
+		return err
+	}
+	if s.EtcdSnapshots != nil {
+		if err := s.EtcdSnapshots.Validate(s.config); err != nil {
+			return err
+		}
+		if err := s.EtcdSnapshots.mergeInto(s.config); err != nil {
+			return err
+		}
+	}
+	if s.SecretsEncryption != nil {
+		if err := s.SecretsEncryption.Validate(); err != nil {
+			return err
+		}
+		if err := s.SecretsEncryption.mergeInto(s.config); err != nil {
+			return err
+		}
+	}
+	if s.GracefulRemoval != nil {
+		if err := s.GracefulRemoval.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.NodeMetadata != nil {
+		if err := s.NodeMetadata.Validate(); err != nil {
+			return err
+		}
+	}
 
 	s.registry = make(map[any]any)
 	if err := yaml.Unmarshal([]byte(s.Registry), &s.registry); err != nil {
 		return fmt.Errorf("parsing registry: %s", err.Error())
 	}
+	if s.Registries != nil {
+		if len(s.registry) > 0 {
+			return fmt.Errorf("registry and registries cannot both be set")
+		}
+		if err := s.Registries.Validate(); err != nil {
+			return err
+		}
+		s.registry = s.Registries.registry()
+	}
+	if err := validateEnv(s.Env); err != nil {
+		return err
+	}
+	if s.Restore != nil {
+		if err := s.Restore.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.CustomCA != nil {
+		if err := s.CustomCA.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := ValidateExtraFiles(s.ExtraFiles); err != nil {
+		return err
+	}
+	if err := ValidateConfigFragments(s.ConfigFragments); err != nil {
+		return err
+	}
+	if err := ValidateStaticPods(s.StaticPods); err != nil {
+		return err
+	}
+	if s.ContainerdConfigTemplate != "" {
+		if err := ValidateContainerdConfigTemplate(s.ContainerdConfigTemplate); err != nil {
+			return err
+		}
+	}
+	if err := ValidateManifests(s.Manifests); err != nil {
+		return err
+	}
+	if err := ValidateConfigKeys(ROLE_SERVER, s.Version, s.config); err != nil {
+		return err
+	}
+	if err := validateConfigFragmentKeys(ROLE_SERVER, s.Version, s.ConfigFragments); err != nil {
+		return err
+	}
 	if s.BinDir == "" {
 		s.BinDir = BIN_DIR
 	}
@@ -103,7 +231,17 @@ func (s *Server) PreInstall(ctx context.Context, client ssh_client.SSHClient) er
 		return err
 	}
 
-	extraFileCommands := syncExtraFiles(s.ExtraFiles)
+	fileCommands := extraFileCommands(s.ExtraFiles, s.StaleExtraFiles)
+	fragmentCommands := configFragmentCommands(s.ConfigFragments, s.StaleConfigFragments)
+	tlsCommands := registryTLSCommands(s.Registries, s.StaleRegistryHosts)
+	snapshotCommands := etcdSnapshotCommands(s.EtcdSnapshots)
+	containerdCommands := containerdConfigTemplateCommands(s.dataDir(), s.ContainerdConfigTemplate, s.RemoveContainerdConfigTemplate)
+	podCommands := staticPodCommands(s.dataDir(), s.StaticPods, s.StaleStaticPods)
+	caCommands, err := s.customCAPreInstallCommands(ctx, client)
+	if err != nil {
+		return err
+	}
+	manifestFileCommands := manifestCommands(s.dataDir(), s.Manifests, s.StaleManifests)
 
 	tflog.Debug(ctx, "Reading install script")
 	installContents, err := ReadInstallScript()
@@ -113,12 +251,12 @@ func (s *Server) PreInstall(ctx context.Context, client ssh_client.SSHClient) er
 
 	commands := append(
 		WriteFileCommands(s.BinDir+"/k3s-install.sh", installContents),
-		fmt.Sprintf("sudo mkdir -p %s", CONFIG_DIR),
-		fmt.Sprintf("sudo mkdir -p %s", s.dataDir()),
+		sudo("mkdir", "-p").Arg(CONFIG_DIR).String(),
+		sudo("mkdir", "-p").Arg(s.dataDir()).String(),
 	)
 
 	if s.BinDir != BIN_DIR {
-		commands = append(commands, fmt.Sprintf("sudo mkdir -p %s", s.BinDir))
+		commands = append(commands, sudo("mkdir", "-p").Arg(s.BinDir).String())
 	}
 
 	// Write config file
@@ -127,8 +265,25 @@ func (s *Server) PreInstall(ctx context.Context, client ssh_client.SSHClient) er
 	if len(regCommands) > 0 {
 		commands = append(commands, regCommands...)
 	}
-	if len(extraFileCommands) > 0 {
-		commands = append(commands, extraFileCommands...)
+	if len(tlsCommands) > 0 {
+		commands = append(commands, tlsCommands...)
+	}
+	commands = append(commands, snapshotCommands...)
+	commands = append(commands, caCommands...)
+	if len(fragmentCommands) > 0 {
+		commands = append(commands, fragmentCommands...)
+	}
+	if len(containerdCommands) > 0 {
+		commands = append(commands, containerdCommands...)
+	}
+	if len(podCommands) > 0 {
+		commands = append(commands, podCommands...)
+	}
+	if len(manifestFileCommands) > 0 {
+		commands = append(commands, manifestFileCommands...)
+	}
+	if len(fileCommands) > 0 {
+		commands = append(commands, fileCommands...)
 	}
 
 	return client.RunStream(commands)
@@ -136,13 +291,23 @@ func (s *Server) PreInstall(ctx context.Context, client ssh_client.SSHClient) er
 
 // Install implements K3sComponent.
 func (s *Server) Install(ctx context.Context, client ssh_client.SSHClient) error {
-	commands := []string{
-		s.installCommand(),
-		"sudo systemctl daemon-reload",
-		"sudo systemctl --no-block start k3s",
+	installCommand, err := s.installCommand()
+	if err != nil {
+		return err
 	}
 
-	if err := client.RunStream(commands); err != nil {
+	if err := client.RunStream([]string{installCommand, "sudo systemctl daemon-reload"}); err != nil {
+		return err
+	}
+
+	// Restore the datastore in place of the first normal start
+	if s.Restore != nil {
+		if err := s.Restore.reset(ctx, client, s.BinDir, s.dataDir(), s.Token, s.Env); err != nil {
+			return err
+		}
+	}
+
+	if err := client.RunStream([]string{"sudo systemctl --no-block start k3s"}); err != nil {
 		return err
 	}
 	if err := waitForK3sSystemdServiceActive(client, "k3s", 4*time.Minute); err != nil {
@@ -160,14 +325,20 @@ func (s *Server) Install(ctx context.Context, client ssh_client.SSHClient) error
 	}
 
 	// Retrieve kubeconfig
-	kubeConfig, err := s.getKubeConfig(client)
+	kubeConfig, err := readKubeConfig(client)
 	if err != nil {
 		return err
 	}
 	tflog.MaskMessageStrings(ctx, kubeConfig)
 	s.KubeConfig = kubeConfig
 
-	return nil
+	if err := waitForNodeReady(ctx, client, s.BinDir, true, s.ReadyTimeout); err != nil {
+		return err
+	}
+	if err := waitForAddons(ctx, client, s.KubeConfig, s.AddonTimeout); err != nil {
+		return err
+	}
+	return waitForStaticPods(ctx, client, s.BinDir, s.StaticPods, s.ReadyTimeout)
 }
 
 func (s *Server) Update(ctx context.Context, client ssh_client.SSHClient) error {
@@ -175,8 +346,13 @@ func (s *Server) Update(ctx context.Context, client ssh_client.SSHClient) error
 		return err
 	}
 
+	installCommand, err := s.installCommand()
+	if err != nil {
+		return err
+	}
+
 	commands := []string{
-		s.installCommand(),
+		installCommand,
 		"sudo systemctl daemon-reload",
 		"sudo systemctl --no-block restart k3s",
 	}
@@ -185,10 +361,39 @@ func (s *Server) Update(ctx context.Context, client ssh_client.SSHClient) error
 		return err
 	}
 	if err := waitForK3sSystemdServiceActive(client, "k3s", 5*time.Minute); err != nil {
+		return rollback(ctx, client, "k3s", s.backupPaths(), err, 5*time.Minute)
+	}
+
+	if err := removeBackup(client, "k3s"); err != nil {
+		tflog.Warn(ctx, fmt.Sprintf("Could not remove k3s backup: %s", err.Error()))
+	}
+
+	if err := waitForNodeReady(ctx, client, s.BinDir, true, s.ReadyTimeout); err != nil {
+		return err
+	}
+	if err := waitForAddons(ctx, client, s.KubeConfig, s.AddonTimeout); err != nil {
 		return err
 	}
+	return waitForStaticPods(ctx, client, s.BinDir, s.StaticPods, s.ReadyTimeout)
+}
 
-	return nil
+// Backup copies the current config, registries, extra files, binary and
+// service files aside so a failed Update can be rolled back.
+func (s *Server) Backup(ctx context.Context, client ssh_client.SSHClient) error {
+	return takeBackup(ctx, client, "k3s", s.backupPaths())
+}
+
+func (s *Server) backupPaths() []string {
+	extraPaths := append(sortedKeys(s.ExtraFiles), s.StaleExtraFiles...)
+	extraPaths = append(extraPaths, configFragmentPaths(sortedKeys(s.ConfigFragments), s.StaleConfigFragments)...)
+	extraPaths = append(extraPaths, registryTLSPaths(s.Registries.hosts(), s.StaleRegistryHosts)...)
+	extraPaths = append(extraPaths, etcdSnapshotPaths()...)
+	extraPaths = append(extraPaths, staticPodPaths(s.dataDir(), sortedKeys(s.StaticPods), s.StaleStaticPods)...)
+	if s.ContainerdConfigTemplate != "" || s.RemoveContainerdConfigTemplate {
+		extraPaths = append(extraPaths, containerdConfigTemplatePath(s.dataDir()))
+	}
+	extraPaths = append(extraPaths, manifestPaths(s.dataDir(), sortedKeys(s.Manifests), s.StaleManifests)...)
+	return backupPaths(s.BinDir, "k3s", extraPaths)
 }
 
 func (s *Server) Uninstall(ctx context.Context, client ssh_client.SSHClient) error {
@@ -205,13 +410,19 @@ func (s *Server) Uninstall(ctx context.Context, client ssh_client.SSHClient) err
 		return nil
 	}
 
+	if s.GracefulRemoval != nil {
+		if err := s.removeFromCluster(ctx, client); err != nil {
+			return fmt.Errorf("removing server from the cluster before uninstall: %s", err.Error())
+		}
+	}
+
 	binDir := s.BinDir
 	if binDir == "" {
 		binDir = BIN_DIR
 	}
 
 	if err := client.RunStream([]string{
-		fmt.Sprintf("sudo test -f %[1]s/k3s-uninstall.sh && sudo bash %[1]s/k3s-uninstall.sh", binDir),
+		fmt.Sprintf("%s && %s", sudo("test", "-f").Arg(binDir+"/k3s-uninstall.sh"), sudo("bash").Arg(binDir+"/k3s-uninstall.sh")),
 	}); err != nil {
 		return err
 	}
@@ -254,42 +465,85 @@ func (s *Server) Refresh(ctx context.Context, client ssh_client.SSHClient) (exis
 	s.Token = token
 	tflog.MaskLogStrings(ctx, s.Token)
 
-	kubeConfig, err := s.getKubeConfig(client)
+	kubeConfig, err := readKubeConfig(client)
 	if err != nil {
 		return true, active, err
 	}
 	s.KubeConfig = kubeConfig
 	tflog.MaskLogStrings(ctx, s.KubeConfig)
 
+	if active {
+		if err := s.NodeMetadata.Refresh(ctx, client, s.KubeConfig); err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Could not read node labels and taints: %s", err.Error()))
+		}
+	}
+
+	if len(s.Manifests) > 0 {
+		status, err := readManifestStatus(ctx, s.KubeConfig, s.Manifests)
+		if err != nil {
+			status = unknownManifestStatus(ctx, s.Manifests, err)
+		}
+		s.ManifestStatus = status
+	}
+
+	if err := s.refreshConfigFragments(client); err != nil {
+		return true, active, err
+	}
+	if err := s.refreshStaticPods(client); err != nil {
+		return true, active, err
+	}
+	if err := s.refreshCertificateExpiry(client); err != nil {
+		return true, active, err
+	}
+	if s.SecretsEncryption != nil && s.SecretsEncryption.Enabled && active {
+		status, err := readSecretsEncryptionStatus(client, s.BinDir)
+		if err != nil {
+			tflog.Warn(ctx, err.Error())
+		}
+		s.SecretsEncryptionStatus = status
+	}
+	template, err := refreshContainerdConfigTemplate(client, s.MergedConfig, s.ContainerdConfigTemplate)
+	if err != nil {
+		return true, active, err
+	}
+	s.ContainerdConfigTemplate = template
+
+	observed, err := readExtraFiles(client, sortedKeys(s.ExtraFiles))
+	if err != nil {
+		return true, active, err
+	}
+	s.ObservedExtraFiles = observed
+
+	nodeInfo, err := readNodeInfo(ctx, client, s.BinDir)
+	if err != nil {
+		return true, active, err
+	}
+	s.NodeInfo = nodeInfo
+
 	return true, active, nil
 }
 
-func (s *Server) installCommand() string {
-	flags := []string{
-		"INSTALL_K3S_SKIP_START=true",
-		fmt.Sprintf("INSTALL_K3S_BIN_DIR=%s", s.BinDir),
-		fmt.Sprintf("INSTALL_K3S_EXEC='--config %s/config.yaml'", CONFIG_DIR),
-	}
+func (s *Server) installCommand() (string, error) {
+	command := sudo().
+		Env("INSTALL_K3S_SKIP_START", "true").
+		Env("INSTALL_K3S_BIN_DIR", s.BinDir).
+		Env("INSTALL_K3S_EXEC", fmt.Sprintf("--config %s/config.yaml", CONFIG_DIR))
 
 	// Join existing cluster as HA node or bootstrap with an existing one
 	if s.Token != "" {
-		flags = append(flags, fmt.Sprintf("K3S_TOKEN=%s", shellQuote(s.Token)))
+		command.Env("K3S_TOKEN", s.Token)
 	}
 
 	// Did version get set?
 	if s.Version != "" {
-		flags = append(flags, fmt.Sprintf("INSTALL_K3S_VERSION=\"%s\"", s.Version))
+		command.Env("INSTALL_K3S_VERSION", s.Version)
 	}
 
-	for k, v := range s.Env {
-		flags = append(flags, fmt.Sprintf("%s=\"%s\"", k, v))
+	for _, k := range sortedKeys(s.Env) {
+		command.Env(k, s.Env[k])
 	}
 
-	return fmt.Sprintf("sudo %s bash %s/k3s-install.sh", strings.Join(flags, " "), s.BinDir)
-}
-
-func shellQuote(value string) string {
-	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	return command.Literal("bash").Arg(s.BinDir + "/k3s-install.sh").Build()
 }
 
 func k3sServiceExists(client ssh_client.SSHClient) (bool, error) {
@@ -301,10 +555,7 @@ func k3sServiceActive(client ssh_client.SSHClient) (bool, error) {
 }
 
 func (s *Server) dataDir() string {
-	if dir, ok := s.config["data_dir"].(string); ok && dir != "" {
-		return dir
-	}
-	return DATA_DIR
+	return configDataDir(s.config)
 }
 
 // Retrieve server token.
@@ -331,26 +582,15 @@ func (s *Server) getToken(client ssh_client.SSHClient) (string, error) {
 
 // Retrieve server token.
 func (s *Server) getServerEnv(client ssh_client.SSHClient) (map[string]string, error) {
-	file, err := client.ReadFile("/etc/systemd/system/k3s.service.env", false, true)
+	file, err := client.ReadFile(serverEnvPath, false, true)
 	if err != nil {
 		return nil, err
 	}
 	return godotenv.Unmarshal(file)
 }
 
-func syncExtraFiles(extraFiles map[string]string) (commands []string) {
-	for k, v := range extraFiles {
-		commands = append(commands,
-			fmt.Sprintf("sudo mkdir -p $(sudo realpath $(dirname %s))", k),
-		)
-		commands = append(commands, WriteFileCommands(k, base64.StdEncoding.EncodeToString([]byte(v)))...)
-	}
-
-	return
-}
-
-// Retrieve kubeconfig.
-func (s *Server) getKubeConfig(client ssh_client.SSHClient) (string, error) {
+// Retrieve kubeconfig, pointed at the ssh host.
+func readKubeConfig(client ssh_client.SSHClient) (string, error) {
 	kubeconfig, err := client.ReadFile("/etc/rancher/k3s/k3s.yaml", false, true)
 	if err != nil {
 		return "", fmt.Errorf("could not retrieve kubeconfig: %s", err.Error())
@@ -384,9 +624,9 @@ func updateKubeConfig(kubeconfigText string, host string) (string, error) {
 
 func (s *Server) addFile(path string, content string) {
 	if s.ExtraFiles == nil {
-		s.ExtraFiles = make(map[string]string)
+		s.ExtraFiles = make(map[string]ExtraFile)
 	}
-	s.ExtraFiles[path] = content
+	s.ExtraFiles[path] = ExtraFile{Content: content}
 }
 
 func (s *Server) OIDCJWKSKeys(client ssh_client.SSHClient) (string, error) {
@@ -395,7 +635,7 @@ func (s *Server) OIDCJWKSKeys(client ssh_client.SSHClient) (string, error) {
 		binDir = BIN_DIR
 	}
 
-	res, err := client.Run(fmt.Sprintf("sudo %s/k3s kubectl get --raw /openid/v1/jwks", binDir))
+	res, err := client.Run(sudo().Arg(binDir+"/k3s").Literal("kubectl", "get", "--raw", "/openid/v1/jwks").String())
 	if err != nil {
 		return "", fmt.Errorf("fetching oidc jwks keys: %s", err.Error())
 	}
@@ -405,3 +645,32 @@ func (s *Server) OIDCJWKSKeys(client ssh_client.SSHClient) (string, error) {
 
 	return strings.TrimSpace(res[0]), nil
 }
+
+// Reads back the managed static pods. Must run after refreshConfigFragments.
+func (s *Server) refreshStaticPods(client ssh_client.SSHClient) error {
+	pods, err := refreshStaticPods(client, s.MergedConfig, s.StaticPods)
+	if err != nil {
+		return err
+	}
+	s.StaticPods = pods
+	return nil
+}
+
+// Reads back the managed drop-ins and the merged config.
+func (s *Server) refreshConfigFragments(client ssh_client.SSHClient) error {
+	if len(s.ConfigFragments) > 0 {
+		fragments, err := readConfigFragments(client, sortedKeys(s.ConfigFragments))
+		if err != nil {
+			return err
+		}
+		s.ConfigFragments = fragments
+	}
+
+	merged, err := readMergedConfig(client)
+	if err != nil {
+		return err
+	}
+	s.MergedConfig = merged
+
+	return nil
+}
@@ -78,11 +78,14 @@ func TestServerWithHaJoinCluster(t *testing.T) {
 		t.Errorf("server config = %#v, want %q", got, "https://10.0.0.1:6443")
 	}
 
-	command := server.installCommand()
+	command, err := server.installCommand()
+	if err != nil {
+		t.Fatalf("installCommand() error = %v", err)
+	}
 	if !strings.Contains(command, "K3S_TOKEN='join-token'") {
 		t.Errorf("installCommand() = %q, want K3S_TOKEN flag", command)
 	}
-	if !strings.Contains(command, "INSTALL_K3S_BIN_DIR=/usr/local/bin") {
+	if !strings.Contains(command, "INSTALL_K3S_BIN_DIR='/usr/local/bin'") {
 		t.Errorf("installCommand() = %q, want default INSTALL_K3S_BIN_DIR", command)
 	}
 }
@@ -110,12 +113,6 @@ func TestServerWithHaExternalDatastore(t *testing.T) {
 	}
 }
 
-func TestShellQuote(t *testing.T) {
-	if got, want := shellQuote("token'with dollar$"), "'token'\\''with dollar$'"; got != want {
-		t.Errorf("shellQuote() = %q, want %q", got, want)
-	}
-}
-
 func TestParseK3sVersionOutput(t *testing.T) {
 	output := `k3s version v1.32.6+k3s1 (eb603acd)
 go version go1.23.10
@@ -136,3 +133,44 @@ func TestParseK3sVersionOutputError(t *testing.T) {
 		t.Fatalf("parseK3sVersionOutput() expected error")
 	}
 }
+
+func TestServerValidateMergesFlags(t *testing.T) {
+	server := Server{
+		Config: "disable-agent: true\n",
+		Flags: map[string]any{
+			"tls-san":               []string{"k3s.example.com"},
+			"write-kubeconfig-mode": "0600",
+		},
+	}
+
+	if err := server.Validate(context.Background()); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if got, ok := server.config["tls-san"].([]string); !ok || len(got) != 1 || got[0] != "k3s.example.com" {
+		t.Errorf("tls-san = %#v, want [k3s.example.com]", server.config["tls-san"])
+	}
+	if got := server.config["write-kubeconfig-mode"]; got != "0600" {
+		t.Errorf("write-kubeconfig-mode = %#v, want 0600", got)
+	}
+	if got := server.config["disable-agent"]; got != true {
+		t.Errorf("disable-agent = %#v, want true", got)
+	}
+}
+
+func TestServerValidateFlagConflicts(t *testing.T) {
+	for _, config := range []string{"tls-san:\n  - a.example.com\n", "tls-san+:\n  - a.example.com\n"} {
+		server := Server{
+			Config: config,
+			Flags:  map[string]any{"tls-san": []string{"b.example.com"}},
+		}
+
+		err := server.Validate(context.Background())
+		if err == nil {
+			t.Fatalf("Validate() expected conflict error for config %q", config)
+		}
+		if !strings.Contains(err.Error(), "tls_san") {
+			t.Errorf("Validate() error = %q, want attribute name in error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvName checks that name can be exported as a shell environment
+// variable.
+func ValidateEnvName(name string) error {
+	if !envNamePattern.MatchString(name) {
+		return fmt.Errorf("environment variable name %q must match %s", name, envNamePattern.String())
+	}
+	return nil
+}
+
+// Checks every env var name, in a stable order.
+func validateEnv(env map[string]string) error {
+	for _, name := range sortedKeys(env) {
+		if err := ValidateEnvName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Quotes a value as a single shell word.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// A single remote command line. Words given to newCommand, sudo and Literal
+// are trusted literals written in this package. Values given to Arg and Env
+// are always quoted, so they can never be interpreted by the shell.
+type shellCommand struct {
+	words []string
+	err   error
+}
+
+func newCommand(words ...string) *shellCommand {
+	return &shellCommand{words: words}
+}
+
+func sudo(words ...string) *shellCommand {
+	return newCommand(append([]string{"sudo"}, words...)...)
+}
+
+// Literal appends trusted words such as flags and redirections.
+func (c *shellCommand) Literal(words ...string) *shellCommand {
+	c.words = append(c.words, words...)
+	return c
+}
+
+// Arg appends quoted values.
+func (c *shellCommand) Arg(values ...string) *shellCommand {
+	for _, value := range values {
+		c.words = append(c.words, shellQuote(value))
+	}
+	return c
+}
+
+// Env appends a NAME='value' assignment. An invalid name is reported by Build.
+func (c *shellCommand) Env(name string, value string) *shellCommand {
+	if err := ValidateEnvName(name); err != nil {
+		if c.err == nil {
+			c.err = err
+		}
+		return c
+	}
+	c.words = append(c.words, name+"="+shellQuote(value))
+	return c
+}
+
+// Build renders the command, failing if any Env name was invalid.
+func (c *shellCommand) Build() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	return strings.Join(c.words, " "), nil
+}
+
+// String renders a command that has no Env assignments.
+func (c *shellCommand) String() string {
+	return strings.Join(c.words, " ")
+}
+
+// Pipes the output of every command into the next.
+func pipeline(commands ...*shellCommand) string {
+	rendered := make([]string, 0, len(commands))
+	for _, command := range commands {
+		rendered = append(rendered, command.String())
+	}
+	return strings.Join(rendered, " | ")
+}
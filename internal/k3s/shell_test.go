@@ -0,0 +1,152 @@
+package k3s
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+var hostileValues = []string{
+	"plain",
+	"with space",
+	`double"quote`,
+	"single'quote",
+	"$(touch /tmp/pwned)",
+	"`touch /tmp/pwned`",
+	"${HOME}",
+	"semi; rm -rf /",
+	"amp && reboot",
+	"pipe | tee /etc/passwd",
+	"new\nline",
+	"glob *",
+	"back\\slash",
+	"'",
+	"",
+}
+
+// Runs the command words through a real shell and returns what it parsed.
+func shellWords(t *testing.T, words string) []string {
+	t.Helper()
+
+	out, err := exec.Command("sh", "-c", "set -- "+words+`; for word in "$@"; do printf '%s\0' "$word"; done`).Output()
+	if err != nil {
+		t.Fatalf("running %q: %v", words, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+}
+
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("token'with dollar$"), "'token'\\''with dollar$'"; got != want {
+		t.Errorf("shellQuote() = %q, want %q", got, want)
+	}
+
+	for _, value := range hostileValues {
+		words := shellWords(t, shellQuote(value))
+		if len(words) != 1 || words[0] != value {
+			t.Errorf("shell parsed shellQuote(%q) as %q", value, words)
+		}
+	}
+}
+
+func TestShellCommandArgs(t *testing.T) {
+	command := newCommand("mkdir", "-p").Arg(hostileValues...).String()
+
+	words := shellWords(t, strings.TrimPrefix(command, "mkdir -p "))
+	if len(words) != len(hostileValues) {
+		t.Fatalf("shell parsed %q into %d words, want %d", command, len(words), len(hostileValues))
+	}
+	for i, value := range hostileValues {
+		if words[i] != value {
+			t.Errorf("word %d = %q, want %q", i, words[i], value)
+		}
+	}
+}
+
+func TestShellCommandEnv(t *testing.T) {
+	for _, value := range hostileValues {
+		command, err := sudo().Env("K3S_TOKEN", value).Literal("bash").Build()
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+
+		words := shellWords(t, strings.TrimSuffix(strings.TrimPrefix(command, "sudo "), " bash"))
+		if len(words) != 1 || words[0] != "K3S_TOKEN="+value {
+			t.Errorf("shell parsed env value %q as %q", value, words)
+		}
+	}
+}
+
+func TestValidateEnvName(t *testing.T) {
+	for _, name := range []string{"INSTALL_K3S_CHANNEL", "_private", "k3s_debug1"} {
+		if err := ValidateEnvName(name); err != nil {
+			t.Errorf("ValidateEnvName(%q) error = %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"", "1NAME", "WITH SPACE", "A=B", "X;reboot", "$(id)", "DASH-NAME"} {
+		if err := ValidateEnvName(name); err == nil {
+			t.Errorf("ValidateEnvName(%q) expected an error", name)
+		}
+		if _, err := sudo().Env(name, "value").Build(); err == nil {
+			t.Errorf("Build() with env name %q expected an error", name)
+		}
+	}
+}
+
+func TestInstallCommandHostileInput(t *testing.T) {
+	agent := Agent{
+		Token:   `tok"en$(id)`,
+		Server:  "https://10.0.0.1:6443; reboot",
+		Version: "v1.32.6+k3s1 `id`",
+		BinDir:  "/opt/my bin",
+		Env:     map[string]string{"INSTALL_K3S_CHANNEL": `stable" && reboot "`},
+	}
+
+	command, err := agent.installCommand()
+	if err != nil {
+		t.Fatalf("installCommand() error = %v", err)
+	}
+
+	words := shellWords(t, strings.TrimPrefix(command, "sudo "))
+	want := []string{
+		"INSTALL_K3S_SKIP_START=true",
+		"INSTALL_K3S_BIN_DIR=/opt/my bin",
+		"INSTALL_K3S_EXEC=agent --config /etc/rancher/k3s/config.yaml",
+		"K3S_URL=https://10.0.0.1:6443; reboot",
+		`K3S_TOKEN=tok"en$(id)`,
+		"INSTALL_K3S_VERSION=v1.32.6+k3s1 `id`",
+		`INSTALL_K3S_CHANNEL=stable" && reboot "`,
+		"bash",
+		"/opt/my bin/k3s-install.sh",
+	}
+	if len(words) != len(want) {
+		t.Fatalf("shell parsed %q into %q, want %q", command, words, want)
+	}
+	for i := range want {
+		if words[i] != want[i] {
+			t.Errorf("word %d = %q, want %q", i, words[i], want[i])
+		}
+	}
+
+	agent.Env = map[string]string{"BAD NAME": "value"}
+	if _, err := agent.installCommand(); err == nil {
+		t.Errorf("installCommand() expected an error for an invalid env name")
+	}
+}
+
+func TestWriteFileCommandsHostilePath(t *testing.T) {
+	path := "/etc/rancher/k3s/tls/$(reboot) key"
+
+	for _, command := range WriteFileCommands(path, "ZGF0YQ==") {
+		if strings.Contains(command, "$(reboot)") && !strings.Contains(command, "'/etc/rancher/k3s/tls/$(reboot) key") {
+			t.Errorf("WriteFileCommands() command %q does not quote the path", command)
+		}
+	}
+	commands := WriteFileCommands(path, "ZGF0YQ==")
+	if got, want := commands[0], "sudo install -m 0600 /dev/null '/etc/rancher/k3s/tls/$(reboot) key.tmp'"; got != want {
+		t.Errorf("WriteFileCommands()[0] = %q, want %q", got, want)
+	}
+	if got, want := commands[len(commands)-1], "sudo rm -f '/etc/rancher/k3s/tls/$(reboot) key.tmp'"; got != want {
+		t.Errorf("WriteFileCommands()[%d] = %q, want %q", len(commands)-1, got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package k3s
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"go.yaml.in/yaml/v2"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// ValidateStaticPods checks that every static pod has a usable file name and
+// contains a single Pod.
+func ValidateStaticPods(pods map[string]string) error {
+	for _, name := range sortedKeys(pods) {
+		if len(name) > 253 || !manifestNamePattern.MatchString(name) || strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml") {
+			return fmt.Errorf("static pod name %q must be a lowercase DNS subdomain without the .yaml extension", name)
+		}
+		if _, _, err := parseStaticPod(pods[name]); err != nil {
+			return fmt.Errorf("static pod %q: %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Namespace and name of the pod in a static pod manifest. The kubelet reads
+// a single Pod from each file.
+func parseStaticPod(content string) (namespace string, name string, err error) {
+	var pod struct {
+		ApiVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name      string `yaml:"name"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(content), &pod); err != nil {
+		return "", "", fmt.Errorf("parsing pod: %s", err.Error())
+	}
+	if pod.ApiVersion != "v1" || pod.Kind != "Pod" {
+		return "", "", fmt.Errorf("must be a v1 Pod, got %s %s", pod.ApiVersion, pod.Kind)
+	}
+	if pod.Metadata.Name == "" {
+		return "", "", fmt.Errorf("pod is missing metadata.name")
+	}
+
+	namespace = pod.Metadata.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace, pod.Metadata.Name, nil
+}
+
+func staticPodPath(dataDir string, name string) string {
+	return fmt.Sprintf("%s/agent/pod-manifests/%s.yaml", dataDir, name)
+}
+
+func staticPodPaths(dataDir string, names ...[]string) (paths []string) {
+	for _, list := range names {
+		for _, name := range list {
+			paths = append(paths, staticPodPath(dataDir, name))
+		}
+	}
+	return
+}
+
+// Commands for writing managed static pods and removing the ones that are
+// no longer declared. The kubelet watches the directory on its own.
+func staticPodCommands(dataDir string, pods map[string]string, stale []string) []string {
+	if len(pods) == 0 && len(stale) == 0 {
+		return nil
+	}
+
+	commands := []string{sudo("mkdir", "-p").Arg(dataDir + "/agent/pod-manifests").String()}
+	for _, name := range sortedKeys(pods) {
+		commands = append(commands, WriteFileCommands(staticPodPath(dataDir, name), base64.StdEncoding.EncodeToString([]byte(pods[name])))...)
+	}
+	for _, name := range stale {
+		commands = append(commands, sudo("rm", "-f").Arg(staticPodPath(dataDir, name)).String())
+	}
+
+	return commands
+}
+
+// Reads back the managed static pods. Pods missing from the host are dropped
+// from the result so drift shows up in the plan.
+func readStaticPods(client ssh_client.SSHClient, dataDir string, names []string) (map[string]string, error) {
+	return readNamedFiles(client, names, func(name string) string {
+		return staticPodPath(dataDir, name)
+	})
+}
+
+// Reads back the managed static pods from the data dir k3s actually uses,
+// since the merged config is all Refresh has to go on.
+func refreshStaticPods(client ssh_client.SSHClient, merged string, pods map[string]string) (map[string]string, error) {
+	if len(pods) == 0 {
+		return pods, nil
+	}
+
+	config, err := parseMergedConfig(merged)
+	if err != nil {
+		return nil, err
+	}
+	return readStaticPods(client, configDataDir(config), sortedKeys(pods))
+}
+
+// Waits until the kubelet reports the mirror pod of every static pod as
+// running. The node's own kubeconfig is used, so this works on agents too.
+// A zero timeout skips the wait, as does a server without an agent, which
+// has no kubelet to run them.
+func waitForStaticPods(ctx context.Context, client ssh_client.SSHClient, binDir string, pods map[string]string, timeout time.Duration) error {
+	if len(pods) == 0 || timeout == 0 {
+		return nil
+	}
+
+	config, err := readObservedConfig(client)
+	if err != nil {
+		return err
+	}
+	if configDisablesAgent(config) {
+		tflog.Warn(ctx, "Not waiting for static pods, disable-agent is set and no kubelet runs them")
+		return nil
+	}
+	dataDir := configDataDir(config)
+	node, err := nodeName(client, config)
+	if err != nil {
+		return err
+	}
+
+	// The timeout covers all pods, as the kubelet starts them together
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, name := range sortedKeys(pods) {
+		namespace, podName, err := parseStaticPod(pods[name])
+		if err != nil {
+			return fmt.Errorf("static pod %q: %s", name, err.Error())
+		}
+		mirror := fmt.Sprintf("%s-%s", podName, node)
+
+		tflog.Info(ctx, fmt.Sprintf("Waiting for mirror pod %s/%s", namespace, mirror))
+		command := kubeletKubectl(binDir, dataDir+"/agent/kubelet.kubeconfig").
+			Literal("get", "pod", "-n").
+			Arg(namespace, mirror).
+			Literal("-o").
+			Arg("jsonpath={.status.phase}").
+			Literal("2>&1", "||", "true")
+
+		var phase string
+		err = wait.PollUntilContextTimeout(ctx, nodeReadyPollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+			res, err := client.Run(command.String())
+			if err != nil {
+				return false, err
+			}
+			if len(res) == 1 {
+				phase = strings.TrimSpace(res[0])
+			}
+			return phase == "Running", nil
+		})
+		if wait.Interrupted(err) {
+			return fmt.Errorf("mirror pod %s/%s of static pod %q is not running after %s: %s", namespace, mirror, name, timeout, phase)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
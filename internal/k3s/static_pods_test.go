@@ -0,0 +1,65 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateStaticPods(t *testing.T) {
+	pod := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: haproxy\nspec:\n  containers: []\n"
+	tests := []struct {
+		name        string
+		pod         string
+		content     string
+		expectError bool
+	}{
+		{name: "valid", pod: "haproxy", content: pod},
+		{name: "uppercase name", pod: "HAProxy", content: pod, expectError: true},
+		{name: "yaml extension", pod: "haproxy.yaml", content: pod, expectError: true},
+		{name: "path in name", pod: "../haproxy", content: pod, expectError: true},
+		{name: "not a pod", pod: "config", content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n", expectError: true},
+		{name: "missing name", pod: "noname", content: "apiVersion: v1\nkind: Pod\n", expectError: true},
+		{name: "invalid yaml", pod: "broken", content: "kind: [", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStaticPods(map[string]string{tt.pod: tt.content})
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseStaticPod(t *testing.T) {
+	namespace, name, err := parseStaticPod("apiVersion: v1\nkind: Pod\nmetadata:\n  name: haproxy\n")
+	if err != nil || namespace != "default" || name != "haproxy" {
+		t.Errorf("parseStaticPod() = %q, %q, %v, want default/haproxy", namespace, name, err)
+	}
+
+	namespace, _, err = parseStaticPod("apiVersion: v1\nkind: Pod\nmetadata:\n  name: haproxy\n  namespace: kube-system\n")
+	if err != nil || namespace != "kube-system" {
+		t.Errorf("parseStaticPod() namespace = %q, %v, want kube-system", namespace, err)
+	}
+}
+
+func TestStaticPodCommands(t *testing.T) {
+	if commands := staticPodCommands(DATA_DIR, nil, nil); len(commands) != 0 {
+		t.Fatalf("staticPodCommands() = %v, want none", commands)
+	}
+
+	joined := strings.Join(staticPodCommands("/data/k3s", map[string]string{"haproxy": "a"}, []string{"old"}), "\n")
+	for _, want := range []string{
+		"sudo mkdir -p '/data/k3s/agent/pod-manifests'",
+		"'/data/k3s/agent/pod-manifests/haproxy.yaml'",
+		"sudo rm -f '/data/k3s/agent/pod-manifests/old.yaml'",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("staticPodCommands() = %q, want %q", joined, want)
+		}
+	}
+}
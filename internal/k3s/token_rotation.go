@@ -0,0 +1,68 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Env file written by the install script and loaded by k3s.service
+const serverEnvPath = "/etc/systemd/system/k3s.service.env"
+
+// Tokens end up in the systemd env file, so keep them to a single plain word.
+var rotateTokenPattern = regexp.MustCompile(`^[^\s'"\\]+$`)
+
+// ValidateRotateToken checks that token can be used as a new server token.
+func ValidateRotateToken(token string) error {
+	if !rotateTokenPattern.MatchString(token) {
+		return fmt.Errorf("rotate_token must not be empty or contain whitespace, quotes or backslashes")
+	}
+	return nil
+}
+
+// RotateToken replaces the cluster token with newToken. The service env
+// file is updated right away, since k3s will not start with the old token
+// once the rotation went through, and the next install or restart picks up
+// s.Token.
+func (s *Server) RotateToken(ctx context.Context, client ssh_client.SSHClient, newToken string) error {
+	if err := ValidateRotateToken(newToken); err != nil {
+		return err
+	}
+	if err := client.WaitForReady(); err != nil {
+		return err
+	}
+
+	oldToken, err := s.getToken(client)
+	if err != nil {
+		return err
+	}
+	if oldToken == "" {
+		return fmt.Errorf("could not read the current server token")
+	}
+	tflog.MaskMessageStrings(ctx, oldToken, newToken)
+
+	tflog.Info(ctx, "Rotating k3s server token")
+	if err := client.RunStream(tokenRotateCommands(s.BinDir, oldToken, newToken)); err != nil {
+		return fmt.Errorf("rotating server token: %s", err.Error())
+	}
+	s.Token = newToken
+
+	return nil
+}
+
+// Commands for rotating the token and writing it to the service env file.
+func tokenRotateCommands(binDir string, oldToken string, newToken string) []string {
+	if binDir == "" {
+		binDir = BIN_DIR
+	}
+
+	return []string{
+		sudo().Arg(binDir+"/k3s").Literal("token", "rotate", "--token").Arg(oldToken).Literal("--new-token").Arg(newToken).String(),
+		sudo("touch").Arg(serverEnvPath).String(),
+		sudo("sed", "-i").Arg("/^K3S_TOKEN=/d", serverEnvPath).String(),
+		pipeline(newCommand("echo").Arg("K3S_TOKEN="+shellQuote(newToken)), sudo("tee", "-a").Arg(serverEnvPath).Literal("> /dev/null")),
+	}
+}
@@ -0,0 +1,36 @@
+package k3s
+
+import (
+	"testing"
+)
+
+func TestValidateRotateToken(t *testing.T) {
+	for _, token := range []string{"abcdef0123456789", "K10abc::server:secret"} {
+		if err := ValidateRotateToken(token); err != nil {
+			t.Errorf("ValidateRotateToken(%q) error = %v", token, err)
+		}
+	}
+	for _, token := range []string{"", "two words", "it's", "back\\slash", "new\nline"} {
+		if err := ValidateRotateToken(token); err == nil {
+			t.Errorf("ValidateRotateToken(%q) accepted an invalid token", token)
+		}
+	}
+}
+
+func TestTokenRotateCommands(t *testing.T) {
+	got := tokenRotateCommands("", "K10abc::server:old", "newtoken")
+	want := []string{
+		"sudo '/usr/local/bin/k3s' token rotate --token 'K10abc::server:old' --new-token 'newtoken'",
+		"sudo touch '/etc/systemd/system/k3s.service.env'",
+		"sudo sed -i '/^K3S_TOKEN=/d' '/etc/systemd/system/k3s.service.env'",
+		"echo 'K3S_TOKEN='\\''newtoken'\\''' | sudo tee -a '/etc/systemd/system/k3s.service.env' > /dev/null",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("tokenRotateCommands() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("tokenRotateCommands()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+const defaultAddonsTimeout = "10m"
+
+func waitForAddonsSchema() schema.Attribute {
+	return schema.BoolAttribute{
+		Optional: true,
+		Computed: true,
+		Default:  booldefault.StaticBool(false),
+		MarkdownDescription: "Wait after install and update until the packaged add-ons not listed in `disable` are available in `kube-system`, and the install job of every HelmChart there succeeded. " +
+			"The status of every add-on is reported when they are not healthy within `addons_timeout`. Defaults to `false`.",
+	}
+}
+
+func addonsTimeoutSchema() schema.Attribute {
+	return schema.StringAttribute{
+		Optional:            true,
+		Computed:            true,
+		Default:             stringdefault.StaticString(defaultAddonsTimeout),
+		MarkdownDescription: "How long `wait_for_addons` waits, as a Go duration. Defaults to `" + defaultAddonsTimeout + "`.",
+	}
+}
+
+// Add-on timeout of the plan, zero unless wait_for_addons is set.
+func addonsTimeoutFromModel(wait types.Bool, value types.String) time.Duration {
+	if !wait.ValueBool() {
+		return 0
+	}
+	if value.IsNull() || value.IsUnknown() {
+		return k3s.ADDON_TIMEOUT
+	}
+	timeout, err := time.ParseDuration(value.ValueString())
+	if err != nil || timeout <= 0 {
+		return k3s.ADDON_TIMEOUT
+	}
+	return timeout
+}
+
+func validateAddonsTimeout(value types.String, d *diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() {
+		return
+	}
+	timeout, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		d.AddAttributeError(path.Root("addons_timeout"), "validating addons_timeout", fmt.Sprintf("addons_timeout must be a duration such as 10m: %s", err.Error()))
+		return
+	}
+	if timeout <= 0 {
+		d.AddAttributeError(path.Root("addons_timeout"), "validating addons_timeout", "addons_timeout must be positive")
+	}
+}
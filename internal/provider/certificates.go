@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func certificateExpirySchema() schema.Attribute {
+	return schema.MapAttribute{
+		Computed:            true,
+		ElementType:         types.StringType,
+		MarkdownDescription: "Expiry of every certificate under `<data-dir>/server/tls` in RFC 3339 format, keyed by path relative to that directory, for example `client-admin.crt` or `etcd/server-client.crt`.",
+	}
+}
+
+func certificateExpiryToModel(ctx context.Context, expiry map[string]string, d *diag.Diagnostics) types.Map {
+	value, diags := types.MapValueFrom(ctx, types.StringType, expiry)
+	d.Append(diags...)
+	return value
+}
+
+// Certificate rotation settings, nil when the block is not set or unknown.
+func certificateRotationFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *schemas.CertificateRotation {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.CertificateRotation
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+	return &model
+}
+
+func certificateServices(ctx context.Context, rotation *schemas.CertificateRotation, d *diag.Diagnostics) []string {
+	var services []string
+	if rotation == nil || rotation.Services.IsNull() || rotation.Services.IsUnknown() {
+		return services
+	}
+	d.Append(rotation.Services.ElementsAs(ctx, &services, false)...)
+	return services
+}
+
+// Whether the planned rotate_certificates asks for a rotation of an
+// existing server.
+func certificateRotationRequested(ctx context.Context, prior types.Object, planned types.Object, d *diag.Diagnostics) bool {
+	if planned.IsNull() {
+		return false
+	}
+	plannedRotation := certificateRotationFromModel(ctx, planned, d)
+	if plannedRotation == nil || plannedRotation.Trigger.IsUnknown() {
+		// Unknown during plan, assume the trigger changes
+		return true
+	}
+
+	priorRotation := certificateRotationFromModel(ctx, prior, d)
+	if priorRotation == nil {
+		return true
+	}
+	return !plannedRotation.Trigger.Equal(priorRotation.Trigger)
+}
+
+func validateCertificateRotation(ctx context.Context, value types.Object, d *diag.Diagnostics) {
+	rotation := certificateRotationFromModel(ctx, value, d)
+	if rotation == nil {
+		return
+	}
+	if err := rotation.Validate(); err != nil {
+		d.AddError("validating rotate_certificates", err.Error())
+		return
+	}
+	if !fullyKnown(ctx, value) {
+		return
+	}
+	if err := k3s.ValidateCertificateServices(certificateServices(ctx, rotation, d)); err != nil {
+		d.AddError("validating rotate_certificates", err.Error())
+	}
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func certificateRotationObject(trigger types.String) types.Object {
+	return types.ObjectValueMust(schemas.CertificateRotation{}.AttributeTypes(), map[string]attr.Value{
+		"trigger":  trigger,
+		"services": types.ListNull(types.StringType),
+	})
+}
+
+func TestCertificateRotationRequested(t *testing.T) {
+	null := types.ObjectNull(schemas.CertificateRotation{}.AttributeTypes())
+	cases := []struct {
+		name           string
+		prior, planned types.Object
+		want           bool
+	}{
+		{"unset", null, null, false},
+		{"added", null, certificateRotationObject(types.StringValue("2026-10")), true},
+		{"unchanged", certificateRotationObject(types.StringValue("2026-10")), certificateRotationObject(types.StringValue("2026-10")), false},
+		{"changed", certificateRotationObject(types.StringValue("2026-10")), certificateRotationObject(types.StringValue("2027-10")), true},
+		{"removed", certificateRotationObject(types.StringValue("2026-10")), null, false},
+		{"unknown trigger", certificateRotationObject(types.StringValue("2026-10")), certificateRotationObject(types.StringUnknown()), true},
+	}
+	for _, c := range cases {
+		var d diag.Diagnostics
+		if got := certificateRotationRequested(context.Background(), c.prior, c.planned, &d); got != c.want {
+			t.Errorf("%s: certificateRotationRequested() = %v, want %v", c.name, got, c.want)
+		}
+		if d.HasError() {
+			t.Errorf("%s: certificateRotationRequested() diagnostics = %v", c.name, d)
+		}
+	}
+}
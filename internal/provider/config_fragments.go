@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+func configFragmentsSchema() schema.Attribute {
+	return schema.MapAttribute{
+		Optional:    true,
+		ElementType: types.StringType,
+		MarkdownDescription: "Managed `" + k3s.CONFIG_FRAGMENTS_DIR + "` drop-ins, keyed by file name without the `.yaml` extension. " +
+			"Fragments removed from this map are deleted from the host. Drop-ins written by other tooling are left untouched.",
+	}
+}
+
+func mergedConfigSchema() schema.Attribute {
+	return schema.StringAttribute{
+		Computed:            true,
+		MarkdownDescription: "Observed `config.yaml` merged with every drop-in in `" + k3s.CONFIG_FRAGMENTS_DIR + "`, in the order k3s applies them.",
+	}
+}
+
+func configFragmentsFromModel(ctx context.Context, value types.Map, d *diag.Diagnostics) map[string]string {
+	fragments := make(map[string]string)
+	if value.IsNull() || value.IsUnknown() {
+		return fragments
+	}
+
+	d.Append(value.ElementsAs(ctx, &fragments, false)...)
+	return fragments
+}
+
+// Whether the map and every fragment in it are known during plan.
+func configFragmentsKnown(value types.Map) bool {
+	if value.IsUnknown() {
+		return false
+	}
+	for _, element := range value.Elements() {
+		if element.IsUnknown() {
+			return false
+		}
+	}
+	return true
+}
+
+func validateConfigFragments(ctx context.Context, value types.Map, d *diag.Diagnostics) {
+	if !configFragmentsKnown(value) {
+		return
+	}
+
+	fragments := configFragmentsFromModel(ctx, value, d)
+	if d.HasError() {
+		return
+	}
+	if err := k3s.ValidateConfigFragments(fragments); err != nil {
+		d.AddError("validating config_fragments", err.Error())
+	}
+}
+
+// Fragments to validate alongside the config. Unknown fragments are
+// validated during apply instead.
+func plannedConfigFragments(ctx context.Context, value types.Map, d *diag.Diagnostics) map[string]string {
+	if !configFragmentsKnown(value) {
+		return nil
+	}
+	return configFragmentsFromModel(ctx, value, d)
+}
+
+// Names present in the prior state that are no longer declared.
+func staleConfigFragments(prior map[string]string, planned map[string]string) []string {
+	stale := []string{}
+	for name := range prior {
+		if _, ok := planned[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// State value for the fragments observed on the host. Unconfigured
+// fragments stay null.
+func configFragmentsToModel(ctx context.Context, declared types.Map, observed map[string]string, d *diag.Diagnostics) types.Map {
+	if declared.IsNull() {
+		return declared
+	}
+
+	value, diags := types.MapValueFrom(ctx, types.StringType, observed)
+	d.Append(diags...)
+	return value
+}
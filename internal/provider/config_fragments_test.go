@@ -0,0 +1,18 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStaleConfigFragments(t *testing.T) {
+	prior := map[string]string{"10-a": "", "20-b": "", "30-c": ""}
+	planned := map[string]string{"20-b": "", "40-d": ""}
+
+	if got, want := staleConfigFragments(prior, planned), []string{"10-a", "30-c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staleConfigFragments() = %v, want %v", got, want)
+	}
+	if got := staleConfigFragments(nil, planned); len(got) != 0 {
+		t.Errorf("staleConfigFragments() = %v, want none", got)
+	}
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+func containerdConfigTemplateSchema() schema.Attribute {
+	return schema.StringAttribute{
+		Optional: true,
+		MarkdownDescription: "Containerd config template written to `<data-dir>/agent/etc/containerd/config.toml.tmpl`, which k3s renders in place " +
+			"of its generated containerd config. Must be valid Go template syntax around valid TOML. Changes restart the k3s service, " +
+			"and removing the attribute deletes the template so k3s goes back to its generated config.",
+	}
+}
+
+func validateContainerdConfigTemplate(value types.String, d *diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() {
+		return
+	}
+	if err := k3s.ValidateContainerdConfigTemplate(value.ValueString()); err != nil {
+		d.AddError("validating containerd_config_template", err.Error())
+	}
+}
+
+// Whether a template in the prior state is no longer declared.
+func removeContainerdConfigTemplate(prior types.String, planned types.String) bool {
+	return prior.ValueString() != "" && planned.ValueString() == ""
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func customCAModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *schemas.CustomCA {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.CustomCA
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+	return &model
+}
+
+// Custom CA material, nil when the block is not set.
+func customCAFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.CustomCA {
+	model := customCAModel(ctx, value, d)
+	if model == nil {
+		return nil
+	}
+	return &k3s.CustomCA{
+		RootCert:         model.RootCert.ValueString(),
+		RootKey:          model.RootKey.ValueString(),
+		IntermediateCert: model.IntermediateCert.ValueString(),
+		IntermediateKey:  model.IntermediateKey.ValueString(),
+	}
+}
+
+// Whether the planned custom_ca asks for a different CA than the one the
+// server was created or last rotated with. Removing the block leaves the
+// CA in place.
+func customCAChanged(ctx context.Context, prior types.Object, planned types.Object, d *diag.Diagnostics) bool {
+	if planned.IsNull() {
+		return false
+	}
+	if planned.IsUnknown() {
+		return true
+	}
+
+	plannedCA := customCAModel(ctx, planned, d)
+	priorCA := customCAModel(ctx, prior, d)
+	if plannedCA == nil {
+		return false
+	}
+	return priorCA == nil || !plannedCA.SameAuthority(*priorCA)
+}
+
+func validateCustomCA(ctx context.Context, value types.Object, d *diag.Diagnostics) {
+	if !fullyKnown(ctx, value) {
+		return
+	}
+	ca := customCAFromModel(ctx, value, d)
+	if ca == nil {
+		return
+	}
+	if err := ca.Validate(); err != nil {
+		d.AddAttributeError(path.Root("custom_ca"), "validating custom_ca", err.Error())
+	}
+}
+
+// The CA of a bootstrapped server only changes through rotate-ca, which
+// has to be asked for explicitly.
+func validateCustomCAChange(ctx context.Context, prior types.Object, planned types.Object, d *diag.Diagnostics) {
+	if planned.IsUnknown() || !customCAChanged(ctx, prior, planned, d) {
+		return
+	}
+	model := customCAModel(ctx, planned, d)
+	if model != nil && model.AllowRotation.ValueBool() {
+		return
+	}
+	d.AddAttributeError(path.Root("custom_ca"), "changing custom_ca",
+		"The cluster CA is fixed once k3s has started. Set custom_ca.allow_rotation to rotate it with `k3s certificate rotate-ca`, or replace the server.")
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func customCAObject(rootCert string, allowRotation bool) types.Object {
+	return types.ObjectValueMust(schemas.CustomCA{}.AttributeTypes(), map[string]attr.Value{
+		"root_cert":         types.StringValue(rootCert),
+		"root_key":          types.StringValue("root key"),
+		"intermediate_cert": types.StringNull(),
+		"intermediate_key":  types.StringNull(),
+		"allow_rotation":    types.BoolValue(allowRotation),
+		"force_rotation":    types.BoolNull(),
+	})
+}
+
+func TestValidateCustomCAChange(t *testing.T) {
+	null := types.ObjectNull(schemas.CustomCA{}.AttributeTypes())
+	cases := []struct {
+		name           string
+		prior, planned types.Object
+		changed        bool
+		wantErr        bool
+	}{
+		{"unset", null, null, false, false},
+		{"removed", customCAObject("root", false), null, false, false},
+		{"unchanged", customCAObject("root", false), customCAObject("root", false), false, false},
+		{"rotation allowed only", customCAObject("root", false), customCAObject("root", true), false, false},
+		{"changed", customCAObject("root", false), customCAObject("new root", false), true, true},
+		{"added", null, customCAObject("root", false), true, true},
+		{"changed with rotation", customCAObject("root", false), customCAObject("new root", true), true, false},
+	}
+	for _, c := range cases {
+		var d diag.Diagnostics
+		if got := customCAChanged(context.Background(), c.prior, c.planned, &d); got != c.changed {
+			t.Errorf("%s: customCAChanged() = %v, want %v", c.name, got, c.changed)
+		}
+		validateCustomCAChange(context.Background(), c.prior, c.planned, &d)
+		if d.HasError() != c.wantErr {
+			t.Errorf("%s: validateCustomCAChange() diagnostics = %v, want error %v", c.name, d, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+// Drain settings, nil when the block is not set.
+func nodeDrainFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.NodeDrain {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.NodeDrain
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+
+	drain := &k3s.NodeDrain{
+		ClusterAuth:      clusterAuthFromModel(ctx, model.ClusterAuth, d),
+		GracePeriod:      -1,
+		IgnoreDaemonSets: model.IgnoreDaemonsets.ValueBool(),
+	}
+	if !model.Timeout.IsNull() {
+		// Checked by schemas.NodeDrain during plan
+		drain.Timeout, _ = time.ParseDuration(model.Timeout.ValueString())
+	}
+	if !model.GracePeriod.IsNull() {
+		drain.GracePeriod = model.GracePeriod.ValueInt64()
+	}
+	return drain
+}
+
+func validateNodeDrain(ctx context.Context, attribute string, value types.Object, d *diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() {
+		return
+	}
+
+	var model schemas.NodeDrain
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return
+	}
+	if err := model.Validate(); err != nil {
+		d.AddAttributeError(path.Root(attribute), "validating "+attribute, err.Error())
+	}
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+// Snapshot to restore on create, nil when the block is not set.
+func etcdRestoreFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.EtcdRestore {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.EtcdRestore
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+
+	restore := &k3s.EtcdRestore{Path: model.Path.ValueString()}
+	if !model.S3.IsNull() && !model.S3.IsUnknown() {
+		var s3 schemas.EtcdRestoreS3
+		d.Append(model.S3.As(ctx, &s3, basetypes.ObjectAsOptions{})...)
+		restore.S3 = &k3s.EtcdRestoreS3{
+			Bucket:        s3.Bucket.ValueString(),
+			Endpoint:      s3.Endpoint.ValueString(),
+			Region:        s3.Region.ValueString(),
+			Folder:        s3.Folder.ValueString(),
+			AccessKey:     s3.AccessKey.ValueString(),
+			SecretKey:     s3.SecretKey.ValueString(),
+			SkipSSLVerify: s3.SkipSSLVerify.ValueBool(),
+			Insecure:      s3.Insecure.ValueBool(),
+		}
+		if restore.S3.SecretKey != "" {
+			tflog.MaskMessageStrings(ctx, restore.S3.SecretKey)
+		}
+	}
+	return restore
+}
+
+// A restore needs the embedded etcd of an init node and the token of the
+// cluster the snapshot came from.
+func validateEtcdRestore(ctx context.Context, data ServerClientModel, d *diag.Diagnostics) {
+	if data.RestoreFromSnapshot.IsNull() {
+		return
+	}
+	if data.BootstrapToken.IsNull() {
+		d.AddError("validating restore_from_snapshot", "restore_from_snapshot requires bootstrap_token, set to the token of the cluster the snapshot was taken from")
+	}
+	if !data.HaConfig.IsUnknown() {
+		var haConfig *schemas.HaConfig
+		if !data.HaConfig.IsNull() {
+			d.Append(data.HaConfig.As(ctx, &haConfig, basetypes.ObjectAsOptions{})...)
+		}
+		if haConfig == nil || (!haConfig.ClusterInit.IsUnknown() && !haConfig.ClusterInit.ValueBool()) {
+			d.AddError("validating restore_from_snapshot", "restore_from_snapshot requires highly_available.cluster_init")
+		}
+	}
+	if !fullyKnown(ctx, data.RestoreFromSnapshot) || d.HasError() {
+		return
+	}
+
+	var model schemas.EtcdRestore
+	d.Append(data.RestoreFromSnapshot.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return
+	}
+	if err := model.Validate(); err != nil {
+		d.AddError("validating restore_from_snapshot", err.Error())
+		return
+	}
+	restore := etcdRestoreFromModel(ctx, data.RestoreFromSnapshot, d)
+	if d.HasError() {
+		return
+	}
+	if err := restore.Validate(); err != nil {
+		d.AddError("validating restore_from_snapshot", err.Error())
+	}
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+// Server that etcd snapshot commands run on, shared by the etcd snapshot
+// resources.
+type etcdSnapshotTargetModel struct {
+	Auth    types.Object `tfsdk:"auth"`
+	BinDir  types.String `tfsdk:"bin_dir"`
+	DataDir types.String `tfsdk:"data_dir"`
+}
+
+func etcdSnapshotTargetAttributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"auth": kubeConfigResourceSSHSchema(),
+		"bin_dir": schema.StringAttribute{
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString(k3s.BIN_DIR),
+			MarkdownDescription: "Directory containing the k3s binary on the server.",
+		},
+		"data_dir": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "Data dir of the server. k3s reads it from the server config when unset.",
+		},
+	}
+}
+
+func (m etcdSnapshotTargetModel) validate(ctx context.Context, d *diag.Diagnostics) {
+	if m.Auth.IsNull() || m.Auth.IsUnknown() {
+		return
+	}
+
+	var sshConfig ssh_client.SSHConfig
+	d.Append(m.Auth.As(ctx, &sshConfig, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return
+	}
+	if err := sshConfig.Validate(); err != nil {
+		d.AddError("validating auth", err.Error())
+	}
+}
+
+func (m etcdSnapshotTargetModel) target(ctx context.Context, d *diag.Diagnostics) (ssh_client.SSHClient, k3s.EtcdSnapshotTarget) {
+	var sshConfig ssh_client.SSHConfig
+	d.Append(m.Auth.As(ctx, &sshConfig, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return ssh_client.SSHClient{}, k3s.EtcdSnapshotTarget{}
+	}
+	sshClient, err := ssh_client.NewSSHClient(ctx, sshConfig)
+	if err != nil {
+		d.AddError("creating ssh client", err.Error())
+		return ssh_client.SSHClient{}, k3s.EtcdSnapshotTarget{}
+	}
+
+	return sshClient, k3s.EtcdSnapshotTarget{
+		BinDir:  m.BinDir.ValueString(),
+		DataDir: m.DataDir.ValueString(),
+	}
+}
+
+// Path of a local snapshot, or the URL of one stored on S3.
+func etcdSnapshotPath(snapshot k3s.EtcdSnapshot) string {
+	return strings.TrimPrefix(snapshot.Location, "file://")
+}
+
+// Splits an import ID into the server SSH URL and the snapshot name.
+func parseEtcdSnapshotImportID(rawID string) (ssh_client.SSHConfig, string, string, string, error) {
+	sshConfig, binDir, err := parseServerImportID(rawID)
+	if err != nil {
+		return ssh_client.SSHConfig{}, "", "", "", err
+	}
+
+	// Already parsed successfully above
+	parsed, _ := url.Parse(rawID)
+	query := parsed.Query()
+	name := query.Get("snapshot")
+	if name == "" {
+		return ssh_client.SSHConfig{}, "", "", "", fmt.Errorf("import id must include the snapshot name as ?snapshot=<name>")
+	}
+	return sshConfig, binDir, query.Get("data_dir"), name, nil
+}
@@ -0,0 +1,47 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+func TestParseEtcdSnapshotImportID(t *testing.T) {
+	sshConfig, binDir, dataDir, name, err := parseEtcdSnapshotImportID("ssh://root@server.example.com?private_key_file=/home/me/.ssh/id_rsa&snapshot=nightly-server-1-1714557600&data_dir=/data/k3s")
+	if err != nil {
+		t.Fatalf("parseEtcdSnapshotImportID() error = %v", err)
+	}
+	if sshConfig.Host.ValueString() != "server.example.com" || binDir != "/usr/local/bin" || dataDir != "/data/k3s" || name != "nightly-server-1-1714557600" {
+		t.Errorf("parseEtcdSnapshotImportID() = %v, %q, %q, %q", sshConfig.Host, binDir, dataDir, name)
+	}
+
+	if _, _, _, _, err := parseEtcdSnapshotImportID("ssh://root@server.example.com?private_key_file=/home/me/.ssh/id_rsa"); err == nil {
+		t.Errorf("parseEtcdSnapshotImportID() accepted an id without a snapshot")
+	}
+}
+
+func TestEtcdSnapshotsToData(t *testing.T) {
+	older := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	snapshots := []k3s.EtcdSnapshot{
+		{Name: "nightly-server-1-1714550400", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/nightly-server-1-1714550400", Size: 1024, CreatedAt: older},
+		{Name: "nightly-server-1-1714554000", Location: "s3://backups/nightly-server-1-1714554000", Size: 2048, CreatedAt: newer},
+		{Name: "etcd-snapshot-server-1-1714554000", Location: "file:///var/lib/rancher/k3s/server/db/snapshots/etcd-snapshot-server-1-1714554000", Size: 4096, CreatedAt: newer},
+	}
+
+	data := etcdSnapshotsToData(snapshots, "nightly")
+	if len(data) != 2 {
+		t.Fatalf("etcdSnapshotsToData() returned %d snapshots, want 2", len(data))
+	}
+	if data[0].Name.ValueString() != "nightly-server-1-1714554000" || data[0].Storage.ValueString() != "s3" || data[0].Path.ValueString() != "s3://backups/nightly-server-1-1714554000" {
+		t.Errorf("etcdSnapshotsToData()[0] = %+v", data[0])
+	}
+	if data[1].Storage.ValueString() != "local" || data[1].Path.ValueString() != "/var/lib/rancher/k3s/server/db/snapshots/nightly-server-1-1714550400" || data[1].CreatedAt.ValueString() != "2024-05-01T08:00:00Z" {
+		t.Errorf("etcdSnapshotsToData()[1] = %+v", data[1])
+	}
+
+	if got := etcdSnapshotsToData(snapshots, ""); len(got) != 3 {
+		t.Errorf("etcdSnapshotsToData() without a prefix returned %d snapshots, want 3", len(got))
+	}
+}
@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+// Typed snapshot settings, nil when the block is not set.
+func etcdSnapshotsFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.EtcdSnapshotConfig {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.EtcdSnapshots
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+
+	config := &k3s.EtcdSnapshotConfig{
+		ScheduleCron: model.ScheduleCron.ValueString(),
+		Retention:    model.Retention.ValueInt64(),
+		Compress:     model.Compress.ValueBool(),
+		Dir:          model.Dir.ValueString(),
+	}
+	if !model.S3.IsNull() && !model.S3.IsUnknown() {
+		var s3 schemas.EtcdSnapshotsS3
+		d.Append(model.S3.As(ctx, &s3, basetypes.ObjectAsOptions{})...)
+		config.S3 = &k3s.EtcdS3Config{
+			Endpoint:      s3.Endpoint.ValueString(),
+			Bucket:        s3.Bucket.ValueString(),
+			Folder:        s3.Folder.ValueString(),
+			Region:        s3.Region.ValueString(),
+			AccessKey:     s3.AccessKey.ValueString(),
+			SecretKey:     s3.SecretKey.ValueString(),
+			EndpointCA:    s3.EndpointCa.ValueString(),
+			SkipSSLVerify: s3.SkipSslVerify.ValueBool(),
+			Insecure:      s3.Insecure.ValueBool(),
+		}
+		if config.S3.SecretKey != "" {
+			tflog.MaskMessageStrings(ctx, config.S3.SecretKey)
+		}
+	}
+	return config
+}
+
+// Planned snapshot settings for config validation, nil until fully known.
+func plannedEtcdSnapshots(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.EtcdSnapshotConfig {
+	if !fullyKnown(ctx, value) {
+		return nil
+	}
+	return etcdSnapshotsFromModel(ctx, value, d)
+}
@@ -0,0 +1,156 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func extraFilesType() types.ObjectType {
+	return types.ObjectType{AttrTypes: schemas.ExtraFile{}.AttributeTypes()}
+}
+
+func extraFilesElements(ctx context.Context, value types.Set, d *diag.Diagnostics) []schemas.ExtraFile {
+	files := []schemas.ExtraFile{}
+	if value.IsNull() || value.IsUnknown() {
+		return files
+	}
+
+	d.Append(value.ElementsAs(ctx, &files, false)...)
+	return files
+}
+
+// Content of an extra file, read from the local source when set.
+func extraFileContent(file schemas.ExtraFile) (string, error) {
+	if file.Source.IsNull() {
+		return file.Content.ValueString(), nil
+	}
+
+	content, err := os.ReadFile(file.Source.ValueString())
+	if err != nil {
+		return "", fmt.Errorf("reading extra file source: %s", err.Error())
+	}
+	return string(content), nil
+}
+
+// Extra files to write, keyed by path, with local sources read from disk.
+func extraFilesFromModel(ctx context.Context, value types.Set, d *diag.Diagnostics) map[string]k3s.ExtraFile {
+	files := make(map[string]k3s.ExtraFile)
+	for _, file := range extraFilesElements(ctx, value, d) {
+		content, err := extraFileContent(file)
+		if err != nil {
+			d.AddError(fmt.Sprintf("reading extra file %s", file.Path.ValueString()), err.Error())
+			continue
+		}
+
+		files[file.Path.ValueString()] = k3s.ExtraFile{
+			Content: content,
+			Mode:    file.Mode.ValueString(),
+			Owner:   file.Owner.ValueString(),
+		}
+	}
+
+	return files
+}
+
+// Paths of the declared extra files, without reading any content.
+func extraFilePaths(ctx context.Context, value types.Set, d *diag.Diagnostics) map[string]k3s.ExtraFile {
+	files := make(map[string]k3s.ExtraFile)
+	for _, file := range extraFilesElements(ctx, value, d) {
+		files[file.Path.ValueString()] = k3s.ExtraFile{}
+	}
+	return files
+}
+
+func validateExtraFiles(ctx context.Context, value types.Set, d *diag.Diagnostics) {
+	if value.IsUnknown() {
+		return
+	}
+	for _, element := range value.Elements() {
+		if element.IsUnknown() {
+			return
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range extraFilesElements(ctx, value, d) {
+		if err := file.Validate(); err != nil {
+			d.AddError("validating extra_files", err.Error())
+			return
+		}
+		if file.Path.IsUnknown() {
+			continue
+		}
+
+		path := file.Path.ValueString()
+		if seen[path] {
+			d.AddError("validating extra_files", fmt.Sprintf("extra file path %q is declared more than once", path))
+			return
+		}
+		seen[path] = true
+
+		if err := k3s.ValidateExtraFiles(map[string]k3s.ExtraFile{path: {}}); err != nil {
+			d.AddError("validating extra_files", err.Error())
+			return
+		}
+	}
+}
+
+// Paths present in the prior state that are no longer declared.
+func staleExtraFiles(prior map[string]k3s.ExtraFile, planned map[string]k3s.ExtraFile) []string {
+	stale := []string{}
+	for path := range prior {
+		if _, ok := planned[path]; !ok {
+			stale = append(stale, path)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// State value for the declared extra files as observed on the host. Files
+// that are missing or whose content drifted are dropped so the next plan
+// rewrites them, and a drifted mode or owner is recorded as observed.
+func extraFilesToModel(ctx context.Context, declared types.Set, observed map[string]k3s.ExtraFileStatus, d *diag.Diagnostics) types.Set {
+	if declared.IsNull() || declared.IsUnknown() {
+		return declared
+	}
+
+	files := []schemas.ExtraFile{}
+	for _, file := range extraFilesElements(ctx, declared, d) {
+		path := file.Path.ValueString()
+		status, ok := observed[path]
+		if !ok {
+			tflog.Info(ctx, fmt.Sprintf("Extra file %s is missing from the host", path))
+			continue
+		}
+
+		content, err := extraFileContent(file)
+		if err != nil {
+			tflog.Warn(ctx, fmt.Sprintf("Could not check extra file %s for drift: %s", path, err.Error()))
+		} else if (k3s.ExtraFile{Content: content}).Sha256() != status.Sha256 {
+			tflog.Info(ctx, fmt.Sprintf("Extra file %s content changed on the host", path))
+			continue
+		}
+
+		if !file.Mode.IsNull() && !status.ModeMatches(file.Mode.ValueString()) {
+			file.Mode = types.StringValue(status.OctalMode())
+		}
+		if !file.Owner.IsNull() && !status.OwnerMatches(file.Owner.ValueString()) {
+			file.Owner = types.StringValue(status.Owner)
+		}
+		files = append(files, file)
+	}
+
+	value, diags := types.SetValueFrom(ctx, extraFilesType(), files)
+	d.Append(diags...)
+	return value
+}
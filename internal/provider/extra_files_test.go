@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func TestStaleExtraFiles(t *testing.T) {
+	prior := map[string]k3s.ExtraFile{"/opt/a": {}, "/opt/b": {}}
+	planned := map[string]k3s.ExtraFile{"/opt/b": {}, "/opt/c": {}}
+
+	if got, want := staleExtraFiles(prior, planned), []string{"/opt/a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staleExtraFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestExtraFilesToModel(t *testing.T) {
+	ctx := context.Background()
+	source := filepath.Join(t.TempDir(), "audit.yaml")
+	if err := os.WriteFile(source, []byte("from source"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	declared, diags := types.SetValueFrom(ctx, extraFilesType(), []schemas.ExtraFile{
+		{Path: types.StringValue("/opt/unchanged"), Content: types.StringValue("a"), Source: types.StringNull(), Mode: types.StringValue("0600"), Owner: types.StringNull()},
+		{Path: types.StringValue("/opt/source"), Content: types.StringNull(), Source: types.StringValue(source), Mode: types.StringNull(), Owner: types.StringValue("root")},
+		{Path: types.StringValue("/opt/drifted"), Content: types.StringValue("b"), Source: types.StringNull(), Mode: types.StringNull(), Owner: types.StringNull()},
+		{Path: types.StringValue("/opt/missing"), Content: types.StringValue("c"), Source: types.StringNull(), Mode: types.StringNull(), Owner: types.StringNull()},
+	})
+	if diags.HasError() {
+		t.Fatalf("building declared set: %v", diags)
+	}
+
+	observed := map[string]k3s.ExtraFileStatus{
+		"/opt/unchanged": {Sha256: k3s.ExtraFile{Content: "a"}.Sha256(), Mode: "644", Owner: "root:root", OwnerId: "0:0"},
+		"/opt/source":    {Sha256: k3s.ExtraFile{Content: "from source"}.Sha256(), Mode: "600", Owner: "k3s:k3s", OwnerId: "990:990"},
+		"/opt/drifted":   {Sha256: k3s.ExtraFile{Content: "edited"}.Sha256(), Mode: "644", Owner: "root:root", OwnerId: "0:0"},
+	}
+
+	var d diag.Diagnostics
+	files := extraFilesElements(ctx, extraFilesToModel(ctx, declared, observed, &d), &d)
+	if d.HasError() {
+		t.Fatalf("extraFilesToModel() diagnostics: %v", d)
+	}
+
+	got := make(map[string]schemas.ExtraFile)
+	for _, file := range files {
+		got[file.Path.ValueString()] = file
+	}
+	if len(got) != 2 {
+		t.Fatalf("extraFilesToModel() kept %v, want only unchanged and source", got)
+	}
+	if mode := got["/opt/unchanged"].Mode.ValueString(); mode != "0644" {
+		t.Errorf("mode = %q, want observed 0644", mode)
+	}
+	if owner := got["/opt/source"].Owner.ValueString(); owner != "k3s:k3s" {
+		t.Errorf("owner = %q, want observed k3s:k3s", owner)
+	}
+
+	if value := extraFilesToModel(ctx, types.SetNull(extraFilesType()), observed, &d); !value.IsNull() {
+		t.Errorf("extraFilesToModel() = %v, want null when undeclared", value)
+	}
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+const defaultHelmTimeout = "10m"
+
+// Where and how the helm resources install their objects.
+type helmTargetModel struct {
+	Auth        types.Object `tfsdk:"auth"`
+	ClusterAuth types.Object `tfsdk:"cluster_auth"`
+	DataDir     types.String `tfsdk:"data_dir"`
+	Wait        types.Bool   `tfsdk:"wait"`
+	Timeout     types.String `tfsdk:"timeout"`
+}
+
+func helmTargetAttributes() map[string]schema.Attribute {
+	auth := kubeConfigResourceSSHSchema().(schema.SingleNestedAttribute)
+	auth.Required = false
+	auth.Optional = true
+	auth.Description = ""
+	auth.MarkdownDescription = "SSH connection to a server. The object is written to the server manifests directory and the API is reached with the server kubeconfig. Conflicts with `cluster_auth`."
+
+	clusterAuth := schemas.ClusterAuth{}.InputSchema().(schema.SingleNestedAttribute)
+	clusterAuth.MarkdownDescription += " The object is applied through the API. Conflicts with `auth`."
+
+	return map[string]schema.Attribute{
+		"auth":         auth,
+		"cluster_auth": clusterAuth,
+		"data_dir": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "Data dir of the server reached with `auth`. Defaults to `" + k3s.DATA_DIR + "`.",
+		},
+		"wait": schema.BoolAttribute{
+			Optional:            true,
+			Computed:            true,
+			Default:             booldefault.StaticBool(true),
+			MarkdownDescription: "Wait for the helm install job to complete on create and update, and for the release to be uninstalled on destroy.",
+		},
+		"timeout": schema.StringAttribute{
+			Optional:            true,
+			Computed:            true,
+			Default:             stringdefault.StaticString(defaultHelmTimeout),
+			MarkdownDescription: "How long to wait, as a Go duration. Defaults to `" + defaultHelmTimeout + "`.",
+		},
+	}
+}
+
+func (m helmTargetModel) validate(ctx context.Context, d *diag.Diagnostics) {
+	if !m.Auth.IsUnknown() && !m.ClusterAuth.IsUnknown() && m.Auth.IsNull() == m.ClusterAuth.IsNull() {
+		d.AddError("validating auth", "exactly one of auth or cluster_auth must be set")
+		return
+	}
+	if !m.Auth.IsNull() && !m.Auth.IsUnknown() {
+		var sshConfig ssh_client.SSHConfig
+		d.Append(m.Auth.As(ctx, &sshConfig, basetypes.ObjectAsOptions{})...)
+		if d.HasError() {
+			return
+		}
+		if err := sshConfig.Validate(); err != nil {
+			d.AddError("validating auth", err.Error())
+			return
+		}
+	}
+	if !m.DataDir.IsNull() && !m.ClusterAuth.IsNull() {
+		d.AddError("validating data_dir", "data_dir can only be set with auth")
+		return
+	}
+	if !m.Timeout.IsNull() && !m.Timeout.IsUnknown() {
+		if _, err := time.ParseDuration(m.Timeout.ValueString()); err != nil {
+			d.AddError("validating timeout", fmt.Sprintf("timeout must be a duration such as 10m: %s", err.Error()))
+		}
+	}
+}
+
+// How long to wait for the helm controller, zero when waiting is disabled.
+func (m helmTargetModel) timeout() time.Duration {
+	if !m.Wait.ValueBool() {
+		return 0
+	}
+	timeout, err := time.ParseDuration(m.Timeout.ValueString())
+	if err != nil {
+		timeout, _ = time.ParseDuration(defaultHelmTimeout)
+	}
+	return timeout
+}
+
+func (m helmTargetModel) target(ctx context.Context, d *diag.Diagnostics) *k3s.HelmTarget {
+	if !m.ClusterAuth.IsNull() {
+		clusterAuth := clusterAuthFromModel(ctx, m.ClusterAuth, d)
+		if d.HasError() {
+			return nil
+		}
+		return &k3s.HelmTarget{ClusterAuth: clusterAuth}
+	}
+
+	var sshConfig ssh_client.SSHConfig
+	d.Append(m.Auth.As(ctx, &sshConfig, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+	sshClient, err := ssh_client.NewSSHClient(ctx, sshConfig)
+	if err != nil {
+		d.AddError("creating ssh client", err.Error())
+		return nil
+	}
+	return &k3s.HelmTarget{SSH: &sshClient, DataDir: m.DataDir.ValueString()}
+}
+
+// API endpoint and credentials passed in as cluster_auth, nil when unset.
+func clusterAuthFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.ClusterAuth {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var clusterAuth schemas.ClusterAuth
+	d.Append(value.As(ctx, &clusterAuth, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+	return &k3s.ClusterAuth{
+		Server:                   clusterAuth.Server.ValueString(),
+		CertificateAuthorityData: clusterAuth.CertificateAuthorityData.ValueString(),
+		ClientCertificateData:    clusterAuth.ClientCertificateData.ValueString(),
+		ClientKeyData:            clusterAuth.ClientKeyData.ValueString(),
+	}
+}
+
+// State value for an optional string read back from the cluster. An unset
+// attribute stays null while the cluster has no value for it.
+func observedString(prior types.String, observed string) types.String {
+	if observed == "" && prior.IsNull() {
+		return prior
+	}
+	return types.StringValue(observed)
+}
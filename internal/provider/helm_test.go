@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+func TestHelmTargetModelValidate(t *testing.T) {
+	ctx := context.Background()
+	sshConfig := ssh_client.SSHConfig{
+		User:           types.StringValue("root"),
+		Host:           types.StringValue("server.example.com"),
+		Port:           types.Int32Value(22),
+		PrivateKey:     types.StringValue("key"),
+		Password:       types.StringNull(),
+		PrivateKeyFile: types.StringNull(),
+		HostKey:        types.StringNull(),
+		HostKeyFile:    types.StringNull(),
+	}
+	auth := sshConfig.ToObject(ctx)
+	clusterAuth := (&schemas.ClusterAuth{
+		Server:                   types.StringValue("https://server.example.com:6443"),
+		CertificateAuthorityData: types.StringValue("ca"),
+		ClientCertificateData:    types.StringValue("cert"),
+		ClientKeyData:            types.StringValue("key"),
+	}).ToObject(ctx)
+	noAuth := types.ObjectNull(ssh_client.SSHConfig{}.AttributeTypes())
+	noClusterAuth := types.ObjectNull(schemas.ClusterAuth{}.AttributeTypes())
+
+	tests := []struct {
+		name        string
+		model       helmTargetModel
+		expectError bool
+	}{
+		{name: "ssh", model: helmTargetModel{Auth: auth, ClusterAuth: noClusterAuth, DataDir: types.StringValue("/data/k3s"), Timeout: types.StringValue("5m")}},
+		{name: "api", model: helmTargetModel{Auth: noAuth, ClusterAuth: clusterAuth, DataDir: types.StringNull(), Timeout: types.StringNull()}},
+		{name: "neither", model: helmTargetModel{Auth: noAuth, ClusterAuth: noClusterAuth, DataDir: types.StringNull(), Timeout: types.StringNull()}, expectError: true},
+		{name: "both", model: helmTargetModel{Auth: auth, ClusterAuth: clusterAuth, DataDir: types.StringNull(), Timeout: types.StringNull()}, expectError: true},
+		{name: "data dir with api", model: helmTargetModel{Auth: noAuth, ClusterAuth: clusterAuth, DataDir: types.StringValue("/data/k3s"), Timeout: types.StringNull()}, expectError: true},
+		{name: "invalid timeout", model: helmTargetModel{Auth: noAuth, ClusterAuth: clusterAuth, DataDir: types.StringNull(), Timeout: types.StringValue("10")}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d diag.Diagnostics
+			tt.model.validate(ctx, &d)
+			if tt.expectError && !d.HasError() {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && d.HasError() {
+				t.Fatalf("expected no error, got: %v", d)
+			}
+		})
+	}
+}
+
+func TestHelmTargetModelTimeout(t *testing.T) {
+	if got := (helmTargetModel{Wait: types.BoolValue(false), Timeout: types.StringValue("5m")}).timeout(); got != 0 {
+		t.Errorf("timeout() = %s without waiting, want 0", got)
+	}
+	if got := (helmTargetModel{Wait: types.BoolValue(true), Timeout: types.StringValue("5m")}).timeout(); got != 5*time.Minute {
+		t.Errorf("timeout() = %s, want 5m", got)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 
 	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
 	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
 )
 
@@ -33,19 +34,36 @@ type K3sAgentResource struct{}
 
 type AgentClientModel struct {
 	// Inputs
-	Version     types.String `tfsdk:"version"`
-	Auth        types.Object `tfsdk:"auth"`
-	BinDir      types.String `tfsdk:"bin_dir"`
-	K3sConfig   types.String `tfsdk:"config"`
-	K3sRegistry types.String `tfsdk:"registry"`
-	Env         types.Map    `tfsdk:"env"`
-	Server      types.String `tfsdk:"server"`
-	Token       types.String `tfsdk:"token"`
-	Orphan      types.Bool   `tfsdk:"orphan"`
+	Version         types.String `tfsdk:"version"`
+	Auth            types.Object `tfsdk:"auth"`
+	BinDir          types.String `tfsdk:"bin_dir"`
+	K3sConfig       types.String `tfsdk:"config"`
+	K3sRegistry     types.String `tfsdk:"registry"`
+	Registries      types.Object `tfsdk:"registries"`
+	Env             types.Map    `tfsdk:"env"`
+	Server          types.String `tfsdk:"server"`
+	Token           types.String `tfsdk:"token"`
+	Orphan          types.Bool   `tfsdk:"orphan"`
+	ConfigFragments types.Map    `tfsdk:"config_fragments"`
+	ExtraFiles      types.Set    `tfsdk:"extra_files"`
+	StaticPods      types.Map    `tfsdk:"static_pods"`
+	// Containerd config template
+	ContainerdConfigTemplate types.String `tfsdk:"containerd_config_template"`
+	// Drain before uninstall
+	Drain types.Object `tfsdk:"drain"`
+	// How long to wait for the node to be Ready
+	ReadyTimeout types.String `tfsdk:"ready_timeout"`
+	// Labels and taints managed on the Node, and the API credentials to do so
+	Labels      types.Map    `tfsdk:"labels"`
+	Taints      types.Set    `tfsdk:"taints"`
+	ClusterAuth types.Object `tfsdk:"cluster_auth"`
 
 	// Outputs
-	Id     types.String `tfsdk:"id"`
-	Active types.Bool   `tfsdk:"active"`
+	Id           types.String `tfsdk:"id"`
+	Active       types.Bool   `tfsdk:"active"`
+	MergedConfig types.String `tfsdk:"merged_config"`
+	NodeIp       types.List   `tfsdk:"node_ip"`
+	schemas.NodeInfo
 }
 
 func NewK3sAgentResource() resource.Resource {
@@ -83,17 +101,30 @@ func (k *K3sAgentResource) ImportState(ctx context.Context, req resource.ImportS
 	}
 
 	data := AgentClientModel{
-		Version:     types.StringValue(agent.Version),
-		Auth:        sshConfig.ToObject(ctx),
-		BinDir:      types.StringValue(binDir),
-		K3sConfig:   types.StringNull(),
-		K3sRegistry: types.StringNull(),
-		Env:         types.MapNull(types.StringType),
-		Id:          types.StringValue(sshClient.Host()),
-		Server:      types.StringValue(agent.Server),
-		Token:       types.StringValue(agent.Token),
-		Active:      types.BoolValue(active),
-		Orphan:      types.BoolValue(false),
+		Version:                  types.StringValue(agent.Version),
+		Auth:                     sshConfig.ToObject(ctx),
+		BinDir:                   types.StringValue(binDir),
+		K3sConfig:                types.StringNull(),
+		K3sRegistry:              types.StringNull(),
+		Registries:               types.ObjectNull(schemas.Registries{}.AttributeTypes()),
+		Env:                      types.MapNull(types.StringType),
+		Id:                       types.StringValue(sshClient.Host()),
+		Server:                   types.StringValue(agent.Server),
+		Token:                    types.StringValue(agent.Token),
+		Active:                   types.BoolValue(active),
+		Orphan:                   types.BoolValue(false),
+		ConfigFragments:          types.MapNull(types.StringType),
+		ExtraFiles:               types.SetNull(extraFilesType()),
+		StaticPods:               types.MapNull(types.StringType),
+		ContainerdConfigTemplate: types.StringNull(),
+		Drain:                    types.ObjectNull(schemas.NodeDrain{}.AttributeTypes()),
+		ReadyTimeout:             types.StringValue(defaultReadyTimeout),
+		Labels:                   types.MapNull(types.StringType),
+		Taints:                   types.SetNull(taintsType()),
+		ClusterAuth:              types.ObjectNull(schemas.ClusterAuth{}.AttributeTypes()),
+		MergedConfig:             types.StringValue(agent.MergedConfig),
+		NodeIp:                   nodeIpToModel(agent.NodeInfo, &resp.Diagnostics),
+		NodeInfo:                 nodeInfoToModel(agent.NodeInfo, &resp.Diagnostics),
 	}
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -161,14 +192,29 @@ func (k *K3sAgentResource) Create(ctx context.Context, req resource.CreateReques
 		tflog.MaskMessageStrings(ctx, data.Token.ValueString())
 	}
 
+	configFragments := configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics)
+	extraFiles := extraFilesFromModel(ctx, data.ExtraFiles, &resp.Diagnostics)
+	staticPods := staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics)
+	registries := registriesFromModel(ctx, data.Registries, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	agent := k3s.Agent{
-		Config:   data.K3sConfig.ValueString(),
-		Registry: data.K3sRegistry.ValueString(),
-		Token:    data.Token.ValueString(),
-		Version:  data.Version.ValueString(),
-		BinDir:   data.BinDir.ValueString(),
-		Env:      env,
-		Server:   data.Server.ValueString(),
+		Config:                   data.K3sConfig.ValueString(),
+		Registry:                 data.K3sRegistry.ValueString(),
+		Registries:               registries,
+		Token:                    data.Token.ValueString(),
+		Version:                  data.Version.ValueString(),
+		BinDir:                   data.BinDir.ValueString(),
+		Env:                      env,
+		Server:                   data.Server.ValueString(),
+		ConfigFragments:          configFragments,
+		ExtraFiles:               extraFiles,
+		StaticPods:               staticPods,
+		ContainerdConfigTemplate: data.ContainerdConfigTemplate.ValueString(),
+		ReadyTimeout:             readyTimeoutFromModel(data.ReadyTimeout),
+		NodeMetadata:             nodeMetadataFromModel(ctx, data.Labels, data.Taints, types.MapNull(types.StringType), types.SetNull(taintsType()), clusterAuthFromModel(ctx, data.ClusterAuth, &resp.Diagnostics), &resp.Diagnostics),
 	}
 
 	if err := agent.Validate(ctx); err != nil {
@@ -184,6 +230,10 @@ func (k *K3sAgentResource) Create(ctx context.Context, req resource.CreateReques
 		resp.Diagnostics.AddError("running k3s agent install", err.Error())
 		return
 	}
+	if err := agent.ApplyNodeMetadata(ctx, sshClient); err != nil {
+		resp.Diagnostics.AddError("applying k3s agent labels and taints", err.Error())
+		return
+	}
 
 	exists, active, err := agent.Refresh(ctx, sshClient)
 	if err != nil {
@@ -195,7 +245,7 @@ func (k *K3sAgentResource) Create(ctx context.Context, req resource.CreateReques
 		return
 	}
 
-	populateAgentState(&data, agent, sshClient, active)
+	populateAgentState(ctx, &data, agent, sshClient, active, &resp.Diagnostics)
 
 	tflog.Info(ctx, "Created a k3s agent resource")
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -238,6 +288,10 @@ func (k *K3sAgentResource) Delete(ctx context.Context, req resource.DeleteReques
 
 	agent := k3s.Agent{
 		BinDir: data.BinDir.ValueString(),
+		Drain:  nodeDrainFromModel(ctx, data.Drain, &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
 	if err := agent.Uninstall(ctx, sshClient); err != nil {
@@ -283,7 +337,15 @@ func (k *K3sAgentResource) Read(ctx context.Context, req resource.ReadRequest, r
 	}
 
 	agent := k3s.Agent{
-		BinDir: data.BinDir.ValueString(),
+		BinDir:                   data.BinDir.ValueString(),
+		ConfigFragments:          configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics),
+		ExtraFiles:               extraFilePaths(ctx, data.ExtraFiles, &resp.Diagnostics),
+		StaticPods:               staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics),
+		ContainerdConfigTemplate: data.ContainerdConfigTemplate.ValueString(),
+		NodeMetadata:             nodeMetadataFromModel(ctx, data.Labels, data.Taints, types.MapNull(types.StringType), types.SetNull(taintsType()), clusterAuthFromModel(ctx, data.ClusterAuth, &resp.Diagnostics), &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 	exists, active, err := agent.Refresh(ctx, sshClient)
 	if err != nil {
@@ -295,7 +357,13 @@ func (k *K3sAgentResource) Read(ctx context.Context, req resource.ReadRequest, r
 		return
 	}
 
-	populateAgentState(&data, agent, sshClient, active)
+	populateAgentState(ctx, &data, agent, sshClient, active, &resp.Diagnostics)
+	data.ConfigFragments = configFragmentsToModel(ctx, data.ConfigFragments, agent.ConfigFragments, &resp.Diagnostics)
+	data.StaticPods = staticPodsToModel(ctx, data.StaticPods, agent.StaticPods, &resp.Diagnostics)
+	data.ContainerdConfigTemplate = observedString(data.ContainerdConfigTemplate, agent.ContainerdConfigTemplate)
+	data.ExtraFiles = extraFilesToModel(ctx, data.ExtraFiles, agent.ObservedExtraFiles, &resp.Diagnostics)
+	data.Labels = labelsToModel(ctx, data.Labels, agent.NodeMetadata, &resp.Diagnostics)
+	data.Taints = taintsToModel(ctx, data.Taints, agent.NodeMetadata, &resp.Diagnostics)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -322,18 +390,28 @@ func (k *K3sAgentResource) Schema(ctx context.Context, req resource.SchemaReques
 			// Config
 			"config": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "K3s agent config",
+				MarkdownDescription: "K3s agent config. Keys and value types are checked against the agent flags of the configured `version` during plan.",
 			},
 			"env": schema.MapAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "Extra environment variables to pass to the process",
+				MarkdownDescription: "Extra environment variables to pass to the process. Names must be valid shell variable names.",
 				ElementType:         types.StringType,
 			},
 			"registry": schema.StringAttribute{
 				Optional:            true,
 				MarkdownDescription: "K3s agent registry",
 			},
+			"registries":                 schemas.Registries{}.Schema(),
+			"config_fragments":           configFragmentsSchema(),
+			"extra_files":                schemas.ExtraFile{}.Schema(),
+			"static_pods":                staticPodsSchema(),
+			"containerd_config_template": containerdConfigTemplateSchema(),
+			"drain":                      schemas.NodeDrain{}.Schema(),
+			"ready_timeout":              readyTimeoutSchema("the node to report Ready"),
+			"labels":                     labelsSchema(),
+			"taints":                     schemas.NodeTaint{}.Schema(),
+			"cluster_auth":               agentClusterAuthSchema(),
 			"token": schema.StringAttribute{
 				Required:            true,
 				Sensitive:           true,
@@ -365,8 +443,13 @@ func (k *K3sAgentResource) Schema(ctx context.Context, req resource.SchemaReques
 					boolplanmodifier.UseStateForUnknown(),
 				},
 			},
+			"merged_config": mergedConfigSchema(),
+			"node_ip":       schemas.NodeIpSchema(),
 		},
 	}
+	for name, attribute := range (schemas.NodeInfo{}).Attributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
 }
 
 // Update implements [resource.ResourceWithConfigValidators].
@@ -403,14 +486,37 @@ func (k *K3sAgentResource) Update(ctx context.Context, req resource.UpdateReques
 		tflog.MaskMessageStrings(ctx, data.Token.ValueString())
 	}
 
+	var state AgentClientModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	configFragments := configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics)
+	priorConfigFragments := configFragmentsFromModel(ctx, state.ConfigFragments, &resp.Diagnostics)
+	extraFiles := extraFilesFromModel(ctx, data.ExtraFiles, &resp.Diagnostics)
+	staticPods := staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics)
+	registries := registriesFromModel(ctx, data.Registries, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	agent := k3s.Agent{
-		Config:   data.K3sConfig.ValueString(),
-		Registry: data.K3sRegistry.ValueString(),
-		Token:    data.Token.ValueString(),
-		Version:  data.Version.ValueString(),
-		BinDir:   data.BinDir.ValueString(),
-		Env:      env,
-		Server:   data.Server.ValueString(),
+		Config:                         data.K3sConfig.ValueString(),
+		Registry:                       data.K3sRegistry.ValueString(),
+		Registries:                     registries,
+		StaleRegistryHosts:             staleRegistryHosts(registriesFromModel(ctx, state.Registries, &resp.Diagnostics), registries),
+		Token:                          data.Token.ValueString(),
+		Version:                        data.Version.ValueString(),
+		BinDir:                         data.BinDir.ValueString(),
+		Env:                            env,
+		Server:                         data.Server.ValueString(),
+		ConfigFragments:                configFragments,
+		StaleConfigFragments:           staleConfigFragments(priorConfigFragments, configFragments),
+		ExtraFiles:                     extraFiles,
+		StaleExtraFiles:                staleExtraFiles(extraFilePaths(ctx, state.ExtraFiles, &resp.Diagnostics), extraFiles),
+		StaticPods:                     staticPods,
+		StaleStaticPods:                staleStaticPods(staticPodsFromModel(ctx, state.StaticPods, &resp.Diagnostics), staticPods),
+		ContainerdConfigTemplate:       data.ContainerdConfigTemplate.ValueString(),
+		RemoveContainerdConfigTemplate: removeContainerdConfigTemplate(state.ContainerdConfigTemplate, data.ContainerdConfigTemplate),
+		ReadyTimeout:                   readyTimeoutFromModel(data.ReadyTimeout),
+		NodeMetadata:                   nodeMetadataFromModel(ctx, data.Labels, data.Taints, state.Labels, state.Taints, clusterAuthFromModel(ctx, data.ClusterAuth, &resp.Diagnostics), &resp.Diagnostics),
 	}
 
 	if err := agent.Validate(ctx); err != nil {
@@ -418,12 +524,24 @@ func (k *K3sAgentResource) Update(ctx context.Context, req resource.UpdateReques
 		return
 	}
 
-	if err := agent.PreInstall(ctx, sshClient); err != nil {
-		resp.Diagnostics.AddError("running k3s agent preinstall", err.Error())
-		return
+	if nodeMetadataOnlyChange(req) {
+		tflog.Info(ctx, "Only labels or taints changed, leaving the k3s agent service running")
+	} else {
+		if err := agent.Backup(ctx, sshClient); err != nil {
+			resp.Diagnostics.AddError("backing up k3s agent", err.Error())
+			return
+		}
+		if err := agent.PreInstall(ctx, sshClient); err != nil {
+			resp.Diagnostics.AddError("running k3s agent preinstall", err.Error())
+			return
+		}
+		if err := agent.Update(ctx, sshClient); err != nil {
+			addUpdateDiagnostics(&resp.Diagnostics, "running k3s agent update", err)
+			return
+		}
 	}
-	if err := agent.Update(ctx, sshClient); err != nil {
-		resp.Diagnostics.AddError("running k3s agent update", err.Error())
+	if err := agent.ApplyNodeMetadata(ctx, sshClient); err != nil {
+		resp.Diagnostics.AddError("applying k3s agent labels and taints", err.Error())
 		return
 	}
 
@@ -437,7 +555,7 @@ func (k *K3sAgentResource) Update(ctx context.Context, req resource.UpdateReques
 		return
 	}
 
-	populateAgentState(&data, agent, sshClient, active)
+	populateAgentState(ctx, &data, agent, sshClient, active, &resp.Diagnostics)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -455,6 +573,31 @@ func validateAgentAndReturn(ctx context.Context, data AgentClientModel, d *diag.
 		return
 	}
 
+	validateConfigFragments(ctx, data.ConfigFragments, d)
+	validateRegistries(ctx, data.Registries, data.K3sRegistry, d)
+	validateExtraFiles(ctx, data.ExtraFiles, d)
+	validateStaticPods(ctx, data.StaticPods, d)
+	validateContainerdConfigTemplate(data.ContainerdConfigTemplate, d)
+	validateEnvNames(data.Env, d)
+	validateNodeDrain(ctx, "drain", data.Drain, d)
+	validateReadyTimeout(data.ReadyTimeout, d)
+	validateNodeMetadata(ctx, data.Labels, data.Taints, d)
+	validateAgentClusterAuth(data.Labels, data.Taints, data.ClusterAuth, d)
+	if d.HasError() {
+		return
+	}
+	if !data.K3sConfig.IsUnknown() {
+		agent := k3s.Agent{
+			Config:          data.K3sConfig.ValueString(),
+			Version:         data.Version.ValueString(),
+			ConfigFragments: plannedConfigFragments(ctx, data.ConfigFragments, d),
+		}
+		if err := agent.Validate(ctx); err != nil {
+			d.AddError("validating config", err.Error())
+			return
+		}
+	}
+
 	if data.Token.IsNull() {
 		d.AddError("validating token", "token cannot be null")
 		return
@@ -476,10 +619,13 @@ func validateAgentAndReturn(ctx context.Context, data AgentClientModel, d *diag.
 	return
 }
 
-func populateAgentState(data *AgentClientModel, agent k3s.Agent, sshClient ssh_client.SSHClient, active bool) {
+func populateAgentState(ctx context.Context, data *AgentClientModel, agent k3s.Agent, sshClient ssh_client.SSHClient, active bool, d *diag.Diagnostics) {
 	data.Version = types.StringValue(agent.Version)
 	data.Id = types.StringValue(sshClient.Host())
 	data.Server = types.StringValue(agent.Server)
 	data.Token = types.StringValue(agent.Token)
 	data.Active = types.BoolValue(active)
+	data.MergedConfig = types.StringValue(agent.MergedConfig)
+	data.NodeIp = nodeIpToModel(agent.NodeInfo, d)
+	data.NodeInfo = nodeInfoToModel(agent.NodeInfo, d)
 }
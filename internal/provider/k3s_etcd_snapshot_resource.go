@@ -0,0 +1,295 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+var (
+	_ resource.ResourceWithConfigValidators = &K3sEtcdSnapshotResource{}
+	_ resource.ResourceWithImportState      = &K3sEtcdSnapshotResource{}
+	_ resource.Resource                     = &K3sEtcdSnapshotResource{}
+	_ resource.ConfigValidator              = &K3sEtcdSnapshotResource{}
+)
+
+type K3sEtcdSnapshotResource struct{}
+
+type K3sEtcdSnapshotModel struct {
+	Id           types.String `tfsdk:"id"`
+	Name         types.String `tfsdk:"name"`
+	Triggers     types.Map    `tfsdk:"triggers"`
+	SnapshotName types.String `tfsdk:"snapshot_name"`
+	Path         types.String `tfsdk:"path"`
+	Size         types.Int64  `tfsdk:"size"`
+	CreatedAt    types.String `tfsdk:"created_at"`
+	etcdSnapshotTargetModel
+}
+
+func NewK3sEtcdSnapshotResource() resource.Resource {
+	return &K3sEtcdSnapshotResource{}
+}
+
+func (r *K3sEtcdSnapshotResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_snapshot"
+}
+
+func (r *K3sEtcdSnapshotResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: ("Takes an on-demand snapshot of the embedded etcd datastore with `k3s etcd-snapshot save` on a server " +
+			"started with `highly_available.cluster_init`. The snapshot is deleted when the resource is destroyed. " +
+			"Change `triggers` to take a new snapshot, for example before a risky change."),
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Full name of the snapshot.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name passed to `--name`. k3s appends the node name and a timestamp to it.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values that take a new snapshot when changed.",
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"snapshot_name": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Full name of the snapshot as listed by `k3s etcd-snapshot ls`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"path": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path of the snapshot on the server, or its `s3://` URL when it is only stored on S3.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"size": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "Size of the snapshot in bytes.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"created_at": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Time the snapshot was taken, in RFC 3339 format.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+
+	for name, attribute := range etcdSnapshotTargetAttributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
+}
+
+func (r *K3sEtcdSnapshotResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data K3sEtcdSnapshotModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	sshClient, target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := sshClient.WaitForReady(); err != nil {
+		resp.Diagnostics.AddError("connecting to k3s server", err.Error())
+		return
+	}
+
+	snapshot, err := target.Save(ctx, sshClient, data.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("saving etcd snapshot", err.Error())
+		return
+	}
+	data.setSnapshot(snapshot)
+
+	tflog.Info(ctx, "Created a k3s etcd snapshot resource")
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sEtcdSnapshotResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data K3sEtcdSnapshotModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	sshClient, target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	snapshot, exists, err := target.Find(ctx, sshClient, data.SnapshotName.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("reading etcd snapshot", err.Error())
+		return
+	}
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	data.setSnapshot(snapshot)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Update only changes how the server is reached, every other input replaces
+// the snapshot.
+func (r *K3sEtcdSnapshotResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data K3sEtcdSnapshotModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sEtcdSnapshotResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data K3sEtcdSnapshotModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	sshClient, target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	_, exists, err := target.Find(ctx, sshClient, data.SnapshotName.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError("reading etcd snapshot", err.Error())
+		return
+	}
+	if exists {
+		if err := target.Delete(ctx, sshClient, data.SnapshotName.ValueString()); err != nil {
+			resp.Diagnostics.AddError("deleting etcd snapshot", err.Error())
+			return
+		}
+	}
+
+	tflog.Info(ctx, "Deleted a k3s etcd snapshot resource")
+	resp.State.RemoveResource(ctx)
+}
+
+// ImportState implements [resource.ResourceWithImportState].
+func (r *K3sEtcdSnapshotResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	sshConfig, binDir, dataDir, name, err := parseEtcdSnapshotImportID(req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("parsing import id", err.Error())
+		return
+	}
+	if err := sshConfig.Validate(); err != nil {
+		resp.Diagnostics.AddError("validating auth", err.Error())
+		return
+	}
+
+	sshClient, err := ssh_client.NewSSHClient(ctx, sshConfig)
+	if err != nil {
+		resp.Diagnostics.AddError("creating ssh client", err.Error())
+		return
+	}
+
+	target := k3s.EtcdSnapshotTarget{BinDir: binDir, DataDir: dataDir}
+	snapshot, exists, err := target.Find(ctx, sshClient, name)
+	if err != nil {
+		resp.Diagnostics.AddError("importing etcd snapshot", err.Error())
+		return
+	}
+	if !exists {
+		resp.Diagnostics.AddError("importing etcd snapshot", fmt.Sprintf("no etcd snapshot named %s found on %s", name, sshClient.Host()))
+		return
+	}
+	requested, err := target.RequestedName(sshClient, name)
+	if err != nil {
+		resp.Diagnostics.AddError("importing etcd snapshot", err.Error())
+		return
+	}
+
+	data := K3sEtcdSnapshotModel{
+		Name:     types.StringValue(requested),
+		Triggers: types.MapNull(types.StringType),
+		etcdSnapshotTargetModel: etcdSnapshotTargetModel{
+			Auth:    sshConfig.ToObject(ctx),
+			BinDir:  types.StringValue(binDir),
+			DataDir: optionalImportString(dataDir),
+		},
+	}
+	data.setSnapshot(snapshot)
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (data *K3sEtcdSnapshotModel) setSnapshot(snapshot k3s.EtcdSnapshot) {
+	data.Id = types.StringValue(snapshot.Name)
+	data.SnapshotName = types.StringValue(snapshot.Name)
+	data.Path = types.StringValue(etcdSnapshotPath(snapshot))
+	data.Size = types.Int64Value(snapshot.Size)
+	data.CreatedAt = types.StringValue(snapshot.CreatedAt.UTC().Format(time.RFC3339))
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *K3sEtcdSnapshotResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{&K3sEtcdSnapshotResource{}}
+}
+
+// Description implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotResource) Description(context.Context) string {
+	return "Validates the snapshot name and server auth"
+}
+
+// MarkdownDescription implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotResource) MarkdownDescription(context.Context) string {
+	return "Validates the snapshot name and server auth"
+}
+
+// ValidateResource implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotResource) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data K3sEtcdSnapshotModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.validate(ctx, &resp.Diagnostics)
+	if !data.Name.IsNull() && !data.Name.IsUnknown() {
+		if err := k3s.ValidateEtcdSnapshotName(data.Name.ValueString()); err != nil {
+			resp.Diagnostics.AddError("validating name", err.Error())
+		}
+	}
+}
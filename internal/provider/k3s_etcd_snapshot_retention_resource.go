@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+var (
+	_ resource.ResourceWithConfigValidators = &K3sEtcdSnapshotRetentionResource{}
+	_ resource.Resource                     = &K3sEtcdSnapshotRetentionResource{}
+	_ resource.ConfigValidator              = &K3sEtcdSnapshotRetentionResource{}
+)
+
+// Name k3s gives on-demand snapshots when --name is not passed.
+const defaultEtcdSnapshotName = "on-demand"
+
+type K3sEtcdSnapshotRetentionResource struct{}
+
+type K3sEtcdSnapshotRetentionModel struct {
+	Id        types.String `tfsdk:"id"`
+	Name      types.String `tfsdk:"name"`
+	Retention types.Int64  `tfsdk:"retention"`
+	Triggers  types.Map    `tfsdk:"triggers"`
+	Removed   types.List   `tfsdk:"removed"`
+	etcdSnapshotTargetModel
+}
+
+func NewK3sEtcdSnapshotRetentionResource() resource.Resource {
+	return &K3sEtcdSnapshotRetentionResource{}
+}
+
+func (r *K3sEtcdSnapshotRetentionResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_snapshot_retention"
+}
+
+func (r *K3sEtcdSnapshotRetentionResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: ("Prunes etcd snapshots with `k3s etcd-snapshot prune`, keeping only the newest `retention` snapshots " +
+			"taken with `name`, locally and on S3 when the server is configured for it. Pruning runs when the resource is created " +
+			"and whenever an input changes. Change `triggers` to prune again, for example after taking a new snapshot. " +
+			"Destroying the resource leaves the snapshots in place."),
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Host of the server.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(defaultEtcdSnapshotName),
+				MarkdownDescription: "Name the snapshots were taken with. Use `etcd-snapshot` for scheduled snapshots.",
+			},
+			"retention": schema.Int64Attribute{
+				Required:            true,
+				MarkdownDescription: "Number of snapshots to keep.",
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Arbitrary values that prune again when changed.",
+			},
+			"removed": schema.ListAttribute{
+				Computed:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Names of the snapshots removed by the last prune.",
+			},
+		},
+	}
+
+	for name, attribute := range etcdSnapshotTargetAttributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
+}
+
+func (r *K3sEtcdSnapshotRetentionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data K3sEtcdSnapshotRetentionModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.prune(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "Created a k3s etcd snapshot retention resource")
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Read keeps the state as is, pruning only happens on apply.
+func (r *K3sEtcdSnapshotRetentionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data K3sEtcdSnapshotRetentionModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sEtcdSnapshotRetentionResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data K3sEtcdSnapshotRetentionModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.prune(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Delete only forgets the resource, pruned snapshots cannot be brought back
+// and the remaining ones are kept.
+func (r *K3sEtcdSnapshotRetentionResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	tflog.Info(ctx, "Deleted a k3s etcd snapshot retention resource")
+	resp.State.RemoveResource(ctx)
+}
+
+func (data *K3sEtcdSnapshotRetentionModel) prune(ctx context.Context, d *diag.Diagnostics) {
+	sshClient, target := data.target(ctx, d)
+	if d.HasError() {
+		return
+	}
+	if err := sshClient.WaitForReady(); err != nil {
+		d.AddError("connecting to k3s server", err.Error())
+		return
+	}
+
+	removed, err := target.Prune(ctx, sshClient, data.Name.ValueString(), int(data.Retention.ValueInt64()))
+	if err != nil {
+		d.AddError("pruning etcd snapshots", err.Error())
+		return
+	}
+	tflog.Info(ctx, fmt.Sprintf("Pruned %d etcd snapshots", len(removed)))
+
+	list, diags := types.ListValueFrom(ctx, types.StringType, removed)
+	d.Append(diags...)
+	data.Id = types.StringValue(sshClient.Host())
+	data.Removed = list
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *K3sEtcdSnapshotRetentionResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{&K3sEtcdSnapshotRetentionResource{}}
+}
+
+// Description implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotRetentionResource) Description(context.Context) string {
+	return "Validates the snapshot name, retention and server auth"
+}
+
+// MarkdownDescription implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotRetentionResource) MarkdownDescription(context.Context) string {
+	return "Validates the snapshot name, retention and server auth"
+}
+
+// ValidateResource implements resource.ConfigValidator.
+func (r *K3sEtcdSnapshotRetentionResource) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data K3sEtcdSnapshotRetentionModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.validate(ctx, &resp.Diagnostics)
+	if !data.Name.IsNull() && !data.Name.IsUnknown() {
+		if err := k3s.ValidateEtcdSnapshotName(data.Name.ValueString()); err != nil {
+			resp.Diagnostics.AddError("validating name", err.Error())
+		}
+	}
+	if !data.Retention.IsNull() && !data.Retention.IsUnknown() && data.Retention.ValueInt64() < 1 {
+		resp.Diagnostics.AddError("validating retention", "retention must be at least 1")
+	}
+}
@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/ssh_client"
+)
+
+var _ datasource.DataSource = &K3sEtcdSnapshotsData{}
+
+type K3sEtcdSnapshotsData struct{}
+
+type K3sEtcdSnapshotsDataModel struct {
+	Id         types.String `tfsdk:"id"`
+	Auth       types.Object `tfsdk:"auth"`
+	BinDir     types.String `tfsdk:"bin_dir"`
+	DataDir    types.String `tfsdk:"data_dir"`
+	NamePrefix types.String `tfsdk:"name_prefix"`
+	Snapshots  types.List   `tfsdk:"snapshots"`
+}
+
+type etcdSnapshotsDataSnapshot struct {
+	Name      types.String `tfsdk:"name"`
+	Location  types.String `tfsdk:"location"`
+	Path      types.String `tfsdk:"path"`
+	Storage   types.String `tfsdk:"storage"`
+	Size      types.Int64  `tfsdk:"size"`
+	CreatedAt types.String `tfsdk:"created_at"`
+}
+
+func (s etcdSnapshotsDataSnapshot) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"name":       types.StringType,
+		"location":   types.StringType,
+		"path":       types.StringType,
+		"storage":    types.StringType,
+		"size":       types.Int64Type,
+		"created_at": types.StringType,
+	}
+}
+
+func NewK3sEtcdSnapshotsData() datasource.DataSource {
+	return &K3sEtcdSnapshotsData{}
+}
+
+// Metadata implements datasource.DataSource.
+func (k *K3sEtcdSnapshotsData) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_etcd_snapshots"
+}
+
+// Schema implements datasource.DataSource.
+func (k *K3sEtcdSnapshotsData) Schema(_ context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: ("Lists the etcd snapshots of a server started with `highly_available.cluster_init`, as reported by " +
+			"`k3s etcd-snapshot ls`. Snapshots uploaded to S3 are listed alongside local ones when the server is configured for S3."),
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Host of the server.",
+			},
+			"auth": ssh_client.SSHConfig{}.DataSourceSchema(),
+			"bin_dir": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Directory containing the k3s binary on the server. Defaults to `/usr/local/bin`.",
+			},
+			"data_dir": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Data dir of the server. k3s reads it from the server config when unset.",
+			},
+			"name_prefix": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Only list snapshots whose name starts with this prefix, for example `etcd-snapshot` for scheduled ones.",
+			},
+			"snapshots": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "Snapshots ordered from newest to oldest. A snapshot stored locally and on S3 is listed once per location.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Full name of the snapshot.",
+						},
+						"location": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "`file://` or `s3://` URL of the snapshot.",
+						},
+						"path": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Path of a local snapshot on the server, or the `s3://` URL of an uploaded one.",
+						},
+						"storage": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Either `local` or `s3`.",
+						},
+						"size": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "Size of the snapshot in bytes.",
+						},
+						"created_at": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "Time the snapshot was taken, in RFC 3339 format.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Read implements datasource.DataSource.
+func (k *K3sEtcdSnapshotsData) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data K3sEtcdSnapshotsDataModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var sshConfig ssh_client.SSHConfig
+	resp.Diagnostics.Append(data.Auth.As(ctx, &sshConfig, basetypes.ObjectAsOptions{})...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	normalizeKubeConfigDataSSHConfig(&sshConfig)
+	if err := sshConfig.Validate(); err != nil {
+		resp.Diagnostics.AddError("validating auth", err.Error())
+		return
+	}
+
+	sshClient, err := ssh_client.NewSSHClient(ctx, sshConfig)
+	if err != nil {
+		resp.Diagnostics.AddError("creating ssh client", err.Error())
+		return
+	}
+
+	target := k3s.EtcdSnapshotTarget{
+		BinDir:  data.BinDir.ValueString(),
+		DataDir: data.DataDir.ValueString(),
+	}
+	snapshots, err := target.List(ctx, sshClient)
+	if err != nil {
+		resp.Diagnostics.AddError("listing etcd snapshots", err.Error())
+		return
+	}
+	tflog.Debug(ctx, "Listed etcd snapshots", map[string]any{"count": len(snapshots)})
+
+	list, diags := types.ListValueFrom(ctx, types.ObjectType{AttrTypes: etcdSnapshotsDataSnapshot{}.AttributeTypes()},
+		etcdSnapshotsToData(snapshots, data.NamePrefix.ValueString()))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Id = types.StringValue(sshClient.Host())
+	data.Auth = sshConfig.ToObject(ctx)
+	data.Snapshots = list
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// Snapshots matching the prefix, newest first.
+func etcdSnapshotsToData(snapshots []k3s.EtcdSnapshot, prefix string) []etcdSnapshotsDataSnapshot {
+	matching := []k3s.EtcdSnapshot{}
+	for _, snapshot := range snapshots {
+		if strings.HasPrefix(snapshot.Name, prefix) {
+			matching = append(matching, snapshot)
+		}
+	}
+	sort.SliceStable(matching, func(i, j int) bool {
+		if !matching[i].CreatedAt.Equal(matching[j].CreatedAt) {
+			return matching[i].CreatedAt.After(matching[j].CreatedAt)
+		}
+		return matching[i].Name < matching[j].Name
+	})
+
+	data := make([]etcdSnapshotsDataSnapshot, 0, len(matching))
+	for _, snapshot := range matching {
+		storage := "s3"
+		if snapshot.Local() {
+			storage = "local"
+		}
+		data = append(data, etcdSnapshotsDataSnapshot{
+			Name:      types.StringValue(snapshot.Name),
+			Location:  types.StringValue(snapshot.Location),
+			Path:      types.StringValue(etcdSnapshotPath(snapshot)),
+			Storage:   types.StringValue(storage),
+			Size:      types.Int64Value(snapshot.Size),
+			CreatedAt: types.StringValue(snapshot.CreatedAt.UTC().Format(time.RFC3339)),
+		})
+	}
+	return data
+}
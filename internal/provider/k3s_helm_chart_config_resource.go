@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+var (
+	_ resource.ResourceWithConfigValidators = &K3sHelmChartConfigResource{}
+	_ resource.Resource                     = &K3sHelmChartConfigResource{}
+	_ resource.ConfigValidator              = &K3sHelmChartConfigResource{}
+)
+
+type K3sHelmChartConfigResource struct{}
+
+type K3sHelmChartConfigModel struct {
+	Id            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	Namespace     types.String `tfsdk:"namespace"`
+	ValuesContent types.String `tfsdk:"values_content"`
+	helmTargetModel
+}
+
+func NewK3sHelmChartConfigResource() resource.Resource {
+	return &K3sHelmChartConfigResource{}
+}
+
+func (r *K3sHelmChartConfigResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_helm_chart_config"
+}
+
+func (r *K3sHelmChartConfigResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: ("Overrides the values of a chart installed by the k3s helm controller, such as the packaged traefik chart, " +
+			"by managing a `helm.cattle.io/v1` HelmChartConfig. The HelmChartConfig is written to the manifests directory of a server " +
+			"over SSH with `auth`, or applied through the API with `cluster_auth`."),
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Namespace and name of the HelmChartConfig, in `namespace/name` format.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the HelmChart to override.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(k3s.HELM_CHART_NAMESPACE),
+				MarkdownDescription: "Namespace of the HelmChart to override. Defaults to `" + k3s.HELM_CHART_NAMESPACE + "`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"values_content": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Values file content merged over the values of the chart, as YAML.",
+			},
+		},
+	}
+
+	for name, attribute := range helmTargetAttributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
+}
+
+func (r *K3sHelmChartConfigResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data K3sHelmChartConfigModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.apply(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "Created a k3s helm chart config resource")
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartConfigResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data K3sHelmChartConfigModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := helmChartConfigFromModel(data)
+	exists, err := config.Refresh(ctx, target)
+	if err != nil {
+		resp.Diagnostics.AddError("reading helm chart config", err.Error())
+		return
+	}
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.ValuesContent = types.StringValue(config.ValuesContent)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartConfigResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data K3sHelmChartConfigModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.apply(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartConfigResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data K3sHelmChartConfigModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	config := helmChartConfigFromModel(data)
+	if err := config.Delete(ctx, target); err != nil {
+		resp.Diagnostics.AddError("deleting helm chart config", err.Error())
+		return
+	}
+
+	tflog.Info(ctx, "Deleted a k3s helm chart config resource")
+	resp.State.RemoveResource(ctx)
+}
+
+func (r *K3sHelmChartConfigResource) apply(ctx context.Context, data *K3sHelmChartConfigModel, d *diag.Diagnostics) {
+	config := helmChartConfigFromModel(*data)
+	if err := config.Validate(); err != nil {
+		d.AddError("validating helm chart config", err.Error())
+		return
+	}
+
+	target := data.target(ctx, d)
+	if d.HasError() {
+		return
+	}
+	if err := config.Apply(ctx, target); err != nil {
+		d.AddError("applying helm chart config", err.Error())
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s/%s", config.Namespace, config.Name))
+}
+
+func helmChartConfigFromModel(data K3sHelmChartConfigModel) k3s.HelmChartConfig {
+	return k3s.HelmChartConfig{
+		Name:          data.Name.ValueString(),
+		Namespace:     data.Namespace.ValueString(),
+		ValuesContent: data.ValuesContent.ValueString(),
+		Timeout:       data.timeout(),
+	}
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *K3sHelmChartConfigResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{&K3sHelmChartConfigResource{}}
+}
+
+// Description implements resource.ConfigValidator.
+func (r *K3sHelmChartConfigResource) Description(context.Context) string {
+	return "Validates the helm chart config and how it is installed"
+}
+
+// MarkdownDescription implements resource.ConfigValidator.
+func (r *K3sHelmChartConfigResource) MarkdownDescription(context.Context) string {
+	return "Requires exactly one of `auth` or `cluster_auth` and valid values"
+}
+
+// ValidateResource implements resource.ConfigValidator.
+func (r *K3sHelmChartConfigResource) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data K3sHelmChartConfigModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.validate(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Name.IsUnknown() || data.Namespace.IsUnknown() || data.ValuesContent.IsUnknown() {
+		return
+	}
+	config := helmChartConfigFromModel(data)
+	if config.Namespace == "" {
+		config.Namespace = k3s.HELM_CHART_NAMESPACE
+	}
+	if err := config.Validate(); err != nil {
+		resp.Diagnostics.AddError("validating helm chart config", err.Error())
+	}
+}
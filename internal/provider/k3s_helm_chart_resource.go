@@ -0,0 +1,291 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+var (
+	_ resource.ResourceWithConfigValidators = &K3sHelmChartResource{}
+	_ resource.Resource                     = &K3sHelmChartResource{}
+	_ resource.ConfigValidator              = &K3sHelmChartResource{}
+)
+
+type K3sHelmChartResource struct{}
+
+type K3sHelmChartModel struct {
+	Id              types.String `tfsdk:"id"`
+	Name            types.String `tfsdk:"name"`
+	Namespace       types.String `tfsdk:"namespace"`
+	Chart           types.String `tfsdk:"chart"`
+	Repo            types.String `tfsdk:"repo"`
+	Version         types.String `tfsdk:"version"`
+	TargetNamespace types.String `tfsdk:"target_namespace"`
+	Values          types.Map    `tfsdk:"values"`
+	ValuesContent   types.String `tfsdk:"values_content"`
+	JobName         types.String `tfsdk:"job_name"`
+	helmTargetModel
+}
+
+func NewK3sHelmChartResource() resource.Resource {
+	return &K3sHelmChartResource{}
+}
+
+func (r *K3sHelmChartResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_helm_chart"
+}
+
+func (r *K3sHelmChartResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: ("Installs a chart with the k3s helm controller by managing a `helm.cattle.io/v1` HelmChart. " +
+			"The HelmChart is written to the manifests directory of a server over SSH with `auth`, or applied through the API with `cluster_auth`."),
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Namespace and name of the HelmChart, in `namespace/name` format.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name of the HelmChart, also used as the release name.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				Default:             stringdefault.StaticString(k3s.HELM_CHART_NAMESPACE),
+				MarkdownDescription: "Namespace of the HelmChart and its install job. Defaults to `" + k3s.HELM_CHART_NAMESPACE + "`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"chart": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Chart name in `repo`, an OCI reference, or a URL of a chart archive.",
+			},
+			"repo": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "URL of the chart repository.",
+			},
+			"version": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Chart version. The latest version is installed when unset.",
+			},
+			"target_namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Namespace the release is installed into. Defaults to `namespace`.",
+			},
+			"values": schema.MapAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Values passed to helm with `--set`. Use `values_content` for values that are not strings.",
+			},
+			"values_content": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Values file content, as YAML.",
+			},
+			"job_name": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Name of the helm install job run by the helm controller.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+
+	for name, attribute := range helmTargetAttributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
+}
+
+func (r *K3sHelmChartResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data K3sHelmChartModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.apply(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Info(ctx, "Created a k3s helm chart resource")
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data K3sHelmChartModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	chart := k3s.HelmChart{Name: data.Name.ValueString(), Namespace: data.Namespace.ValueString()}
+	exists, err := chart.Refresh(ctx, target)
+	if err != nil {
+		resp.Diagnostics.AddError("reading helm chart", err.Error())
+		return
+	}
+	if !exists {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	data.Chart = types.StringValue(chart.Chart)
+	data.Repo = observedString(data.Repo, chart.Repo)
+	data.Version = observedString(data.Version, chart.Version)
+	data.TargetNamespace = observedString(data.TargetNamespace, chart.TargetNamespace)
+	data.ValuesContent = observedString(data.ValuesContent, chart.ValuesContent)
+	if len(chart.Set) > 0 || !data.Values.IsNull() {
+		values, diags := types.MapValueFrom(ctx, types.StringType, chart.Set)
+		resp.Diagnostics.Append(diags...)
+		data.Values = values
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data K3sHelmChartModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	r.apply(ctx, &data, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *K3sHelmChartResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data K3sHelmChartModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	target := data.target(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	chart := helmChartFromModel(ctx, data, &resp.Diagnostics)
+	if err := chart.Delete(ctx, target); err != nil {
+		resp.Diagnostics.AddError("deleting helm chart", err.Error())
+		return
+	}
+
+	tflog.Info(ctx, "Deleted a k3s helm chart resource")
+	resp.State.RemoveResource(ctx)
+}
+
+func (r *K3sHelmChartResource) apply(ctx context.Context, data *K3sHelmChartModel, d *diag.Diagnostics) {
+	chart := helmChartFromModel(ctx, *data, d)
+	if d.HasError() {
+		return
+	}
+	if err := chart.Validate(); err != nil {
+		d.AddError("validating helm chart", err.Error())
+		return
+	}
+
+	target := data.target(ctx, d)
+	if d.HasError() {
+		return
+	}
+	if err := chart.Apply(ctx, target); err != nil {
+		d.AddError("applying helm chart", err.Error())
+		return
+	}
+
+	data.Id = types.StringValue(fmt.Sprintf("%s/%s", chart.Namespace, chart.Name))
+	data.JobName = types.StringValue(chart.JobName())
+}
+
+func helmChartFromModel(ctx context.Context, data K3sHelmChartModel, d *diag.Diagnostics) k3s.HelmChart {
+	set := make(map[string]string)
+	if !data.Values.IsNull() && !data.Values.IsUnknown() {
+		d.Append(data.Values.ElementsAs(ctx, &set, false)...)
+	}
+
+	return k3s.HelmChart{
+		Name:            data.Name.ValueString(),
+		Namespace:       data.Namespace.ValueString(),
+		Chart:           data.Chart.ValueString(),
+		Repo:            data.Repo.ValueString(),
+		Version:         data.Version.ValueString(),
+		TargetNamespace: data.TargetNamespace.ValueString(),
+		Set:             set,
+		ValuesContent:   data.ValuesContent.ValueString(),
+		Timeout:         data.timeout(),
+	}
+}
+
+// ConfigValidators implements resource.ResourceWithConfigValidators.
+func (r *K3sHelmChartResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
+	return []resource.ConfigValidator{&K3sHelmChartResource{}}
+}
+
+// Description implements resource.ConfigValidator.
+func (r *K3sHelmChartResource) Description(context.Context) string {
+	return "Validates the helm chart and how it is installed"
+}
+
+// MarkdownDescription implements resource.ConfigValidator.
+func (r *K3sHelmChartResource) MarkdownDescription(context.Context) string {
+	return "Requires exactly one of `auth` or `cluster_auth` and a valid chart name and values"
+}
+
+// ValidateResource implements resource.ConfigValidator.
+func (r *K3sHelmChartResource) ValidateResource(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data K3sHelmChartModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.validate(ctx, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Name.IsUnknown() || data.Namespace.IsUnknown() || data.Chart.IsUnknown() || data.ValuesContent.IsUnknown() {
+		return
+	}
+	chart := helmChartFromModel(ctx, data, &resp.Diagnostics)
+	if chart.Namespace == "" {
+		chart.Namespace = k3s.HELM_CHART_NAMESPACE
+	}
+	if err := chart.Validate(); err != nil {
+		resp.Diagnostics.AddError("validating helm chart", err.Error())
+	}
+}
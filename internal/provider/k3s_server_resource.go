@@ -2,8 +2,10 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -30,29 +32,69 @@ var (
 	_ resource.Resource                     = &K3sServerResource{}
 	_ resource.ConfigValidator              = &K3sServerResource{}
 	_ resource.ResourceWithImportState      = &K3sServerResource{}
+	_ resource.ResourceWithModifyPlan       = &K3sServerResource{}
 )
 
 type K3sServerResource struct{}
 
 type ServerClientModel struct {
 	// Inputs
-	Version        types.String `tfsdk:"version"`
-	Auth           types.Object `tfsdk:"auth"`
-	BinDir         types.String `tfsdk:"bin_dir"`
-	K3sConfig      types.String `tfsdk:"config"`
-	K3sRegistry    types.String `tfsdk:"registry"`
-	Env            types.Map    `tfsdk:"env"`
-	HaConfig       types.Object `tfsdk:"highly_available"`
-	OidcConfig     types.Object `tfsdk:"oidc"`
-	BootstrapToken types.String `tfsdk:"bootstrap_token"`
-	Orphan         types.Bool   `tfsdk:"orphan"`
+	Version         types.String `tfsdk:"version"`
+	Auth            types.Object `tfsdk:"auth"`
+	BinDir          types.String `tfsdk:"bin_dir"`
+	K3sConfig       types.String `tfsdk:"config"`
+	K3sRegistry     types.String `tfsdk:"registry"`
+	Registries      types.Object `tfsdk:"registries"`
+	Env             types.Map    `tfsdk:"env"`
+	HaConfig        types.Object `tfsdk:"highly_available"`
+	OidcConfig      types.Object `tfsdk:"oidc"`
+	BootstrapToken  types.String `tfsdk:"bootstrap_token"`
+	RotateToken     types.String `tfsdk:"rotate_token"`
+	Orphan          types.Bool   `tfsdk:"orphan"`
+	ConfigFragments types.Map    `tfsdk:"config_fragments"`
+	ExtraFiles      types.Set    `tfsdk:"extra_files"`
+	Manifests       types.Map    `tfsdk:"manifests"`
+	StaticPods      types.Map    `tfsdk:"static_pods"`
+	// Snapshot restored on create
+	RestoreFromSnapshot types.Object `tfsdk:"restore_from_snapshot"`
+	// Scheduled etcd snapshots
+	EtcdSnapshots types.Object `tfsdk:"etcd_snapshots"`
+	// Organisation CA the cluster CAs are issued from
+	CustomCA types.Object `tfsdk:"custom_ca"`
+	// Certificate rotation trigger
+	RotateCertificates types.Object `tfsdk:"rotate_certificates"`
+	// Secrets encryption at rest and key rotation trigger
+	SecretsEncryption types.Object `tfsdk:"secrets_encryption"`
+	// Drain and removal from the cluster before uninstall
+	GracefulRemoval types.Object `tfsdk:"graceful_removal"`
+	// How long to wait for /readyz and the node to be Ready
+	ReadyTimeout types.String `tfsdk:"ready_timeout"`
+	// Wait for the packaged add-ons and helm install jobs
+	WaitForAddons types.Bool   `tfsdk:"wait_for_addons"`
+	AddonsTimeout types.String `tfsdk:"addons_timeout"`
+	// Labels and taints managed on the Node through the API
+	Labels types.Map `tfsdk:"labels"`
+	Taints types.Set `tfsdk:"taints"`
+	// Containerd config template
+	ContainerdConfigTemplate types.String `tfsdk:"containerd_config_template"`
+	// Typed config flags
+	schemas.ServerFlags
 	// Outputs
-	Id          types.String `tfsdk:"id"`
-	Server      types.String `tfsdk:"server"`
-	KubeConfig  types.String `tfsdk:"kubeconfig"`
-	Token       types.String `tfsdk:"token"`
-	Active      types.Bool   `tfsdk:"active"`
-	ClusterAuth types.Object `tfsdk:"cluster_auth"`
+	Id             types.String `tfsdk:"id"`
+	Server         types.String `tfsdk:"server"`
+	KubeConfig     types.String `tfsdk:"kubeconfig"`
+	Token          types.String `tfsdk:"token"`
+	Active         types.Bool   `tfsdk:"active"`
+	ClusterAuth    types.Object `tfsdk:"cluster_auth"`
+	MergedConfig   types.String `tfsdk:"merged_config"`
+	ManifestStatus types.Map    `tfsdk:"manifest_status"`
+	RestoredFrom   types.String `tfsdk:"restored_from"`
+	// Certificate expiry, keyed by path under <data-dir>/server/tls
+	CertificateExpiry types.Map `tfsdk:"certificate_expiry"`
+	// Output of k3s secrets-encrypt status
+	SecretsEncryptionStatus types.Object `tfsdk:"secrets_encryption_status"`
+	// Observed node name, addresses and system info
+	schemas.NodeInfo
 }
 
 func NewK3sServerResource() resource.Resource {
@@ -98,23 +140,50 @@ func (s *K3sServerResource) ImportState(ctx context.Context, req resource.Import
 	}
 
 	data := ServerClientModel{
-		Version:        types.StringValue(server.Version),
-		Auth:           sshConfig.ToObject(ctx),
-		BinDir:         types.StringValue(binDir),
-		K3sConfig:      types.StringNull(),
-		K3sRegistry:    types.StringNull(),
-		Env:            types.MapNull(types.StringType),
-		HaConfig:       types.ObjectNull(schemas.HaConfig{}.AttributeTypes()),
-		OidcConfig:     types.ObjectNull(schemas.OidcConfig{}.AttributeTypes()),
-		BootstrapToken: types.StringNull(),
-		Id:             types.StringValue(sshClient.Host()),
-		Server:         clusterAuth.Server,
-		KubeConfig:     types.StringValue(server.KubeConfig),
-		Token:          types.StringValue(server.Token),
-		Active:         types.BoolValue(active),
-		ClusterAuth:    clusterAuth.ToObject(ctx),
-		Orphan:         types.BoolValue(false),
-	}
+		Version:                  types.StringValue(server.Version),
+		Auth:                     sshConfig.ToObject(ctx),
+		BinDir:                   types.StringValue(binDir),
+		K3sConfig:                types.StringNull(),
+		K3sRegistry:              types.StringNull(),
+		Registries:               types.ObjectNull(schemas.Registries{}.AttributeTypes()),
+		Env:                      types.MapNull(types.StringType),
+		HaConfig:                 types.ObjectNull(schemas.HaConfig{}.AttributeTypes()),
+		OidcConfig:               types.ObjectNull(schemas.OidcConfig{}.AttributeTypes()),
+		BootstrapToken:           types.StringNull(),
+		RotateToken:              types.StringNull(),
+		Id:                       types.StringValue(sshClient.Host()),
+		Server:                   clusterAuth.Server,
+		KubeConfig:               types.StringValue(server.KubeConfig),
+		Token:                    types.StringValue(server.Token),
+		Active:                   types.BoolValue(active),
+		ClusterAuth:              clusterAuth.ToObject(ctx),
+		Orphan:                   types.BoolValue(false),
+		ConfigFragments:          types.MapNull(types.StringType),
+		ExtraFiles:               types.SetNull(extraFilesType()),
+		Manifests:                types.MapNull(types.StringType),
+		StaticPods:               types.MapNull(types.StringType),
+		ContainerdConfigTemplate: types.StringNull(),
+		ServerFlags:              schemas.NullServerFlags(),
+		MergedConfig:             types.StringValue(server.MergedConfig),
+		ManifestStatus:           types.MapNull(manifestStatusType()),
+		RestoreFromSnapshot:      types.ObjectNull(schemas.EtcdRestore{}.AttributeTypes()),
+		EtcdSnapshots:            types.ObjectNull(schemas.EtcdSnapshots{}.AttributeTypes()),
+		RestoredFrom:             types.StringNull(),
+		RotateCertificates:       types.ObjectNull(schemas.CertificateRotation{}.AttributeTypes()),
+		CustomCA:                 types.ObjectNull(schemas.CustomCA{}.AttributeTypes()),
+		CertificateExpiry:        certificateExpiryToModel(ctx, server.CertificateExpiry, &resp.Diagnostics),
+		SecretsEncryption:        types.ObjectNull(schemas.SecretsEncryption{}.AttributeTypes()),
+		GracefulRemoval:          types.ObjectNull(schemas.NodeDrain{}.AttributeTypes()),
+		ReadyTimeout:             types.StringValue(defaultReadyTimeout),
+		WaitForAddons:            types.BoolValue(false),
+		AddonsTimeout:            types.StringValue(defaultAddonsTimeout),
+		Labels:                   types.MapNull(types.StringType),
+		Taints:                   types.SetNull(taintsType()),
+		SecretsEncryptionStatus:  secretsEncryptionStatusToModel(ctx, nil),
+		NodeInfo:                 nodeInfoToModel(server.NodeInfo, &resp.Diagnostics),
+	}
+	data.NodeIp = nodeIpToModel(server.NodeInfo, &resp.Diagnostics)
+	setNodeIpConfigured(ctx, resp.Private, types.ListNull(types.StringType), &resp.Diagnostics)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -126,6 +195,8 @@ func (s *K3sServerResource) Create(ctx context.Context, req resource.CreateReque
 	// Read Terraform plan data into the model
 	tflog.Trace(ctx, "Deserializing ServerClientModel")
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	// Only a configured node_ip is rendered, the plan may hold the observed one
+	data.NodeIp = configuredNodeIp(ctx, req.Config, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -153,13 +224,43 @@ func (s *K3sServerResource) Create(ctx context.Context, req resource.CreateReque
 	if data.BootstrapToken.ValueString() != "" {
 		tflog.MaskMessageStrings(ctx, data.BootstrapToken.ValueString())
 	}
+	if data.RotateToken.ValueString() != "" {
+		tflog.MaskMessageStrings(ctx, data.RotateToken.ValueString())
+	}
+
+	configFragments := configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics)
+	extraFiles := extraFilesFromModel(ctx, data.ExtraFiles, &resp.Diagnostics)
+	manifests := manifestsFromModel(ctx, data.Manifests, &resp.Diagnostics)
+	staticPods := staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics)
+	registries := registriesFromModel(ctx, data.Registries, &resp.Diagnostics)
+	restore := etcdRestoreFromModel(ctx, data.RestoreFromSnapshot, &resp.Diagnostics)
+	etcdSnapshots := etcdSnapshotsFromModel(ctx, data.EtcdSnapshots, &resp.Diagnostics)
+	flags, diags := data.ServerFlags.ConfigValues(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	server := k3s.Server{
-		Config:   data.K3sConfig.ValueString(),
-		Registry: data.K3sRegistry.ValueString(),
-		Version:  data.Version.ValueString(),
-		BinDir:   data.BinDir.ValueString(),
-		Env:      env,
+		Config:                   data.K3sConfig.ValueString(),
+		Registry:                 data.K3sRegistry.ValueString(),
+		Registries:               registries,
+		Version:                  data.Version.ValueString(),
+		BinDir:                   data.BinDir.ValueString(),
+		Env:                      env,
+		ConfigFragments:          configFragments,
+		ExtraFiles:               extraFiles,
+		Manifests:                manifests,
+		StaticPods:               staticPods,
+		ContainerdConfigTemplate: data.ContainerdConfigTemplate.ValueString(),
+		Flags:                    flags,
+		Restore:                  restore,
+		EtcdSnapshots:            etcdSnapshots,
+		CustomCA:                 customCAFromModel(ctx, data.CustomCA, &resp.Diagnostics),
+		SecretsEncryption:        secretsEncryptionFromModel(ctx, data.SecretsEncryption, &resp.Diagnostics),
+		ReadyTimeout:             readyTimeoutFromModel(data.ReadyTimeout),
+		AddonTimeout:             addonsTimeoutFromModel(data.WaitForAddons, data.AddonsTimeout),
+		NodeMetadata:             nodeMetadataFromModel(ctx, data.Labels, data.Taints, types.MapNull(types.StringType), types.SetNull(taintsType()), nil, &resp.Diagnostics),
 	}
 	if !data.BootstrapToken.IsNull() && !data.BootstrapToken.IsUnknown() {
 		server.Token = data.BootstrapToken.ValueString()
@@ -188,6 +289,20 @@ func (s *K3sServerResource) Create(ctx context.Context, req resource.CreateReque
 		resp.Diagnostics.AddError("running k3s server install", err.Error())
 		return
 	}
+	if tokenRotationRequested(types.StringNull(), data.RotateToken) {
+		if err := server.RotateToken(ctx, sshClient, data.RotateToken.ValueString()); err != nil {
+			resp.Diagnostics.AddError("rotating k3s server token", err.Error())
+			return
+		}
+		if err := server.Update(ctx, sshClient); err != nil {
+			resp.Diagnostics.AddError("restarting k3s server with the rotated token", err.Error())
+			return
+		}
+	}
+	if err := server.ApplyNodeMetadata(ctx, sshClient); err != nil {
+		resp.Diagnostics.AddError("applying k3s server labels and taints", err.Error())
+		return
+	}
 
 	exists, active, err := server.Refresh(ctx, sshClient)
 	if err != nil {
@@ -204,6 +319,19 @@ func (s *K3sServerResource) Create(ctx context.Context, req resource.CreateReque
 	data.Version = types.StringValue(server.Version)
 	data.Id = types.StringValue(sshClient.Host())
 	data.Active = types.BoolValue(active)
+	data.MergedConfig = types.StringValue(server.MergedConfig)
+	data.ManifestStatus = manifestStatusToModel(ctx, server.ManifestStatus, &resp.Diagnostics)
+	data.CertificateExpiry = certificateExpiryToModel(ctx, server.CertificateExpiry, &resp.Diagnostics)
+	data.SecretsEncryptionStatus = secretsEncryptionStatusToModel(ctx, server.SecretsEncryptionStatus)
+	data.NodeInfo = nodeInfoToModel(server.NodeInfo, &resp.Diagnostics)
+	setNodeIpConfigured(ctx, resp.Private, data.NodeIp, &resp.Diagnostics)
+	if data.NodeIp.IsNull() {
+		data.NodeIp = nodeIpToModel(server.NodeInfo, &resp.Diagnostics)
+	}
+	data.RestoredFrom = types.StringNull()
+	if restore != nil {
+		data.RestoredFrom = types.StringValue(restore.Source())
+	}
 
 	clusterAuth, err := schemas.BuildClusterAuth(server.KubeConfig)
 	if err != nil {
@@ -258,7 +386,11 @@ func (s *K3sServerResource) Delete(ctx context.Context, req resource.DeleteReque
 	}
 
 	server := k3s.Server{
-		BinDir: data.BinDir.ValueString(),
+		BinDir:          data.BinDir.ValueString(),
+		GracefulRemoval: nodeDrainFromModel(ctx, data.GracefulRemoval, &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
 	if err := server.Uninstall(ctx, sshClient); err != nil {
@@ -305,7 +437,17 @@ func (s *K3sServerResource) Read(ctx context.Context, req resource.ReadRequest,
 	}
 
 	server := k3s.Server{
-		BinDir: data.BinDir.ValueString(),
+		BinDir:                   data.BinDir.ValueString(),
+		ConfigFragments:          configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics),
+		ExtraFiles:               extraFilePaths(ctx, data.ExtraFiles, &resp.Diagnostics),
+		Manifests:                manifestsFromModel(ctx, data.Manifests, &resp.Diagnostics),
+		StaticPods:               staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics),
+		ContainerdConfigTemplate: data.ContainerdConfigTemplate.ValueString(),
+		SecretsEncryption:        secretsEncryptionFromModel(ctx, data.SecretsEncryption, &resp.Diagnostics),
+		NodeMetadata:             nodeMetadataFromModel(ctx, data.Labels, data.Taints, types.MapNull(types.StringType), types.SetNull(taintsType()), nil, &resp.Diagnostics),
+	}
+	if resp.Diagnostics.HasError() {
+		return
 	}
 	exists, active, err := server.Refresh(ctx, sshClient)
 	if err != nil {
@@ -322,6 +464,12 @@ func (s *K3sServerResource) Read(ctx context.Context, req resource.ReadRequest,
 	data.Version = types.StringValue(server.Version)
 	data.Id = types.StringValue(sshClient.Host())
 	data.Active = types.BoolValue(active)
+	data.MergedConfig = types.StringValue(server.MergedConfig)
+	data.ManifestStatus = manifestStatusToModel(ctx, server.ManifestStatus, &resp.Diagnostics)
+	data.CertificateExpiry = certificateExpiryToModel(ctx, server.CertificateExpiry, &resp.Diagnostics)
+	data.SecretsEncryptionStatus = secretsEncryptionStatusToModel(ctx, server.SecretsEncryptionStatus)
+	data.NodeInfo = nodeInfoToModel(server.NodeInfo, &resp.Diagnostics)
+	data.NodeIp = refreshedNodeIp(ctx, resp.Private, data.NodeIp, server.NodeInfo, &resp.Diagnostics)
 
 	clusterAuth, err := schemas.BuildClusterAuth(server.KubeConfig)
 	if err != nil {
@@ -330,6 +478,12 @@ func (s *K3sServerResource) Read(ctx context.Context, req resource.ReadRequest,
 	}
 	data.ClusterAuth = clusterAuth.ToObject(ctx)
 	data.Server = clusterAuth.Server
+	data.ConfigFragments = configFragmentsToModel(ctx, data.ConfigFragments, server.ConfigFragments, &resp.Diagnostics)
+	data.StaticPods = staticPodsToModel(ctx, data.StaticPods, server.StaticPods, &resp.Diagnostics)
+	data.ContainerdConfigTemplate = observedString(data.ContainerdConfigTemplate, server.ContainerdConfigTemplate)
+	data.ExtraFiles = extraFilesToModel(ctx, data.ExtraFiles, server.ObservedExtraFiles, &resp.Diagnostics)
+	data.Labels = labelsToModel(ctx, data.Labels, server.NodeMetadata, &resp.Diagnostics)
+	data.Taints = taintsToModel(ctx, data.Taints, server.NodeMetadata, &resp.Diagnostics)
 
 	var oidcConfig *schemas.OidcConfig
 	if !data.OidcConfig.IsNull() && !data.OidcConfig.IsUnknown() {
@@ -351,6 +505,9 @@ func (s *K3sServerResource) Update(ctx context.Context, req resource.UpdateReque
 
 	tflog.Trace(ctx, "Deserializing ServerClientModel")
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+	// Only a configured node_ip is rendered, the plan may hold the observed one
+	plannedNodeIp := data.NodeIp
+	data.NodeIp = configuredNodeIp(ctx, req.Config, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -378,13 +535,50 @@ func (s *K3sServerResource) Update(ctx context.Context, req resource.UpdateReque
 	if data.BootstrapToken.ValueString() != "" {
 		tflog.MaskMessageStrings(ctx, data.BootstrapToken.ValueString())
 	}
+	if data.RotateToken.ValueString() != "" {
+		tflog.MaskMessageStrings(ctx, data.RotateToken.ValueString())
+	}
+
+	var state ServerClientModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	validateCustomCAChange(ctx, state.CustomCA, data.CustomCA, &resp.Diagnostics)
+	configFragments := configFragmentsFromModel(ctx, data.ConfigFragments, &resp.Diagnostics)
+	priorConfigFragments := configFragmentsFromModel(ctx, state.ConfigFragments, &resp.Diagnostics)
+	extraFiles := extraFilesFromModel(ctx, data.ExtraFiles, &resp.Diagnostics)
+	manifests := manifestsFromModel(ctx, data.Manifests, &resp.Diagnostics)
+	staticPods := staticPodsFromModel(ctx, data.StaticPods, &resp.Diagnostics)
+	registries := registriesFromModel(ctx, data.Registries, &resp.Diagnostics)
+	etcdSnapshots := etcdSnapshotsFromModel(ctx, data.EtcdSnapshots, &resp.Diagnostics)
+	flags, diags := data.ServerFlags.ConfigValues(ctx)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	server := k3s.Server{
-		Config:   data.K3sConfig.ValueString(),
-		Registry: data.K3sRegistry.ValueString(),
-		Version:  data.Version.ValueString(),
-		BinDir:   data.BinDir.ValueString(),
-		Env:      env,
+		Config:                         data.K3sConfig.ValueString(),
+		Registry:                       data.K3sRegistry.ValueString(),
+		Registries:                     registries,
+		StaleRegistryHosts:             staleRegistryHosts(registriesFromModel(ctx, state.Registries, &resp.Diagnostics), registries),
+		Version:                        data.Version.ValueString(),
+		BinDir:                         data.BinDir.ValueString(),
+		Env:                            env,
+		ConfigFragments:                configFragments,
+		StaleConfigFragments:           staleConfigFragments(priorConfigFragments, configFragments),
+		ExtraFiles:                     extraFiles,
+		StaleExtraFiles:                staleExtraFiles(extraFilePaths(ctx, state.ExtraFiles, &resp.Diagnostics), extraFiles),
+		Manifests:                      manifests,
+		StaleManifests:                 staleManifests(manifestsFromModel(ctx, state.Manifests, &resp.Diagnostics), manifests),
+		StaticPods:                     staticPods,
+		StaleStaticPods:                staleStaticPods(staticPodsFromModel(ctx, state.StaticPods, &resp.Diagnostics), staticPods),
+		ContainerdConfigTemplate:       data.ContainerdConfigTemplate.ValueString(),
+		RemoveContainerdConfigTemplate: removeContainerdConfigTemplate(state.ContainerdConfigTemplate, data.ContainerdConfigTemplate),
+		EtcdSnapshots:                  etcdSnapshots,
+		SecretsEncryption:              secretsEncryptionFromModel(ctx, data.SecretsEncryption, &resp.Diagnostics),
+		ReadyTimeout:                   readyTimeoutFromModel(data.ReadyTimeout),
+		AddonTimeout:                   addonsTimeoutFromModel(data.WaitForAddons, data.AddonsTimeout),
+		NodeMetadata:                   nodeMetadataFromModel(ctx, data.Labels, data.Taints, state.Labels, state.Taints, nil, &resp.Diagnostics),
+		Flags:                          flags,
 	}
 
 	if err := server.Validate(ctx); err != nil {
@@ -402,12 +596,60 @@ func (s *K3sServerResource) Update(ctx context.Context, req resource.UpdateReque
 		server.WithHa(*haConfig)
 	}
 
-	if err := server.PreInstall(ctx, sshClient); err != nil {
-		resp.Diagnostics.AddError("running k3s server preinstall", err.Error())
-		return
+	// Rotate before the backup, k3s only starts with the new token afterwards
+	if tokenRotationRequested(state.RotateToken, data.RotateToken) {
+		if err := server.RotateToken(ctx, sshClient, data.RotateToken.ValueString()); err != nil {
+			resp.Diagnostics.AddError("rotating k3s server token", err.Error())
+			return
+		}
 	}
-	if err := server.Update(ctx, sshClient); err != nil {
-		resp.Diagnostics.AddError("running k3s server update", err.Error())
+
+	if nodeMetadataOnlyChange(req) {
+		tflog.Info(ctx, "Only labels or taints changed, leaving the k3s service running")
+	} else {
+		if err := server.Backup(ctx, sshClient); err != nil {
+			resp.Diagnostics.AddError("backing up k3s server", err.Error())
+			return
+		}
+		if err := server.PreInstall(ctx, sshClient); err != nil {
+			resp.Diagnostics.AddError("running k3s server preinstall", err.Error())
+			return
+		}
+		// The restart in Update picks up the rotated CA
+		if customCAChanged(ctx, state.CustomCA, data.CustomCA, &resp.Diagnostics) {
+			model := customCAModel(ctx, data.CustomCA, &resp.Diagnostics)
+			ca := customCAFromModel(ctx, data.CustomCA, &resp.Diagnostics)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			if err := server.RotateCA(ctx, sshClient, ca, model.ForceRotation.ValueBool()); err != nil {
+				resp.Diagnostics.AddError("rotating k3s cluster CA", err.Error())
+				return
+			}
+		}
+		if err := server.Update(ctx, sshClient); err != nil {
+			addUpdateDiagnostics(&resp.Diagnostics, "running k3s server update", err)
+			return
+		}
+		if certificateRotationRequested(ctx, state.RotateCertificates, data.RotateCertificates, &resp.Diagnostics) {
+			services := certificateServices(ctx, certificateRotationFromModel(ctx, data.RotateCertificates, &resp.Diagnostics), &resp.Diagnostics)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+			if err := server.RotateCertificates(ctx, sshClient, services); err != nil {
+				resp.Diagnostics.AddError("rotating k3s server certificates", err.Error())
+				return
+			}
+		}
+		if secretsKeyRotationRequested(ctx, state.SecretsEncryption, data.SecretsEncryption, &resp.Diagnostics) {
+			if err := server.RotateEncryptionKeys(ctx, sshClient, k3s.SECRETS_REENCRYPT_TIMEOUT); err != nil {
+				resp.Diagnostics.AddError("rotating k3s secrets encryption keys", err.Error())
+				return
+			}
+		}
+	}
+	if err := server.ApplyNodeMetadata(ctx, sshClient); err != nil {
+		resp.Diagnostics.AddError("applying k3s server labels and taints", err.Error())
 		return
 	}
 
@@ -426,6 +668,19 @@ func (s *K3sServerResource) Update(ctx context.Context, req resource.UpdateReque
 	data.Version = types.StringValue(server.Version)
 	data.Id = types.StringValue(sshClient.Host())
 	data.Active = types.BoolValue(active)
+	data.MergedConfig = types.StringValue(server.MergedConfig)
+	data.ManifestStatus = manifestStatusToModel(ctx, server.ManifestStatus, &resp.Diagnostics)
+	data.CertificateExpiry = certificateExpiryToModel(ctx, server.CertificateExpiry, &resp.Diagnostics)
+	data.SecretsEncryptionStatus = secretsEncryptionStatusToModel(ctx, server.SecretsEncryptionStatus)
+	data.NodeInfo = nodeInfoToModel(server.NodeInfo, &resp.Diagnostics)
+	setNodeIpConfigured(ctx, resp.Private, data.NodeIp, &resp.Diagnostics)
+	if data.NodeIp.IsNull() {
+		// A known plan holds the prior observed addresses, refresh updates them
+		data.NodeIp = plannedNodeIp
+		if plannedNodeIp.IsUnknown() {
+			data.NodeIp = nodeIpToModel(server.NodeInfo, &resp.Diagnostics)
+		}
+	}
 
 	clusterAuth, err := schemas.BuildClusterAuth(server.KubeConfig)
 	if err != nil {
@@ -443,6 +698,53 @@ func (s *K3sServerResource) Update(ctx context.Context, req resource.UpdateReque
 
 }
 
+// ModifyPlan implements resource.ResourceWithModifyPlan. A custom CA can
+// only change through rotate-ca, and secrets encryption cannot be turned
+// off while secrets are encrypted. A node_ip removed from configuration is
+// recomputed. Rotating the certificates replaces the
+// client certificate in the kubeconfig, so the outputs built from it are
+// only known after apply.
+func (s *K3sServerResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan, state ServerClientModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	validateCustomCAChange(ctx, state.CustomCA, plan.CustomCA, &resp.Diagnostics)
+	validateSecretsEncryptionChange(ctx, state.SecretsEncryption, state.SecretsEncryptionStatus, plan.SecretsEncryption, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	modified := false
+	configuredIp := configuredNodeIp(ctx, req.Config, &resp.Diagnostics)
+	configured, _ := nodeIpConfigured(ctx, req.Private, &resp.Diagnostics)
+	switch {
+	case configuredIp.IsNull() && configured:
+		// The prior node_ip is the configured one and has to be removed from
+		// config, not kept as the observed address
+		plan.NodeIp = types.ListUnknown(types.StringType)
+		modified = true
+	case !configuredIp.IsNull() && !configured:
+		// Imported state holds the observed address, record the configured
+		// one with the planned apply
+		setNodeIpConfigured(ctx, resp.Private, configuredIp, &resp.Diagnostics)
+	}
+	if certificateRotationRequested(ctx, state.RotateCertificates, plan.RotateCertificates, &resp.Diagnostics) {
+		plan.KubeConfig = types.StringUnknown()
+		plan.ClusterAuth = types.ObjectUnknown(schemas.ClusterAuth{}.AttributeTypes())
+		plan.CertificateExpiry = types.MapUnknown(types.StringType)
+		modified = true
+	}
+	if modified {
+		resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+	}
+}
+
 // ConfigValidators implements resource.ResourceWithConfigValidators.
 func (s *K3sServerResource) ConfigValidators(ctx context.Context) []resource.ConfigValidator {
 	return []resource.ConfigValidator{&K3sServerResource{}}
@@ -490,6 +792,52 @@ func validateServerAndReturn(ctx context.Context, data ServerClientModel, d *dia
 		return
 	}
 
+	validateConfigFragments(ctx, data.ConfigFragments, d)
+	validateRegistries(ctx, data.Registries, data.K3sRegistry, d)
+	validateExtraFiles(ctx, data.ExtraFiles, d)
+	validateManifests(ctx, data.Manifests, d)
+	validateStaticPods(ctx, data.StaticPods, d)
+	validateContainerdConfigTemplate(data.ContainerdConfigTemplate, d)
+	validateEnvNames(data.Env, d)
+	validateRotateToken(data.RotateToken, d)
+	validateCertificateRotation(ctx, data.RotateCertificates, d)
+	validateCustomCA(ctx, data.CustomCA, d)
+	validateSecretsEncryption(ctx, data.SecretsEncryption, d)
+	validateNodeDrain(ctx, "graceful_removal", data.GracefulRemoval, d)
+	validateReadyTimeout(data.ReadyTimeout, d)
+	validateAddonsTimeout(data.AddonsTimeout, d)
+	validateNodeMetadata(ctx, data.Labels, data.Taints, d)
+	validateEtcdRestore(ctx, data, d)
+	if d.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "Validating typed server flags")
+	if err := data.ServerFlags.Validate(ctx); err != nil {
+		d.AddError("validating server flags", err.Error())
+		return
+	}
+	if !data.K3sConfig.IsUnknown() {
+		flags, diags := data.ServerFlags.ConfigValues(ctx)
+		d.Append(diags...)
+		if d.HasError() {
+			return
+		}
+
+		server := k3s.Server{
+			Config:            data.K3sConfig.ValueString(),
+			Version:           data.Version.ValueString(),
+			ConfigFragments:   plannedConfigFragments(ctx, data.ConfigFragments, d),
+			EtcdSnapshots:     plannedEtcdSnapshots(ctx, data.EtcdSnapshots, d),
+			SecretsEncryption: plannedSecretsEncryption(ctx, data.SecretsEncryption, d),
+			Flags:             flags,
+		}
+		if err := server.Validate(ctx); err != nil {
+			d.AddError("validating config", err.Error())
+			return
+		}
+	}
+
 	if !data.HaConfig.IsNull() && !data.HaConfig.IsUnknown() {
 		tflog.Trace(ctx, "Deserializing HaConfig")
 		d.Append(data.HaConfig.As(ctx, &haConfig, basetypes.ObjectAsOptions{})...)
@@ -520,6 +868,23 @@ func validateServerAndReturn(ctx context.Context, data ServerClientModel, d *dia
 	return
 }
 
+// Reports a failed update. When the previous configuration was restored,
+// the rollback outcome is reported as a separate diagnostic.
+func addUpdateDiagnostics(d *diag.Diagnostics, summary string, err error) {
+	var rollbackErr *k3s.RollbackError
+	if !errors.As(err, &rollbackErr) {
+		d.AddError(summary, err.Error())
+		return
+	}
+
+	d.AddError(summary, rollbackErr.Err.Error())
+	if rollbackErr.RollbackErr != nil {
+		d.AddError("rolling back k3s update", rollbackErr.RollbackErr.Error())
+		return
+	}
+	d.AddWarning("rolled back k3s update", "The service did not become active with the new configuration. The previous config, registries, extra files and binary were restored and the service is active again.")
+}
+
 func setOIDCJWKSKeys(ctx context.Context, data *ServerClientModel, oidcConfig *schemas.OidcConfig, server k3s.Server, sshClient ssh_client.SSHClient, d *diag.Diagnostics) bool {
 	if oidcConfig == nil {
 		return true
@@ -541,8 +906,8 @@ func (s *K3sServerResource) Schema(context context.Context, resource resource.Sc
 	resp.Schema = schema.Schema{
 		MarkdownDescription: ("Creates a k3s server resource. At least one of `password`, `private_key`, or `private_key_file` must be provided.\n" +
 			"When running in highly available mode, it is up to the consumers of this module to correctly implement " +
-			"the raft protocol and create an odd number of ha nodes. Due to how HA works, we do not offer a method to " +
-			"gracefully delete a controller node from the cluster before running `k3s-uninstall.sh` during deletion of this resource."),
+			"the raft protocol and create an odd number of ha nodes. Set `graceful_removal` to drain a server and remove its etcd member " +
+			"through another server before `k3s-uninstall.sh` runs during deletion of this resource."),
 		Attributes: map[string]schema.Attribute{
 			// Version of k3s
 			"version": schema.StringAttribute{
@@ -561,12 +926,12 @@ func (s *K3sServerResource) Schema(context context.Context, resource resource.Sc
 			// Config
 			"config": schema.StringAttribute{
 				Optional:            true,
-				MarkdownDescription: "K3s server config",
+				MarkdownDescription: "K3s server config. Keys and value types are checked against the server flags of the configured `version` during plan.",
 			},
 			"env": schema.MapAttribute{
 				Optional:            true,
 				Sensitive:           true,
-				MarkdownDescription: "Extra environment variables to pass to the process",
+				MarkdownDescription: "Extra environment variables to pass to the process. Names must be valid shell variable names.",
 				ElementType:         types.StringType,
 			},
 			"bootstrap_token": schema.StringAttribute{
@@ -577,10 +942,28 @@ func (s *K3sServerResource) Schema(context context.Context, resource resource.Sc
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"rotate_token": rotateTokenSchema(),
 			"registry": schema.StringAttribute{
 				Optional:            true,
 				MarkdownDescription: "K3s server registry",
 			},
+			"registries":                 schemas.Registries{}.Schema(),
+			"config_fragments":           configFragmentsSchema(),
+			"extra_files":                schemas.ExtraFile{}.Schema(),
+			"manifests":                  manifestsSchema(),
+			"static_pods":                staticPodsSchema(),
+			"containerd_config_template": containerdConfigTemplateSchema(),
+			"restore_from_snapshot":      schemas.EtcdRestore{}.Schema(),
+			"etcd_snapshots":             schemas.EtcdSnapshots{}.Schema(),
+			"rotate_certificates":        schemas.CertificateRotation{}.Schema(),
+			"custom_ca":                  schemas.CustomCA{}.Schema(),
+			"secrets_encryption":         schemas.SecretsEncryption{}.Schema(),
+			"graceful_removal":           schemas.NodeDrain{}.GracefulRemovalSchema(),
+			"ready_timeout":              readyTimeoutSchema("the API server to pass `/readyz` and, unless `disable-agent` is set, the node to report Ready"),
+			"wait_for_addons":            waitForAddonsSchema(),
+			"addons_timeout":             addonsTimeoutSchema(),
+			"labels":                     labelsSchema(),
+			"taints":                     schemas.NodeTaint{}.Schema(),
 			"orphan": schema.BoolAttribute{
 				Optional:            true,
 				Computed:            true,
@@ -609,6 +992,7 @@ func (s *K3sServerResource) Schema(context context.Context, resource resource.Sc
 				MarkdownDescription: "Observed server token used for joining nodes to the cluster.",
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
+					rotatedTokenModifier{},
 				},
 			},
 			"server": schema.StringAttribute{
@@ -626,11 +1010,29 @@ func (s *K3sServerResource) Schema(context context.Context, resource resource.Sc
 					boolplanmodifier.UseStateForUnknown(),
 				},
 			},
-			"highly_available": schemas.HaConfig{}.Schema(),
-			"oidc":             schemas.OidcConfig{}.Schema(),
-			"cluster_auth":     schemas.ClusterAuth{}.Schema(),
+			"restored_from": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Path or `s3://` URL of the snapshot the cluster was restored from on create, null when it was not restored.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"merged_config":             mergedConfigSchema(),
+			"certificate_expiry":        certificateExpirySchema(),
+			"secrets_encryption_status": schemas.SecretsEncryptionStatus{}.Schema(),
+			"manifest_status":           schemas.ManifestStatus{}.Schema(),
+			"highly_available":          schemas.HaConfig{}.Schema(),
+			"oidc":                      schemas.OidcConfig{}.Schema(),
+			"cluster_auth":              schemas.ClusterAuth{}.Schema(),
 		},
 	}
+
+	for name, attribute := range (schemas.ServerFlags{}).Attributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
+	for name, attribute := range (schemas.NodeInfo{}).Attributes() {
+		resp.Schema.Attributes[name] = attribute
+	}
 }
 
 func parseServerImportID(rawID string) (ssh_client.SSHConfig, string, error) {
@@ -693,3 +1095,24 @@ func optionalImportString(value string) types.String {
 	}
 	return types.StringValue(value)
 }
+
+// Env names end up in the install command, so reject anything that is not a
+// plain variable name during plan.
+func validateEnvNames(env types.Map, d *diag.Diagnostics) {
+	if env.IsNull() || env.IsUnknown() {
+		return
+	}
+
+	names := make([]string, 0, len(env.Elements()))
+	for name := range env.Elements() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := k3s.ValidateEnvName(name); err != nil {
+			d.AddError("validating env", err.Error())
+			return
+		}
+	}
+}
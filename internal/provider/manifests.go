@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func manifestsSchema() schema.Attribute {
+	return schema.MapAttribute{
+		Optional:    true,
+		ElementType: types.StringType,
+		MarkdownDescription: "Auto-deploy manifests written to `<data-dir>/server/manifests`, keyed by name without the `.yaml` extension. " +
+			"Each value must be one or more Kubernetes YAML documents. Manifests removed from this map are deleted from the host, " +
+			"which stops k3s from reapplying them but does not delete the objects they created.",
+	}
+}
+
+func manifestStatusType() types.ObjectType {
+	return types.ObjectType{AttrTypes: schemas.ManifestStatus{}.AttributeTypes()}
+}
+
+// Manifests share the name to content shape of config fragments.
+func manifestsFromModel(ctx context.Context, value types.Map, d *diag.Diagnostics) map[string]string {
+	return configFragmentsFromModel(ctx, value, d)
+}
+
+func validateManifests(ctx context.Context, value types.Map, d *diag.Diagnostics) {
+	if !configFragmentsKnown(value) {
+		return
+	}
+
+	manifests := manifestsFromModel(ctx, value, d)
+	if d.HasError() {
+		return
+	}
+	if err := k3s.ValidateManifests(manifests); err != nil {
+		d.AddError("validating manifests", err.Error())
+	}
+}
+
+// Names present in the prior state that are no longer declared.
+func staleManifests(prior map[string]string, planned map[string]string) []string {
+	return staleConfigFragments(prior, planned)
+}
+
+// State value for the status of the declared manifests.
+func manifestStatusToModel(ctx context.Context, observed map[string]k3s.ManifestStatus, d *diag.Diagnostics) types.Map {
+	statuses := make(map[string]schemas.ManifestStatus, len(observed))
+	for name, status := range observed {
+		statuses[name] = schemas.ManifestStatus{
+			Status:  types.StringValue(status.Status),
+			Message: types.StringValue(status.Message),
+		}
+	}
+
+	value, diags := types.MapValueFrom(ctx, manifestStatusType(), statuses)
+	d.Append(diags...)
+	return value
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+// Private state key recording whether node_ip of k3s_server was set in
+// configuration. The attribute is computed from the Node otherwise, so the
+// state alone cannot tell a configured address from an observed one.
+const nodeIpConfiguredKey = "node_ip_configured"
+
+// Private state of a resource, as passed to every CRUD method.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func nodeInfoToModel(info *k3s.NodeInfo, d *diag.Diagnostics) schemas.NodeInfo {
+	if info == nil {
+		return schemas.NullNodeInfo()
+	}
+	return schemas.NodeInfo{
+		NodeName:   nonEmptyString(info.NodeName),
+		ExternalIp: addressesToModel(info.ExternalIPs, d),
+		Os:         nonEmptyString(info.OS),
+		Arch:       nonEmptyString(info.Arch),
+		Kernel:     nonEmptyString(info.Kernel),
+	}
+}
+
+// InternalIP addresses of the Node, null until it registered.
+func nodeIpToModel(info *k3s.NodeInfo, d *diag.Diagnostics) types.List {
+	if info == nil {
+		return types.ListNull(types.StringType)
+	}
+	return addressesToModel(info.NodeIPs, d)
+}
+
+func addressesToModel(addresses []string, d *diag.Diagnostics) types.List {
+	if len(addresses) == 0 {
+		return types.ListNull(types.StringType)
+	}
+	list, diags := types.ListValueFrom(context.Background(), types.StringType, addresses)
+	d.Append(diags...)
+	return list
+}
+
+// node_ip as set in configuration, null when it is left to be computed.
+func configuredNodeIp(ctx context.Context, config tfsdk.Config, d *diag.Diagnostics) types.List {
+	var nodeIp types.List
+	d.Append(config.GetAttribute(ctx, path.Root("node_ip"), &nodeIp)...)
+	return nodeIp
+}
+
+// Whether node_ip was set in configuration, and whether the private state
+// records it at all. It does not for resources created before node_ip was
+// computed.
+func nodeIpConfigured(ctx context.Context, private privateState, d *diag.Diagnostics) (configured bool, recorded bool) {
+	value, diags := private.GetKey(ctx, nodeIpConfiguredKey)
+	d.Append(diags...)
+	return string(value) == "true", len(value) != 0
+}
+
+func setNodeIpConfigured(ctx context.Context, private privateState, configured types.List, d *diag.Diagnostics) {
+	value := "false"
+	if !configured.IsNull() {
+		value = "true"
+	}
+	d.Append(private.SetKey(ctx, nodeIpConfiguredKey, []byte(value))...)
+}
+
+// node_ip after a refresh: the prior value when it was configured, the
+// observed addresses otherwise. The private state of resources created before
+// node_ip was computed has no record, their node_ip is configured when set.
+func refreshedNodeIp(ctx context.Context, private privateState, prior types.List, info *k3s.NodeInfo, d *diag.Diagnostics) types.List {
+	configured, recorded := nodeIpConfigured(ctx, private, d)
+	if !recorded {
+		configured = !prior.IsNull()
+		setNodeIpConfigured(ctx, private, prior, d)
+	}
+	if configured {
+		return prior
+	}
+	return nodeIpToModel(info, d)
+}
+
+// Null for facts that could not be observed.
+func nonEmptyString(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+	return types.StringValue(value)
+}
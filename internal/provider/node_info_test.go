@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+type fakePrivateState map[string][]byte
+
+func (p fakePrivateState) GetKey(_ context.Context, key string) ([]byte, diag.Diagnostics) {
+	return p[key], nil
+}
+
+func (p fakePrivateState) SetKey(_ context.Context, key string, value []byte) diag.Diagnostics {
+	p[key] = value
+	return nil
+}
+
+func TestNodeInfoToModel(t *testing.T) {
+	var d diag.Diagnostics
+
+	info := &k3s.NodeInfo{
+		NodeName: "server-0",
+		NodeIPs:  []string{"10.0.0.5", "fd00::5"},
+		OS:       "linux",
+		Arch:     "arm64",
+		Kernel:   "6.8.0",
+	}
+	model := nodeInfoToModel(info, &d)
+	nodeIp := nodeIpToModel(info, &d)
+	if d.HasError() {
+		t.Fatalf("diagnostics = %v", d)
+	}
+	if model.NodeName.ValueString() != "server-0" || model.Arch.ValueString() != "arm64" {
+		t.Errorf("nodeInfoToModel() = %+v", model)
+	}
+	if !model.ExternalIp.IsNull() {
+		t.Errorf("external_ip = %v, want null without ExternalIP addresses", model.ExternalIp)
+	}
+	want := types.ListValueMust(types.StringType, []attr.Value{types.StringValue("10.0.0.5"), types.StringValue("fd00::5")})
+	if !nodeIp.Equal(want) {
+		t.Errorf("nodeIpToModel() = %v, want %v", nodeIp, want)
+	}
+
+	if model := nodeInfoToModel(nil, &d); !model.NodeName.IsNull() || !model.ExternalIp.IsNull() {
+		t.Errorf("nodeInfoToModel(nil) = %+v, want nulls", model)
+	}
+	if nodeIp := nodeIpToModel(nil, &d); !nodeIp.IsNull() {
+		t.Errorf("nodeIpToModel(nil) = %v, want null", nodeIp)
+	}
+}
+
+func TestNodeIpConfigured(t *testing.T) {
+	ctx := context.Background()
+	var d diag.Diagnostics
+	private := fakePrivateState{}
+
+	if configured, recorded := nodeIpConfigured(ctx, private, &d); configured || recorded {
+		t.Errorf("nodeIpConfigured() = %v, %v without private state", configured, recorded)
+	}
+	setNodeIpConfigured(ctx, private, types.ListValueMust(types.StringType, []attr.Value{types.StringValue("10.0.0.5")}), &d)
+	if configured, _ := nodeIpConfigured(ctx, private, &d); !configured {
+		t.Error("nodeIpConfigured() = false after a configured node_ip")
+	}
+	setNodeIpConfigured(ctx, private, types.ListNull(types.StringType), &d)
+	if configured, recorded := nodeIpConfigured(ctx, private, &d); configured || !recorded {
+		t.Errorf("nodeIpConfigured() = %v, %v after node_ip was removed", configured, recorded)
+	}
+	if d.HasError() {
+		t.Fatalf("diagnostics = %v", d)
+	}
+}
+
+func TestRefreshedNodeIp(t *testing.T) {
+	ctx := context.Background()
+	configuredIp := types.ListValueMust(types.StringType, []attr.Value{types.StringValue("192.168.1.5")})
+	observedIp := types.ListValueMust(types.StringType, []attr.Value{types.StringValue("10.0.0.5")})
+	info := &k3s.NodeInfo{NodeIPs: []string{"10.0.0.5"}}
+
+	cases := []struct {
+		name           string
+		private        fakePrivateState
+		prior          types.List
+		want           types.List
+		wantConfigured bool
+	}{
+		{"configured", fakePrivateState{nodeIpConfiguredKey: []byte("true")}, configuredIp, configuredIp, true},
+		{"computed", fakePrivateState{nodeIpConfiguredKey: []byte("false")}, configuredIp, observedIp, false},
+		{"pre-existing configured", fakePrivateState{}, configuredIp, configuredIp, true},
+		{"pre-existing unset", fakePrivateState{}, types.ListNull(types.StringType), observedIp, false},
+	}
+	for _, c := range cases {
+		var d diag.Diagnostics
+		got := refreshedNodeIp(ctx, c.private, c.prior, info, &d)
+		if d.HasError() {
+			t.Fatalf("%s: diagnostics = %v", c.name, d)
+		}
+		if !got.Equal(c.want) {
+			t.Errorf("%s: refreshedNodeIp() = %v, want %v", c.name, got, c.want)
+		}
+		if configured, recorded := nodeIpConfigured(ctx, c.private, &d); configured != c.wantConfigured || !recorded {
+			t.Errorf("%s: nodeIpConfigured() = %v, %v, want %v, true", c.name, configured, recorded, c.wantConfigured)
+		}
+	}
+}
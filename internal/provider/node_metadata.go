@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func labelsSchema() schema.Attribute {
+	return schema.MapAttribute{
+		Optional:    true,
+		ElementType: types.StringType,
+		MarkdownDescription: "Labels set on the Node through the API on every apply. Labels removed from this map are removed from the Node, labels set by anything else are left alone. " +
+			"Refresh reports labels that were changed or removed on the Node. Unlike `node-label` in config, changes apply to registered nodes without restarting k3s.",
+	}
+}
+
+// API credentials of an agent, which has no kubeconfig of its own.
+func agentClusterAuthSchema() schema.Attribute {
+	clusterAuth := schemas.ClusterAuth{}.InputSchema().(schema.SingleNestedAttribute)
+	clusterAuth.MarkdownDescription += " Required to manage `labels` and `taints`."
+	return clusterAuth
+}
+
+func taintsType() types.ObjectType {
+	return types.ObjectType{AttrTypes: schemas.NodeTaint{}.AttributeTypes()}
+}
+
+func labelsFromModel(ctx context.Context, value types.Map, d *diag.Diagnostics) map[string]string {
+	labels := make(map[string]string)
+	if value.IsNull() || value.IsUnknown() {
+		return labels
+	}
+	d.Append(value.ElementsAs(ctx, &labels, false)...)
+	return labels
+}
+
+func taintsFromModel(ctx context.Context, value types.Set, d *diag.Diagnostics) []k3s.Taint {
+	taints := []k3s.Taint{}
+	if value.IsNull() || value.IsUnknown() {
+		return taints
+	}
+
+	var models []schemas.NodeTaint
+	d.Append(value.ElementsAs(ctx, &models, false)...)
+	for _, model := range models {
+		taints = append(taints, k3s.Taint{
+			Key:    model.Key.ValueString(),
+			Value:  model.Value.ValueString(),
+			Effect: model.Effect.ValueString(),
+		})
+	}
+	sort.Slice(taints, func(i, j int) bool {
+		if taints[i].Key != taints[j].Key {
+			return taints[i].Key < taints[j].Key
+		}
+		return taints[i].Effect < taints[j].Effect
+	})
+	return taints
+}
+
+// Labels and taints to reconcile, with those of the prior state that are no
+// longer declared as stale. Nil when neither declares anything.
+func nodeMetadataFromModel(ctx context.Context, labels types.Map, taints types.Set, priorLabels types.Map, priorTaints types.Set, clusterAuth *k3s.ClusterAuth, d *diag.Diagnostics) *k3s.NodeMetadata {
+	planned := labelsFromModel(ctx, labels, d)
+	plannedTaints := taintsFromModel(ctx, taints, d)
+	prior := labelsFromModel(ctx, priorLabels, d)
+	stale := staleTaints(taintsFromModel(ctx, priorTaints, d), plannedTaints)
+	if len(planned)+len(plannedTaints)+len(prior)+len(stale) == 0 {
+		return nil
+	}
+
+	return &k3s.NodeMetadata{
+		Labels:      planned,
+		Taints:      plannedTaints,
+		StaleLabels: staleConfigFragments(prior, planned),
+		StaleTaints: stale,
+		ClusterAuth: clusterAuth,
+	}
+}
+
+// Prior taints whose key and effect are no longer declared.
+func staleTaints(prior []k3s.Taint, planned []k3s.Taint) []k3s.Taint {
+	stale := []k3s.Taint{}
+	for _, taint := range prior {
+		declared := false
+		for _, p := range planned {
+			if p.Key == taint.Key && p.Effect == taint.Effect {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			stale = append(stale, taint)
+		}
+	}
+	return stale
+}
+
+// State value for the labels observed on the Node. Unconfigured labels stay
+// null.
+func labelsToModel(ctx context.Context, declared types.Map, metadata *k3s.NodeMetadata, d *diag.Diagnostics) types.Map {
+	if declared.IsNull() || metadata == nil {
+		return declared
+	}
+	return configFragmentsToModel(ctx, declared, metadata.Labels, d)
+}
+
+// State value for the taints observed on the Node. Unconfigured taints stay
+// null.
+func taintsToModel(ctx context.Context, declared types.Set, metadata *k3s.NodeMetadata, d *diag.Diagnostics) types.Set {
+	if declared.IsNull() || metadata == nil {
+		return declared
+	}
+
+	models := make([]schemas.NodeTaint, 0, len(metadata.Taints))
+	for _, taint := range metadata.Taints {
+		value := types.StringNull()
+		if taint.Value != "" {
+			value = types.StringValue(taint.Value)
+		}
+		models = append(models, schemas.NodeTaint{
+			Key:    types.StringValue(taint.Key),
+			Value:  value,
+			Effect: types.StringValue(taint.Effect),
+		})
+	}
+	set, diags := types.SetValueFrom(ctx, taintsType(), models)
+	d.Append(diags...)
+	return set
+}
+
+func validateNodeMetadata(ctx context.Context, labels types.Map, taints types.Set, d *diag.Diagnostics) {
+	if !taints.IsNull() && !taints.IsUnknown() {
+		var models []schemas.NodeTaint
+		d.Append(taints.ElementsAs(ctx, &models, false)...)
+		for _, model := range models {
+			if err := model.Validate(); err != nil {
+				d.AddAttributeError(path.Root("taints"), "validating taints", err.Error())
+			}
+		}
+	}
+	if d.HasError() || !fullyKnown(ctx, labels) || !fullyKnown(ctx, taints) {
+		return
+	}
+
+	metadata := k3s.NodeMetadata{
+		Labels: labelsFromModel(ctx, labels, d),
+		Taints: taintsFromModel(ctx, taints, d),
+	}
+	if err := metadata.Validate(); err != nil {
+		d.AddError("validating labels and taints", err.Error())
+	}
+}
+
+// An agent reaches the API through cluster_auth only.
+func validateAgentClusterAuth(labels types.Map, taints types.Set, clusterAuth types.Object, d *diag.Diagnostics) {
+	if clusterAuth.IsNull() && (!labels.IsNull() || !taints.IsNull()) {
+		d.AddAttributeError(path.Root("cluster_auth"), "validating cluster_auth", "cluster_auth is required to manage the labels and taints of an agent")
+	}
+}
+
+// Attributes only used to reconcile labels and taints.
+var nodeMetadataAttributes = []string{"labels", "taints", "cluster_auth"}
+
+// Whether the update only changes labels or taints, which are reconciled
+// through the API without reinstalling or restarting k3s. Computed values
+// that are unknown until apply do not count as changes.
+func nodeMetadataOnlyChange(req resource.UpdateRequest) bool {
+	var prior, planned map[string]tftypes.Value
+	if err := req.State.Raw.As(&prior); err != nil {
+		return false
+	}
+	if err := req.Plan.Raw.As(&planned); err != nil {
+		return false
+	}
+
+	attributes := req.Plan.Schema.GetAttributes()
+	for name, value := range planned {
+		if slices.Contains(nodeMetadataAttributes, name) {
+			continue
+		}
+		attribute, ok := attributes[name]
+		if ok && attribute.IsComputed() && !attribute.IsOptional() && !value.IsFullyKnown() {
+			continue
+		}
+		if !value.Equal(prior[name]) {
+			return false
+		}
+	}
+	return true
+}
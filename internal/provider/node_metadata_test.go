@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+func taintsSet(taints ...k3s.Taint) types.Set {
+	elements := make([]attr.Value, 0, len(taints))
+	for _, taint := range taints {
+		value := types.StringNull()
+		if taint.Value != "" {
+			value = types.StringValue(taint.Value)
+		}
+		elements = append(elements, types.ObjectValueMust(taintsType().AttrTypes, map[string]attr.Value{
+			"key":    types.StringValue(taint.Key),
+			"value":  value,
+			"effect": types.StringValue(taint.Effect),
+		}))
+	}
+	return types.SetValueMust(taintsType(), elements)
+}
+
+func TestNodeMetadataFromModel(t *testing.T) {
+	ctx := context.Background()
+	var d diag.Diagnostics
+
+	labels := types.MapValueMust(types.StringType, map[string]attr.Value{"zone": types.StringValue("b")})
+	priorLabels := types.MapValueMust(types.StringType, map[string]attr.Value{"zone": types.StringValue("a"), "team": types.StringValue("data")})
+	taints := taintsSet(k3s.Taint{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"})
+	priorTaints := taintsSet(k3s.Taint{Key: "dedicated", Value: "cpu", Effect: "NoSchedule"}, k3s.Taint{Key: "spot", Effect: "NoSchedule"})
+
+	metadata := nodeMetadataFromModel(ctx, labels, taints, priorLabels, priorTaints, nil, &d)
+	if d.HasError() {
+		t.Fatalf("nodeMetadataFromModel() diagnostics = %v", d)
+	}
+	want := &k3s.NodeMetadata{
+		Labels:      map[string]string{"zone": "b"},
+		Taints:      []k3s.Taint{{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}},
+		StaleLabels: []string{"team"},
+		StaleTaints: []k3s.Taint{{Key: "spot", Effect: "NoSchedule"}},
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("nodeMetadataFromModel() = %+v, want %+v", metadata, want)
+	}
+
+	null := nodeMetadataFromModel(ctx, types.MapNull(types.StringType), types.SetNull(taintsType()), types.MapNull(types.StringType), types.SetNull(taintsType()), nil, &d)
+	if null != nil {
+		t.Errorf("nodeMetadataFromModel() = %+v without labels or taints, want nil", null)
+	}
+}
+
+func TestNodeMetadataToModel(t *testing.T) {
+	ctx := context.Background()
+	var d diag.Diagnostics
+
+	declared := taintsSet(k3s.Taint{Key: "dedicated", Value: "gpu", Effect: "NoSchedule"}, k3s.Taint{Key: "spot", Effect: "NoSchedule"})
+	observed := &k3s.NodeMetadata{
+		Labels: map[string]string{},
+		Taints: []k3s.Taint{{Key: "spot", Effect: "NoSchedule"}},
+	}
+	if got, want := taintsToModel(ctx, declared, observed, &d), taintsSet(k3s.Taint{Key: "spot", Effect: "NoSchedule"}); !got.Equal(want) {
+		t.Errorf("taintsToModel() = %v, want %v", got, want)
+	}
+
+	labels := types.MapValueMust(types.StringType, map[string]attr.Value{"zone": types.StringValue("b")})
+	if got := labelsToModel(ctx, labels, observed, &d); len(got.Elements()) != 0 {
+		t.Errorf("labelsToModel() = %v, want the removed label dropped", got)
+	}
+	if got := labelsToModel(ctx, types.MapNull(types.StringType), observed, &d); !got.IsNull() {
+		t.Errorf("labelsToModel() = %v, want unconfigured labels to stay null", got)
+	}
+	if d.HasError() {
+		t.Errorf("diagnostics = %v", d)
+	}
+}
+
+func TestValidateNodeMetadata(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name      string
+		labels    types.Map
+		taints    types.Set
+		wantError bool
+	}{
+		{name: "valid", labels: types.MapValueMust(types.StringType, map[string]attr.Value{"zone": types.StringValue("a")}), taints: taintsSet(k3s.Taint{Key: "dedicated", Effect: "NoSchedule"})},
+		{name: "invalid label", labels: types.MapValueMust(types.StringType, map[string]attr.Value{"zone": types.StringValue("a b")}), taints: types.SetNull(taintsType()), wantError: true},
+		{name: "invalid effect", labels: types.MapNull(types.StringType), taints: taintsSet(k3s.Taint{Key: "dedicated", Effect: "Never"}), wantError: true},
+		{
+			name:   "empty taint value",
+			labels: types.MapNull(types.StringType),
+			taints: types.SetValueMust(taintsType(), []attr.Value{types.ObjectValueMust(taintsType().AttrTypes, map[string]attr.Value{
+				"key":    types.StringValue("dedicated"),
+				"value":  types.StringValue(""),
+				"effect": types.StringValue("NoSchedule"),
+			})}),
+			wantError: true,
+		},
+	}
+
+	for _, c := range cases {
+		var d diag.Diagnostics
+		validateNodeMetadata(ctx, c.labels, c.taints, &d)
+		if d.HasError() != c.wantError {
+			t.Errorf("%s: validateNodeMetadata() diagnostics = %v, want error %v", c.name, d, c.wantError)
+		}
+	}
+}
+
+func TestNodeMetadataOnlyChange(t *testing.T) {
+	testSchema := schema.Schema{Attributes: map[string]schema.Attribute{
+		"version": schema.StringAttribute{Optional: true},
+		"labels":  schema.MapAttribute{Optional: true, ElementType: types.StringType},
+		"active":  schema.BoolAttribute{Computed: true},
+	}}
+	objectType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+		"version": tftypes.String,
+		"labels":  tftypes.Map{ElementType: tftypes.String},
+		"active":  tftypes.Bool,
+	}}
+	value := func(version string, zone string, active any) tftypes.Value {
+		activeValue := tftypes.NewValue(tftypes.Bool, tftypes.UnknownValue)
+		if active != nil {
+			activeValue = tftypes.NewValue(tftypes.Bool, active)
+		}
+		return tftypes.NewValue(objectType, map[string]tftypes.Value{
+			"version": tftypes.NewValue(tftypes.String, version),
+			"labels": tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, map[string]tftypes.Value{
+				"zone": tftypes.NewValue(tftypes.String, zone),
+			}),
+			"active": activeValue,
+		})
+	}
+
+	prior := value("v1.31.0+k3s1", "a", true)
+	cases := []struct {
+		name    string
+		planned tftypes.Value
+		want    bool
+	}{
+		{name: "labels changed", planned: value("v1.31.0+k3s1", "b", true), want: true},
+		{name: "computed unknown", planned: value("v1.31.0+k3s1", "b", nil), want: true},
+		{name: "version changed", planned: value("v1.32.0+k3s1", "b", true)},
+	}
+	for _, c := range cases {
+		req := resource.UpdateRequest{
+			State: tfsdk.State{Schema: testSchema, Raw: prior},
+			Plan:  tfsdk.Plan{Schema: testSchema, Raw: c.planned},
+		}
+		if got := nodeMetadataOnlyChange(req); got != c.want {
+			t.Errorf("%s: nodeMetadataOnlyChange() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
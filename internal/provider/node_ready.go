@@ -0,0 +1,52 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+const defaultReadyTimeout = "5m"
+
+func readyTimeoutSchema(waitsFor string) schema.Attribute {
+	return schema.StringAttribute{
+		Optional: true,
+		Computed: true,
+		Default:  stringdefault.StaticString(defaultReadyTimeout),
+		MarkdownDescription: "How long install and update wait for " + waitsFor + " after the service started, as a Go duration. " +
+			"The node conditions are reported when it is not Ready in time. `0s` only waits for the service to be active. Defaults to `" + defaultReadyTimeout + "`.",
+	}
+}
+
+// Ready timeout of the plan, checked by validateReadyTimeout.
+func readyTimeoutFromModel(value types.String) time.Duration {
+	if value.IsNull() || value.IsUnknown() {
+		return k3s.NODE_READY_TIMEOUT
+	}
+	timeout, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		return k3s.NODE_READY_TIMEOUT
+	}
+	return timeout
+}
+
+func validateReadyTimeout(value types.String, d *diag.Diagnostics) {
+	if value.IsNull() || value.IsUnknown() {
+		return
+	}
+	timeout, err := time.ParseDuration(value.ValueString())
+	if err != nil {
+		d.AddAttributeError(path.Root("ready_timeout"), "validating ready_timeout", fmt.Sprintf("ready_timeout must be a duration such as 5m: %s", err.Error()))
+		return
+	}
+	if timeout < 0 {
+		d.AddAttributeError(path.Root("ready_timeout"), "validating ready_timeout", "ready_timeout cannot be negative")
+	}
+}
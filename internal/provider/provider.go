@@ -65,6 +65,7 @@ func (p *K3sProvider) Configure(ctx context.Context, req provider.ConfigureReque
 func (p *K3sProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
 		NewK3sKubeConfigData,
+		NewK3sEtcdSnapshotsData,
 	}
 }
 
@@ -75,5 +76,9 @@ func (p *K3sProvider) Resources(_ context.Context) []func() resource.Resource {
 		NewK3sAgentResource,
 		NewK3sTokenResource,
 		NewK3sKubeConfigResource,
+		NewK3sHelmChartResource,
+		NewK3sHelmChartConfigResource,
+		NewK3sEtcdSnapshotResource,
+		NewK3sEtcdSnapshotRetentionResource,
 	}
 }
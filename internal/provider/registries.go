@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"context"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+// Typed registries to write, nil when the block is not set.
+func registriesFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.Registries {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.Registries
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+
+	mirrors := make(map[string]schemas.RegistryMirror)
+	if !model.Mirrors.IsNull() && !model.Mirrors.IsUnknown() {
+		d.Append(model.Mirrors.ElementsAs(ctx, &mirrors, false)...)
+	}
+	configs := make(map[string]schemas.RegistryConfig)
+	if !model.Configs.IsNull() && !model.Configs.IsUnknown() {
+		d.Append(model.Configs.ElementsAs(ctx, &configs, false)...)
+	}
+	if d.HasError() {
+		return nil
+	}
+
+	registries := &k3s.Registries{
+		Mirrors: make(map[string]k3s.RegistryMirror, len(mirrors)),
+		Configs: make(map[string]k3s.RegistryConfig, len(configs)),
+	}
+	for host, mirror := range mirrors {
+		var entry k3s.RegistryMirror
+		d.Append(mirror.Endpoints.ElementsAs(ctx, &entry.Endpoints, false)...)
+		if !mirror.Rewrite.IsNull() && !mirror.Rewrite.IsUnknown() {
+			d.Append(mirror.Rewrite.ElementsAs(ctx, &entry.Rewrites, false)...)
+		}
+		registries.Mirrors[host] = entry
+	}
+	for host, config := range configs {
+		var entry k3s.RegistryConfig
+		if !config.Auth.IsNull() && !config.Auth.IsUnknown() {
+			var auth schemas.RegistryAuth
+			d.Append(config.Auth.As(ctx, &auth, basetypes.ObjectAsOptions{})...)
+			entry.Username = auth.Username.ValueString()
+			entry.Password = auth.Password.ValueString()
+			entry.Auth = auth.Auth.ValueString()
+			entry.IdentityToken = auth.IdentityToken.ValueString()
+		}
+		if !config.Tls.IsNull() && !config.Tls.IsUnknown() {
+			var tls schemas.RegistryTls
+			d.Append(config.Tls.As(ctx, &tls, basetypes.ObjectAsOptions{})...)
+			entry.CA = tls.Ca.ValueString()
+			entry.Cert = tls.Cert.ValueString()
+			entry.Key = tls.Key.ValueString()
+			entry.CAFile = tls.CaFile.ValueString()
+			entry.CertFile = tls.CertFile.ValueString()
+			entry.KeyFile = tls.KeyFile.ValueString()
+			entry.InsecureSkipVerify = tls.InsecureSkipVerify.ValueBool()
+		}
+		for _, secret := range []string{entry.Password, entry.Auth, entry.IdentityToken, entry.Key} {
+			if secret != "" {
+				tflog.MaskMessageStrings(ctx, secret)
+			}
+		}
+		registries.Configs[host] = entry
+	}
+
+	return registries
+}
+
+func validateRegistries(ctx context.Context, value types.Object, registry types.String, d *diag.Diagnostics) {
+	if value.IsNull() {
+		return
+	}
+	if !registry.IsNull() && !registry.IsUnknown() && registry.ValueString() != "" {
+		d.AddError("validating registries", "registry and registries cannot both be set")
+		return
+	}
+	if !fullyKnown(ctx, value) {
+		return
+	}
+
+	var model schemas.Registries
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return
+	}
+	if err := model.Validate(); err != nil {
+		d.AddError("validating registries", err.Error())
+		return
+	}
+
+	registries := registriesFromModel(ctx, value, d)
+	if d.HasError() {
+		return
+	}
+	if err := registries.Validate(); err != nil {
+		d.AddError("validating registries", err.Error())
+	}
+}
+
+// Whether a value and everything nested in it is known.
+func fullyKnown(ctx context.Context, value attr.Value) bool {
+	tfValue, err := value.ToTerraformValue(ctx)
+	return err == nil && tfValue.IsFullyKnown()
+}
+
+// Registry hosts present in the prior state that are no longer declared.
+func staleRegistryHosts(prior *k3s.Registries, planned *k3s.Registries) []string {
+	stale := []string{}
+	if prior == nil {
+		return stale
+	}
+	for host := range prior.Configs {
+		if planned != nil {
+			if _, ok := planned.Configs[host]; ok {
+				continue
+			}
+		}
+		stale = append(stale, host)
+	}
+	sort.Strings(stale)
+	return stale
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func TestStaleRegistryHosts(t *testing.T) {
+	prior := &k3s.Registries{Configs: map[string]k3s.RegistryConfig{"a.example.com": {}, "b.example.com": {}}}
+	planned := &k3s.Registries{Configs: map[string]k3s.RegistryConfig{"b.example.com": {}}}
+
+	if got, want := staleRegistryHosts(prior, planned), []string{"a.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staleRegistryHosts() = %v, want %v", got, want)
+	}
+	if got, want := staleRegistryHosts(prior, nil), []string{"a.example.com", "b.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staleRegistryHosts() = %v, want %v", got, want)
+	}
+	if got := staleRegistryHosts(nil, planned); len(got) != 0 {
+		t.Errorf("staleRegistryHosts() = %v, want none", got)
+	}
+}
+
+func TestRegistriesFromModel(t *testing.T) {
+	ctx := context.Background()
+	mirrorType := types.ObjectType{AttrTypes: schemas.RegistryMirror{}.AttributeTypes()}
+	configType := types.ObjectType{AttrTypes: schemas.RegistryConfig{}.AttributeTypes()}
+	auth := types.ObjectValueMust(schemas.RegistryAuth{}.AttributeTypes(), map[string]attr.Value{
+		"username":       types.StringValue("user"),
+		"password":       types.StringValue("pass"),
+		"auth":           types.StringNull(),
+		"identity_token": types.StringNull(),
+	})
+	value := types.ObjectValueMust(schemas.Registries{}.AttributeTypes(), map[string]attr.Value{
+		"mirrors": types.MapValueMust(mirrorType, map[string]attr.Value{
+			"docker.io": types.ObjectValueMust(mirrorType.AttrTypes, map[string]attr.Value{
+				"endpoints": types.ListValueMust(types.StringType, []attr.Value{types.StringValue("https://mirror.example.com")}),
+				"rewrite":   types.MapNull(types.StringType),
+			}),
+		}),
+		"configs": types.MapValueMust(configType, map[string]attr.Value{
+			"registry.example.com": types.ObjectValueMust(configType.AttrTypes, map[string]attr.Value{
+				"auth": auth,
+				"tls":  types.ObjectNull(schemas.RegistryTls{}.AttributeTypes()),
+			}),
+		}),
+	})
+
+	var d diag.Diagnostics
+	registries := registriesFromModel(ctx, value, &d)
+	if d.HasError() {
+		t.Fatalf("registriesFromModel() diagnostics = %v", d)
+	}
+	if got := registries.Mirrors["docker.io"].Endpoints; !reflect.DeepEqual(got, []string{"https://mirror.example.com"}) {
+		t.Errorf("registriesFromModel() endpoints = %v", got)
+	}
+	if got := registries.Configs["registry.example.com"]; got.Username != "user" || got.Password != "pass" {
+		t.Errorf("registriesFromModel() config = %+v", got)
+	}
+
+	validateRegistries(ctx, value, types.StringValue("mirrors: {}"), &d)
+	if !d.HasError() {
+		t.Errorf("validateRegistries() accepted both registry and registries")
+	}
+}
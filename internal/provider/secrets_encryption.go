@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func secretsEncryptionModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *schemas.SecretsEncryption {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	var model schemas.SecretsEncryption
+	d.Append(value.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	if d.HasError() {
+		return nil
+	}
+	return &model
+}
+
+// Secrets encryption settings, nil when the block is not set.
+func secretsEncryptionFromModel(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.SecretsEncryption {
+	model := secretsEncryptionModel(ctx, value, d)
+	if model == nil {
+		return nil
+	}
+	return &k3s.SecretsEncryption{
+		Enabled:  model.Enabled.ValueBool(),
+		Provider: model.Provider.ValueString(),
+	}
+}
+
+// Planned secrets encryption settings for config validation, nil until
+// fully known.
+func plannedSecretsEncryption(ctx context.Context, value types.Object, d *diag.Diagnostics) *k3s.SecretsEncryption {
+	if !fullyKnown(ctx, value) {
+		return nil
+	}
+	return secretsEncryptionFromModel(ctx, value, d)
+}
+
+// Whether the planned rotate_keys asks for a key rotation of an existing
+// server. Removing the trigger does not rotate.
+func secretsKeyRotationRequested(ctx context.Context, prior types.Object, planned types.Object, d *diag.Diagnostics) bool {
+	if planned.IsNull() {
+		return false
+	}
+	plannedModel := secretsEncryptionModel(ctx, planned, d)
+	if plannedModel == nil || plannedModel.RotateKeys.IsUnknown() {
+		// Unknown during plan, assume the trigger changes
+		return true
+	}
+	if plannedModel.RotateKeys.IsNull() {
+		return false
+	}
+
+	priorModel := secretsEncryptionModel(ctx, prior, d)
+	if priorModel == nil {
+		return true
+	}
+	return !plannedModel.RotateKeys.Equal(priorModel.RotateKeys)
+}
+
+func validateSecretsEncryption(ctx context.Context, value types.Object, d *diag.Diagnostics) {
+	model := secretsEncryptionModel(ctx, value, d)
+	if model == nil {
+		return
+	}
+	// The provider is checked by k3s.SecretsEncryption with the planned config
+	if err := model.Validate(); err != nil {
+		d.AddAttributeError(path.Root("secrets_encryption"), "validating secrets_encryption", err.Error())
+	}
+}
+
+// Turning encryption off only drops the config key, after which the API
+// server can no longer read the secrets it encrypted. Refuse it until the
+// refreshed status shows the secrets were decrypted by hand.
+func validateSecretsEncryptionChange(ctx context.Context, prior types.Object, status types.Object, planned types.Object, d *diag.Diagnostics) {
+	priorModel := secretsEncryptionModel(ctx, prior, d)
+	if planned.IsUnknown() || priorModel == nil || !priorModel.Enabled.ValueBool() {
+		return
+	}
+	plannedModel := secretsEncryptionModel(ctx, planned, d)
+	if plannedModel != nil && (plannedModel.Enabled.IsUnknown() || plannedModel.Enabled.ValueBool()) {
+		return
+	}
+	if secretsEncryptionDisabled(ctx, status, d) {
+		return
+	}
+	d.AddAttributeError(path.Root("secrets_encryption"), "disabling secrets_encryption",
+		"Secrets that are already encrypted cannot be read once encryption is turned off. Run `k3s secrets-encrypt disable`, restart k3s and run "+
+			"`k3s secrets-encrypt reencrypt --force --skip` on the server before disabling secrets_encryption, or replace the server. "+
+			"The change is accepted once `secrets_encryption_status.status` reports `Disabled`.")
+}
+
+// Whether the observed status reports encryption as turned off.
+func secretsEncryptionDisabled(ctx context.Context, status types.Object, d *diag.Diagnostics) bool {
+	if status.IsNull() || status.IsUnknown() {
+		return false
+	}
+	var model schemas.SecretsEncryptionStatus
+	d.Append(status.As(ctx, &model, basetypes.ObjectAsOptions{})...)
+	return model.Status.ValueString() == "Disabled"
+}
+
+func secretsEncryptionStatusToModel(ctx context.Context, status *k3s.SecretsEncryptionStatus) types.Object {
+	if status == nil {
+		return types.ObjectNull(schemas.SecretsEncryptionStatus{}.AttributeTypes())
+	}
+	return schemas.SecretsEncryptionStatus{
+		Status:        types.StringValue(status.Status),
+		RotationStage: types.StringValue(status.RotationStage),
+		ServerHashes:  types.StringValue(status.ServerHashes),
+		ActiveKeyType: types.StringValue(status.ActiveKeyType),
+		ActiveKey:     types.StringValue(status.ActiveKey),
+	}.ToObject(ctx)
+}
@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func secretsEncryptionObject(enabled bool, rotateKeys types.String) types.Object {
+	return types.ObjectValueMust(schemas.SecretsEncryption{}.AttributeTypes(), map[string]attr.Value{
+		"enabled":     types.BoolValue(enabled),
+		"provider":    types.StringNull(),
+		"rotate_keys": rotateKeys,
+	})
+}
+
+func TestSecretsKeyRotationRequested(t *testing.T) {
+	null := types.ObjectNull(schemas.SecretsEncryption{}.AttributeTypes())
+	cases := []struct {
+		name           string
+		prior, planned types.Object
+		want           bool
+	}{
+		{"unset", null, null, false},
+		{"enabled without trigger", null, secretsEncryptionObject(true, types.StringNull()), false},
+		{"added", secretsEncryptionObject(true, types.StringNull()), secretsEncryptionObject(true, types.StringValue("1")), true},
+		{"unchanged", secretsEncryptionObject(true, types.StringValue("1")), secretsEncryptionObject(true, types.StringValue("1")), false},
+		{"changed", secretsEncryptionObject(true, types.StringValue("1")), secretsEncryptionObject(true, types.StringValue("2")), true},
+		{"trigger removed", secretsEncryptionObject(true, types.StringValue("1")), secretsEncryptionObject(true, types.StringNull()), false},
+		{"unknown trigger", secretsEncryptionObject(true, types.StringValue("1")), secretsEncryptionObject(true, types.StringUnknown()), true},
+	}
+	for _, c := range cases {
+		var d diag.Diagnostics
+		if got := secretsKeyRotationRequested(context.Background(), c.prior, c.planned, &d); got != c.want {
+			t.Errorf("%s: secretsKeyRotationRequested() = %v, want %v", c.name, got, c.want)
+		}
+		if d.HasError() {
+			t.Errorf("%s: secretsKeyRotationRequested() diagnostics = %v", c.name, d)
+		}
+	}
+}
+
+func TestValidateSecretsEncryption(t *testing.T) {
+	var d diag.Diagnostics
+	validateSecretsEncryption(context.Background(), secretsEncryptionObject(false, types.StringValue("1")), &d)
+	if !d.HasError() {
+		t.Errorf("expected rotate_keys without enabled to be rejected")
+	}
+
+	d = diag.Diagnostics{}
+	validateSecretsEncryption(context.Background(), secretsEncryptionObject(true, types.StringValue("1")), &d)
+	if d.HasError() {
+		t.Errorf("unexpected diagnostics %v", d)
+	}
+}
+
+func TestValidateSecretsEncryptionChange(t *testing.T) {
+	null := types.ObjectNull(schemas.SecretsEncryption{}.AttributeTypes())
+	noStatus := secretsEncryptionStatusToModel(context.Background(), nil)
+	encrypted := secretsEncryptionStatusToModel(context.Background(), &k3s.SecretsEncryptionStatus{Status: "Enabled"})
+	decrypted := secretsEncryptionStatusToModel(context.Background(), &k3s.SecretsEncryptionStatus{Status: "Disabled"})
+	cases := []struct {
+		name                   string
+		prior, status, planned types.Object
+		wantErr                bool
+	}{
+		{"enabled", null, noStatus, secretsEncryptionObject(true, types.StringNull()), false},
+		{"unchanged", secretsEncryptionObject(true, types.StringNull()), encrypted, secretsEncryptionObject(true, types.StringValue("1")), false},
+		{"never enabled", secretsEncryptionObject(false, types.StringNull()), noStatus, null, false},
+		{"disabled", secretsEncryptionObject(true, types.StringNull()), encrypted, secretsEncryptionObject(false, types.StringNull()), true},
+		{"removed", secretsEncryptionObject(true, types.StringNull()), encrypted, null, true},
+		{"decrypted by hand", secretsEncryptionObject(true, types.StringNull()), decrypted, null, false},
+		{"unknown", secretsEncryptionObject(true, types.StringNull()), encrypted, types.ObjectUnknown(schemas.SecretsEncryption{}.AttributeTypes()), false},
+	}
+	for _, c := range cases {
+		var d diag.Diagnostics
+		validateSecretsEncryptionChange(context.Background(), c.prior, c.status, c.planned, &d)
+		if d.HasError() != c.wantErr {
+			t.Errorf("%s: validateSecretsEncryptionChange() diagnostics = %v, want error %v", c.name, d, c.wantErr)
+		}
+	}
+}
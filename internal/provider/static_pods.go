@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+func staticPodsSchema() schema.Attribute {
+	return schema.MapAttribute{
+		Optional:    true,
+		ElementType: types.StringType,
+		MarkdownDescription: "Static pod manifests written to `<data-dir>/agent/pod-manifests`, keyed by name without the `.yaml` extension. " +
+			"Each value must be a single v1 Pod. Apply waits up to `ready_timeout` for the kubelet to report the mirror pod of every static pod as running, unless `disable-agent` is set. " +
+			"Static pods removed from this map are deleted from the host, which stops the pod.",
+	}
+}
+
+// Static pods share the name to content shape of config fragments.
+func staticPodsFromModel(ctx context.Context, value types.Map, d *diag.Diagnostics) map[string]string {
+	return configFragmentsFromModel(ctx, value, d)
+}
+
+func validateStaticPods(ctx context.Context, value types.Map, d *diag.Diagnostics) {
+	if !configFragmentsKnown(value) {
+		return
+	}
+
+	pods := staticPodsFromModel(ctx, value, d)
+	if d.HasError() {
+		return
+	}
+	if err := k3s.ValidateStaticPods(pods); err != nil {
+		d.AddError("validating static_pods", err.Error())
+	}
+}
+
+// Names present in the prior state that are no longer declared.
+func staleStaticPods(prior map[string]string, planned map[string]string) []string {
+	return staleConfigFragments(prior, planned)
+}
+
+// State value for the static pods observed on the host.
+func staticPodsToModel(ctx context.Context, current types.Map, observed map[string]string, d *diag.Diagnostics) types.Map {
+	return configFragmentsToModel(ctx, current, observed, d)
+}
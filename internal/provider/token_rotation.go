@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"striveworks.us/terraform-provider-k3s/internal/k3s"
+)
+
+var _ planmodifier.String = rotatedTokenModifier{}
+
+func rotateTokenSchema() schema.Attribute {
+	return schema.StringAttribute{
+		Optional:  true,
+		Sensitive: true,
+		MarkdownDescription: "New server token. Setting or changing it runs `k3s token rotate` on this server, writes the token to `K3S_TOKEN` in the service env file and restarts k3s. " +
+			"`token` is unknown until the rotation ran, so agents and servers using it are updated with the new token. Only set it on one server of a cluster. Removing it does not rotate the token again.",
+	}
+}
+
+// Whether the planned rotate_token asks for a rotation.
+func tokenRotationRequested(prior types.String, planned types.String) bool {
+	if planned.IsNull() || planned.IsUnknown() {
+		return false
+	}
+	return !planned.Equal(prior)
+}
+
+func validateRotateToken(token types.String, d *diag.Diagnostics) {
+	if token.IsNull() || token.IsUnknown() {
+		return
+	}
+	if err := k3s.ValidateRotateToken(token.ValueString()); err != nil {
+		d.AddAttributeError(path.Root("rotate_token"), "validating rotate_token", err.Error())
+	}
+}
+
+// Marks the observed token unknown when the plan rotates it, so resources
+// joining with the token get a new plan.
+type rotatedTokenModifier struct{}
+
+// Description implements planmodifier.String.
+func (m rotatedTokenModifier) Description(ctx context.Context) string {
+	return "The token is unknown when rotate_token changes."
+}
+
+// MarkdownDescription implements planmodifier.String.
+func (m rotatedTokenModifier) MarkdownDescription(ctx context.Context) string {
+	return "The token is unknown when `rotate_token` changes."
+}
+
+// PlanModifyString implements planmodifier.String.
+func (m rotatedTokenModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var prior, planned types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("rotate_token"), &prior)...)
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("rotate_token"), &planned)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if planned.IsUnknown() || tokenRotationRequested(prior, planned) {
+		resp.PlanValue = types.StringUnknown()
+	}
+}
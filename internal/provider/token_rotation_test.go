@@ -0,0 +1,26 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestTokenRotationRequested(t *testing.T) {
+	cases := []struct {
+		prior, planned types.String
+		want           bool
+	}{
+		{types.StringNull(), types.StringNull(), false},
+		{types.StringNull(), types.StringValue("new"), true},
+		{types.StringValue("old"), types.StringValue("new"), true},
+		{types.StringValue("same"), types.StringValue("same"), false},
+		{types.StringValue("old"), types.StringNull(), false},
+		{types.StringValue("old"), types.StringUnknown(), false},
+	}
+	for _, c := range cases {
+		if got := tokenRotationRequested(c.prior, c.planned); got != c.want {
+			t.Errorf("tokenRotationRequested(%v, %v) = %v, want %v", c.prior, c.planned, got, c.want)
+		}
+	}
+}
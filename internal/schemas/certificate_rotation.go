@@ -0,0 +1,58 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// CertificateRotation triggers `k3s certificate rotate` when its trigger
+// changes.
+type CertificateRotation struct {
+	Trigger  types.String `tfsdk:"trigger"`
+	Services types.List   `tfsdk:"services"`
+}
+
+// Schema implements K3sTypeSchema.
+func (c CertificateRotation) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Rotate the server certificates. Setting the block on an existing server or changing `trigger` stops k3s, runs `k3s certificate rotate` and starts k3s again. " +
+			"`kubeconfig`, `cluster_auth` and `certificate_expiry` are read again afterwards. Nothing is rotated when the server is created.",
+		Attributes: map[string]schema.Attribute{
+			"trigger": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Arbitrary value, certificates are rotated whenever it changes.",
+			},
+			"services": schema.ListAttribute{
+				Optional:            true,
+				ElementType:         types.StringType,
+				MarkdownDescription: "Services to rotate the certificates of, for example `admin` or `api-server`. All services when unset.",
+			},
+		},
+	}
+}
+
+func (c CertificateRotation) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"trigger":  types.StringType,
+		"services": types.ListType{ElemType: types.StringType},
+	}
+}
+
+func (c CertificateRotation) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, c)
+}
+
+// Validate checks that a trigger is set. Services are checked by
+// k3s.ValidateCertificateServices.
+func (c CertificateRotation) Validate() error {
+	if !c.Trigger.IsUnknown() && c.Trigger.ValueString() == "" {
+		return fmt.Errorf("rotate_certificates trigger cannot be empty")
+	}
+	return nil
+}
@@ -133,6 +133,36 @@ func (n ClusterAuth) DataSourceSchema() datasourceschema.Attribute {
 	}
 }
 
+// InputSchema returns the schema for cluster authentication details passed
+// in by a configuration, typically the cluster_auth output of a k3s_server.
+func (n ClusterAuth) InputSchema() resourceschema.Attribute {
+	return resourceschema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Cluster authentication details used to reach the Kubernetes API, typically `k3s_server.<name>.cluster_auth`.",
+		Attributes: map[string]resourceschema.Attribute{
+			"client_certificate_data": resourceschema.StringAttribute{
+				Required:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded client certificate.",
+			},
+			"certificate_authority_data": resourceschema.StringAttribute{
+				Required:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded certificate authority of the API server.",
+			},
+			"client_key_data": resourceschema.StringAttribute{
+				Required:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded client key.",
+			},
+			"server": resourceschema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The URL of the Kubernetes API server endpoint.",
+			},
+		},
+	}
+}
+
 // BuildClusterAuth constructs a ClusterAuth object from a raw Kubeconfig string.
 // It parses the Kubeconfig, extracts relevant authentication and cluster details,
 // and populates the ClusterAuth struct.
@@ -0,0 +1,88 @@
+package schemas
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// CustomCA is the organisation CA the k3s cluster CAs are issued from.
+type CustomCA struct {
+	RootCert         types.String `tfsdk:"root_cert"`
+	RootKey          types.String `tfsdk:"root_key"`
+	IntermediateCert types.String `tfsdk:"intermediate_cert"`
+	IntermediateKey  types.String `tfsdk:"intermediate_key"`
+	AllowRotation    types.Bool   `tfsdk:"allow_rotation"`
+	ForceRotation    types.Bool   `tfsdk:"force_rotation"`
+}
+
+// Schema implements K3sTypeSchema.
+func (c CustomCA) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Issue the k3s cluster CAs from your own CA. On create, the client, server, request-header and etcd CAs and the service account key are generated, " +
+			"signed by the intermediate, or by the root when there is no intermediate, and placed in `<data-dir>/server/tls` before k3s first starts. " +
+			"The signing key is not uploaded. Changing the CA of an existing server is refused unless `allow_rotation` is set.",
+		Attributes: map[string]schema.Attribute{
+			"root_cert": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "PEM encoded root CA certificate.",
+			},
+			"root_key": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded root CA key. Only needed without an intermediate.",
+			},
+			"intermediate_cert": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "PEM encoded intermediate CA certificate signed by `root_cert`.",
+			},
+			"intermediate_key": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "PEM encoded intermediate CA key.",
+			},
+			"allow_rotation": schema.BoolAttribute{
+				Optional: true,
+				MarkdownDescription: "Allow changing the CA of an existing server. New cluster CAs are generated and passed to `k3s certificate rotate-ca`, keeping the current service account key, and k3s is restarted. " +
+					"Other servers and agents must be restarted afterwards to pick up the new CA.",
+			},
+			"force_rotation": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Pass `--force` to `k3s certificate rotate-ca`. Needed when the new CA is not cross-signed by the old one, in which case agents have to rejoin.",
+			},
+		},
+	}
+}
+
+func (c CustomCA) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"root_cert":         types.StringType,
+		"root_key":          types.StringType,
+		"intermediate_cert": types.StringType,
+		"intermediate_key":  types.StringType,
+		"allow_rotation":    types.BoolType,
+		"force_rotation":    types.BoolType,
+	}
+}
+
+func (c CustomCA) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, c)
+}
+
+// Validate implements K3sTypeSchema. The certificates and keys are checked
+// by k3s.CustomCA.
+func (c CustomCA) Validate() error {
+	return nil
+}
+
+// SameAuthority reports whether both blocks describe the same CA material.
+func (c CustomCA) SameAuthority(other CustomCA) bool {
+	return c.RootCert.Equal(other.RootCert) &&
+		c.RootKey.Equal(other.RootKey) &&
+		c.IntermediateCert.Equal(other.IntermediateCert) &&
+		c.IntermediateKey.Equal(other.IntermediateKey)
+}
@@ -0,0 +1,116 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// EtcdRestore is the snapshot a new server restores its datastore from.
+type EtcdRestore struct {
+	Path types.String `tfsdk:"path"`
+	S3   types.Object `tfsdk:"s3"`
+}
+
+type EtcdRestoreS3 struct {
+	Bucket        types.String `tfsdk:"bucket"`
+	Endpoint      types.String `tfsdk:"endpoint"`
+	Region        types.String `tfsdk:"region"`
+	Folder        types.String `tfsdk:"folder"`
+	AccessKey     types.String `tfsdk:"access_key"`
+	SecretKey     types.String `tfsdk:"secret_key"`
+	SkipSSLVerify types.Bool   `tfsdk:"skip_ssl_verify"`
+	Insecure      types.Bool   `tfsdk:"insecure"`
+}
+
+// Schema implements K3sTypeSchema.
+func (r EtcdRestore) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Restore the embedded etcd datastore from a snapshot when the server is created. " +
+			"k3s runs with `--cluster-reset` in place of the first normal start, then the service is started as usual. " +
+			"Requires `highly_available.cluster_init` and `bootstrap_token` set to the token of the cluster the snapshot was taken from. " +
+			"Only used on create, replace the server to restore it again.",
+		Attributes: map[string]schema.Attribute{
+			"path": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Absolute path of the snapshot on the node, or the snapshot name when restoring from `s3`.",
+			},
+			"s3": schema.SingleNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Download the snapshot from S3 compatible storage.",
+				Attributes: map[string]schema.Attribute{
+					"bucket": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "Bucket holding the snapshot.",
+					},
+					"endpoint": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 endpoint. k3s defaults to `s3.amazonaws.com`.",
+					},
+					"region": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 region.",
+					},
+					"folder": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Folder of the snapshot in the bucket.",
+					},
+					"access_key": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 access key. Uses the instance credentials when unset.",
+					},
+					"secret_key": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "S3 secret key.",
+					},
+					"skip_ssl_verify": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Skip verification of the S3 endpoint certificate.",
+					},
+					"insecure": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Use plain HTTP to reach the S3 endpoint.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r EtcdRestore) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"path": types.StringType,
+		"s3":   types.ObjectType{AttrTypes: EtcdRestoreS3{}.AttributeTypes()},
+	}
+}
+
+func (s EtcdRestoreS3) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"bucket":          types.StringType,
+		"endpoint":        types.StringType,
+		"region":          types.StringType,
+		"folder":          types.StringType,
+		"access_key":      types.StringType,
+		"secret_key":      types.StringType,
+		"skip_ssl_verify": types.BoolType,
+		"insecure":        types.BoolType,
+	}
+}
+
+func (r EtcdRestore) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, r)
+}
+
+// Validate checks that a snapshot is named.
+func (r EtcdRestore) Validate() error {
+	if !r.Path.IsUnknown() && r.Path.ValueString() == "" {
+		return fmt.Errorf("restore_from_snapshot path cannot be empty")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package schemas
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// EtcdSnapshots is the typed form of the etcd-snapshot-* and etcd-s3-*
+// server flags.
+type EtcdSnapshots struct {
+	ScheduleCron types.String `tfsdk:"schedule_cron"`
+	Retention    types.Int64  `tfsdk:"retention"`
+	Compress     types.Bool   `tfsdk:"compress"`
+	Dir          types.String `tfsdk:"dir"`
+	S3           types.Object `tfsdk:"s3"`
+}
+
+type EtcdSnapshotsS3 struct {
+	Endpoint      types.String `tfsdk:"endpoint"`
+	Bucket        types.String `tfsdk:"bucket"`
+	Folder        types.String `tfsdk:"folder"`
+	Region        types.String `tfsdk:"region"`
+	AccessKey     types.String `tfsdk:"access_key"`
+	SecretKey     types.String `tfsdk:"secret_key"`
+	EndpointCa    types.String `tfsdk:"endpoint_ca"`
+	SkipSslVerify types.Bool   `tfsdk:"skip_ssl_verify"`
+	Insecure      types.Bool   `tfsdk:"insecure"`
+}
+
+// Schema implements K3sTypeSchema.
+func (e EtcdSnapshots) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Scheduled etcd snapshots of a server using embedded etcd, rendered to the `etcd-snapshot-*` and `etcd-s3-*` config keys. " +
+			"S3 credentials are written to a root-only env file loaded by the service instead of `config.yaml`.",
+		Attributes: map[string]schema.Attribute{
+			"schedule_cron": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Snapshot schedule in cron format. k3s defaults to every 12 hours.",
+			},
+			"retention": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "Number of scheduled snapshots to keep. k3s defaults to 5.",
+			},
+			"compress": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Compress snapshots.",
+			},
+			"dir": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Directory to save snapshots to. k3s defaults to `<data-dir>/server/db/snapshots`.",
+			},
+			"s3": schema.SingleNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Upload snapshots to S3 compatible storage.",
+				Attributes: map[string]schema.Attribute{
+					"endpoint": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 endpoint. k3s defaults to `s3.amazonaws.com`.",
+					},
+					"bucket": schema.StringAttribute{
+						Required:            true,
+						MarkdownDescription: "Bucket to upload snapshots to.",
+					},
+					"folder": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "Folder in the bucket.",
+					},
+					"region": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 region.",
+					},
+					"access_key": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "S3 access key. Uses the instance credentials when unset.",
+					},
+					"secret_key": schema.StringAttribute{
+						Optional:            true,
+						Sensitive:           true,
+						MarkdownDescription: "S3 secret key.",
+					},
+					"endpoint_ca": schema.StringAttribute{
+						Optional:            true,
+						MarkdownDescription: "PEM encoded CA bundle of the endpoint, uploaded to the server.",
+					},
+					"skip_ssl_verify": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Skip verification of the endpoint certificate.",
+					},
+					"insecure": schema.BoolAttribute{
+						Optional:            true,
+						MarkdownDescription: "Use plain HTTP to reach the endpoint.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (e EtcdSnapshots) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"schedule_cron": types.StringType,
+		"retention":     types.Int64Type,
+		"compress":      types.BoolType,
+		"dir":           types.StringType,
+		"s3":            types.ObjectType{AttrTypes: EtcdSnapshotsS3{}.AttributeTypes()},
+	}
+}
+
+func (s EtcdSnapshotsS3) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"endpoint":        types.StringType,
+		"bucket":          types.StringType,
+		"folder":          types.StringType,
+		"region":          types.StringType,
+		"access_key":      types.StringType,
+		"secret_key":      types.StringType,
+		"endpoint_ca":     types.StringType,
+		"skip_ssl_verify": types.BoolType,
+		"insecure":        types.BoolType,
+	}
+}
+
+func (e EtcdSnapshots) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, e)
+}
+
+// Validate implements K3sTypeSchema. The settings are checked against the
+// rest of the config by k3s.EtcdSnapshotConfig.
+func (e EtcdSnapshots) Validate() error {
+	return nil
+}
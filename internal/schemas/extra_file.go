@@ -0,0 +1,96 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+var (
+	fileModePattern  = regexp.MustCompile(`^0?[0-7]{3,4}$`)
+	fileOwnerPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.-]*|[0-9]+)(:([A-Za-z_][A-Za-z0-9_.-]*|[0-9]+))?$`)
+)
+
+// ExtraFile is a file written to the host next to the k3s config.
+type ExtraFile struct {
+	Path    types.String `tfsdk:"path"`
+	Content types.String `tfsdk:"content"`
+	Source  types.String `tfsdk:"source"`
+	Mode    types.String `tfsdk:"mode"`
+	Owner   types.String `tfsdk:"owner"`
+}
+
+// Schema implements K3sTypeSchema.
+func (f ExtraFile) Schema() schema.Attribute {
+	return schema.SetNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Files written to the host before k3s is installed or updated. Files removed from this set are deleted from the host, and content that changed on the host is rewritten on the next apply.",
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"path": schema.StringAttribute{
+					Required:            true,
+					MarkdownDescription: "Absolute path of the file on the host. Parent directories are created as needed.",
+				},
+				"content": schema.StringAttribute{
+					Optional:            true,
+					Sensitive:           true,
+					MarkdownDescription: "Content of the file. Conflicts with `source`.",
+				},
+				"source": schema.StringAttribute{
+					Optional:            true,
+					MarkdownDescription: "Path of a local file to upload. Conflicts with `content`.",
+				},
+				"mode": schema.StringAttribute{
+					Optional:            true,
+					MarkdownDescription: "Octal file mode, for example `0600`. The remote umask applies when unset.",
+				},
+				"owner": schema.StringAttribute{
+					Optional:            true,
+					MarkdownDescription: "Owner in `user` or `user:group` format. Defaults to `root:root`.",
+				},
+			},
+		},
+	}
+}
+
+func (f ExtraFile) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"path":    types.StringType,
+		"content": types.StringType,
+		"source":  types.StringType,
+		"mode":    types.StringType,
+		"owner":   types.StringType,
+	}
+}
+
+func (f ExtraFile) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, f)
+}
+
+// Validate checks the known attributes of a single file.
+func (f ExtraFile) Validate() error {
+	if !f.Path.IsUnknown() {
+		p := f.Path.ValueString()
+		if !path.IsAbs(p) || path.Clean(p) != p || p == "/" || strings.ContainsAny(p, "\n\x00") {
+			return fmt.Errorf("extra file path %q must be a clean absolute file path", p)
+		}
+	}
+	if !f.Content.IsUnknown() && !f.Source.IsUnknown() && f.Content.IsNull() == f.Source.IsNull() {
+		return fmt.Errorf("extra file %q requires exactly one of content or source", f.Path.ValueString())
+	}
+	if !f.Mode.IsNull() && !f.Mode.IsUnknown() && !fileModePattern.MatchString(f.Mode.ValueString()) {
+		return fmt.Errorf("extra file %q mode %q must be an octal file mode such as 0600", f.Path.ValueString(), f.Mode.ValueString())
+	}
+	if !f.Owner.IsNull() && !f.Owner.IsUnknown() && !fileOwnerPattern.MatchString(f.Owner.ValueString()) {
+		return fmt.Errorf("extra file %q owner %q must be in user or user:group format", f.Path.ValueString(), f.Owner.ValueString())
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package schemas_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func TestExtraFile_Validate(t *testing.T) {
+	file := func(path string, content types.String, source types.String, mode types.String, owner types.String) schemas.ExtraFile {
+		return schemas.ExtraFile{Path: types.StringValue(path), Content: content, Source: source, Mode: mode, Owner: owner}
+	}
+
+	tests := []struct {
+		name        string
+		file        schemas.ExtraFile
+		expectError bool
+	}{
+		{
+			name: "inline content",
+			file: file("/etc/rancher/k3s/audit.yaml", types.StringValue("a"), types.StringNull(), types.StringValue("0600"), types.StringValue("root:root")),
+		},
+		{
+			name: "local source",
+			file: file("/etc/rancher/k3s/audit.yaml", types.StringNull(), types.StringValue("audit.yaml"), types.StringNull(), types.StringValue("1000")),
+		},
+		{
+			name: "unknown content",
+			file: file("/etc/rancher/k3s/audit.yaml", types.StringUnknown(), types.StringNull(), types.StringNull(), types.StringNull()),
+		},
+		{
+			name:        "content and source",
+			file:        file("/etc/rancher/k3s/audit.yaml", types.StringValue("a"), types.StringValue("audit.yaml"), types.StringNull(), types.StringNull()),
+			expectError: true,
+		},
+		{
+			name:        "neither content nor source",
+			file:        file("/etc/rancher/k3s/audit.yaml", types.StringNull(), types.StringNull(), types.StringNull(), types.StringNull()),
+			expectError: true,
+		},
+		{
+			name:        "relative path",
+			file:        file("audit.yaml", types.StringValue("a"), types.StringNull(), types.StringNull(), types.StringNull()),
+			expectError: true,
+		},
+		{
+			name:        "directory path",
+			file:        file("/etc/rancher/k3s/", types.StringValue("a"), types.StringNull(), types.StringNull(), types.StringNull()),
+			expectError: true,
+		},
+		{
+			name:        "invalid mode",
+			file:        file("/etc/rancher/k3s/audit.yaml", types.StringValue("a"), types.StringNull(), types.StringValue("rw-r--r--"), types.StringNull()),
+			expectError: true,
+		},
+		{
+			name:        "invalid owner",
+			file:        file("/etc/rancher/k3s/audit.yaml", types.StringValue("a"), types.StringNull(), types.StringNull(), types.StringValue("root;reboot")),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.file.Validate()
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package schemas
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// ManifestStatus is the apply status of an auto-deploy manifest, as read
+// from its addons.k3s.cattle.io object.
+type ManifestStatus struct {
+	Status  types.String `tfsdk:"status"`
+	Message types.String `tfsdk:"message"`
+}
+
+// Schema implements K3sTypeSchema.
+func (m ManifestStatus) Schema() schema.Attribute {
+	return schema.MapNestedAttribute{
+		Computed:            true,
+		MarkdownDescription: "Apply status of every entry in `manifests`, keyed by manifest name.",
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"status": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "One of `applied`, `pending`, `failed` or `unknown` when the API could not be reached.",
+				},
+				"message": schema.StringAttribute{
+					Computed:            true,
+					MarkdownDescription: "Message of the most recent deploy event, usually the apply error.",
+				},
+			},
+		},
+	}
+}
+
+func (m ManifestStatus) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"status":  types.StringType,
+		"message": types.StringType,
+	}
+}
+
+func (m ManifestStatus) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, m)
+}
+
+// Validate implements K3sTypeSchema. The status is computed only.
+func (m ManifestStatus) Validate() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// NodeDrain is how a node is drained and deleted from the cluster before
+// k3s is uninstalled.
+type NodeDrain struct {
+	ClusterAuth      types.Object `tfsdk:"cluster_auth"`
+	Timeout          types.String `tfsdk:"timeout"`
+	GracePeriod      types.Int64  `tfsdk:"grace_period"`
+	IgnoreDaemonsets types.Bool   `tfsdk:"ignore_daemonsets"`
+}
+
+// Schema implements K3sTypeSchema.
+func (n NodeDrain) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Drain the node before uninstalling k3s. On destroy the node is cordoned, its pods are evicted while respecting PodDisruptionBudgets, " +
+			"and the Node object is deleted. Mirror pods of static pods are left to stop with k3s. A failed drain aborts the destroy and leaves the node cordoned.",
+		Attributes: n.attributes(),
+	}
+}
+
+// GracefulRemovalSchema is the schema of the same settings on a server,
+// where the etcd member is removed as well.
+func (n NodeDrain) GracefulRemovalSchema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Remove the server from the cluster before uninstalling k3s. On destroy the node is drained like an agent with `drain` and its Node object is deleted through another server. " +
+			"For a member of the embedded etcd cluster, the node is marked with the `etcd.k3s.cattle.io/remove` annotation after the drain, and the Node is only deleted once a surviving server reports the etcd member removed. " +
+			"The removal is refused when the ready etcd members left afterwards would not form a quorum, or when this is the last member.",
+		Attributes: n.attributes(),
+	}
+}
+
+func (n NodeDrain) attributes() map[string]schema.Attribute {
+	clusterAuth := ClusterAuth{}.InputSchema().(schema.SingleNestedAttribute)
+	clusterAuth.Optional = false
+	clusterAuth.Required = true
+	clusterAuth.MarkdownDescription += " Must reach a server that stays in the cluster."
+
+	return map[string]schema.Attribute{
+		"cluster_auth": clusterAuth,
+		"timeout": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "How long evicting the pods may take, as a Go duration. Defaults to `5m`.",
+		},
+		"grace_period": schema.Int64Attribute{
+			Optional:            true,
+			MarkdownDescription: "Seconds each pod gets to terminate. Uses the termination grace period of each pod when unset.",
+		},
+		"ignore_daemonsets": schema.BoolAttribute{
+			Optional:            true,
+			MarkdownDescription: "Leave pods of daemon sets running. Without it, a node running daemon set pods is not drained.",
+		},
+	}
+}
+
+func (n NodeDrain) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"cluster_auth":      types.ObjectType{AttrTypes: ClusterAuth{}.AttributeTypes()},
+		"timeout":           types.StringType,
+		"grace_period":      types.Int64Type,
+		"ignore_daemonsets": types.BoolType,
+	}
+}
+
+func (n NodeDrain) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, n)
+}
+
+// Validate implements K3sTypeSchema.
+func (n NodeDrain) Validate() error {
+	if !n.Timeout.IsNull() && !n.Timeout.IsUnknown() {
+		timeout, err := time.ParseDuration(n.Timeout.ValueString())
+		if err != nil {
+			return fmt.Errorf("drain timeout must be a duration such as 5m: %s", err.Error())
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("drain timeout must be positive")
+		}
+	}
+	if !n.GracePeriod.IsNull() && !n.GracePeriod.IsUnknown() && n.GracePeriod.ValueInt64() < 0 {
+		return fmt.Errorf("drain grace_period cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package schemas
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// NodeInfo holds the observed identity of the node running on the host,
+// exposed as top level computed attributes. node_ip is not part of it, as
+// it is also a server flag.
+type NodeInfo struct {
+	NodeName   types.String `tfsdk:"node_name"`
+	ExternalIp types.List   `tfsdk:"external_ip"`
+	Os         types.String `tfsdk:"os"`
+	Arch       types.String `tfsdk:"arch"`
+	Kernel     types.String `tfsdk:"kernel"`
+}
+
+// NullNodeInfo returns NodeInfo with every attribute set to null.
+func NullNodeInfo() NodeInfo {
+	return NodeInfo{
+		NodeName:   types.StringNull(),
+		ExternalIp: types.ListNull(types.StringType),
+		Os:         types.StringNull(),
+		Arch:       types.StringNull(),
+		Kernel:     types.StringNull(),
+	}
+}
+
+// Attributes returns the top level schema attributes, read on every
+// refresh from the host and from its Node object through the kubelet
+// kubeconfig.
+func (n NodeInfo) Attributes() map[string]schema.Attribute {
+	computedString := func(description string) schema.Attribute {
+		return schema.StringAttribute{
+			Computed:            true,
+			MarkdownDescription: description,
+			PlanModifiers: []planmodifier.String{
+				stringplanmodifier.UseStateForUnknown(),
+			},
+		}
+	}
+	return map[string]schema.Attribute{
+		"node_name": computedString("Kubernetes node name, `node-name` from config or the lowercased hostname, or the name the kubelet registered with when `with-node-id` is set."),
+		"external_ip": schema.ListAttribute{
+			Computed:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "`ExternalIP` addresses the Node reports, null until the node registered or when it has none.",
+			PlanModifiers: []planmodifier.List{
+				listplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"os":     computedString("Operating system of the node, for example `linux`."),
+		"arch":   computedString("CPU architecture of the node as Kubernetes names it, for example `amd64` or `arm64`."),
+		"kernel": computedString("Kernel release of the node."),
+	}
+}
+
+// NodeIpSchema is node_ip on k3s_agent, which has no server flags.
+func NodeIpSchema() schema.Attribute {
+	return schema.ListAttribute{
+		Computed:            true,
+		ElementType:         types.StringType,
+		MarkdownDescription: "`InternalIP` addresses the Node reports, null until the node registered.",
+		PlanModifiers: []planmodifier.List{
+			listplanmodifier.UseStateForUnknown(),
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// NodeTaint is a taint the provider manages on a Node.
+type NodeTaint struct {
+	Key    types.String `tfsdk:"key"`
+	Value  types.String `tfsdk:"value"`
+	Effect types.String `tfsdk:"effect"`
+}
+
+// Schema implements K3sTypeSchema.
+func (t NodeTaint) Schema() schema.Attribute {
+	return schema.SetNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Taints set on the Node through the API on every apply, identified by key and effect. Taints removed from this set are removed from the Node, " +
+			"taints set by anything else are left alone. Refresh reports taints that were changed or removed on the Node. Unlike `node-taint` in config, changes apply to registered nodes without restarting k3s.",
+		NestedObject: schema.NestedAttributeObject{
+			Attributes: map[string]schema.Attribute{
+				"key": schema.StringAttribute{
+					Required:            true,
+					MarkdownDescription: "Taint key.",
+				},
+				"value": schema.StringAttribute{
+					Optional:            true,
+					MarkdownDescription: "Taint value.",
+				},
+				"effect": schema.StringAttribute{
+					Required:            true,
+					MarkdownDescription: "One of `NoSchedule`, `PreferNoSchedule` or `NoExecute`.",
+				},
+			},
+		},
+	}
+}
+
+func (t NodeTaint) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"key":    types.StringType,
+		"value":  types.StringType,
+		"effect": types.StringType,
+	}
+}
+
+func (t NodeTaint) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, t)
+}
+
+// Validate implements K3sTypeSchema. Keys and effects are checked with the
+// labels of the node.
+func (t NodeTaint) Validate() error {
+	if !t.Value.IsNull() && !t.Value.IsUnknown() && t.Value.ValueString() == "" {
+		return fmt.Errorf("value of taint %s cannot be empty, omit it instead", t.Key.ValueString())
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// Registries is the typed form of registries.yaml.
+type Registries struct {
+	Mirrors types.Map `tfsdk:"mirrors"`
+	Configs types.Map `tfsdk:"configs"`
+}
+
+type RegistryMirror struct {
+	Endpoints types.List `tfsdk:"endpoints"`
+	Rewrite   types.Map  `tfsdk:"rewrite"`
+}
+
+type RegistryConfig struct {
+	Auth types.Object `tfsdk:"auth"`
+	Tls  types.Object `tfsdk:"tls"`
+}
+
+type RegistryAuth struct {
+	Username      types.String `tfsdk:"username"`
+	Password      types.String `tfsdk:"password"`
+	Auth          types.String `tfsdk:"auth"`
+	IdentityToken types.String `tfsdk:"identity_token"`
+}
+
+type RegistryTls struct {
+	Ca                 types.String `tfsdk:"ca"`
+	Cert               types.String `tfsdk:"cert"`
+	Key                types.String `tfsdk:"key"`
+	CaFile             types.String `tfsdk:"ca_file"`
+	CertFile           types.String `tfsdk:"cert_file"`
+	KeyFile            types.String `tfsdk:"key_file"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+}
+
+// Schema implements K3sTypeSchema.
+func (r Registries) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional: true,
+		MarkdownDescription: "Typed private registry configuration rendered to `registries.yaml`. Conflicts with `registry`. " +
+			"Inline TLS material is uploaded to `/etc/rancher/k3s/registries-tls/<registry>` and referenced from `registries.yaml`.",
+		Attributes: map[string]schema.Attribute{
+			"mirrors": schema.MapNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Registry mirrors keyed by registry host, or `*` for every registry.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"endpoints": schema.ListAttribute{
+							Required:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "URLs of the mirror endpoints, tried in order before the default endpoint.",
+						},
+						"rewrite": schema.MapAttribute{
+							Optional:            true,
+							ElementType:         types.StringType,
+							MarkdownDescription: "Image name rewrites, from a regular expression to its replacement.",
+						},
+					},
+				},
+			},
+			"configs": schema.MapNestedAttribute{
+				Optional:            true,
+				MarkdownDescription: "Authentication and TLS settings keyed by registry host.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"auth": schema.SingleNestedAttribute{
+							Optional:            true,
+							MarkdownDescription: "Registry credentials. Use either `username` and `password`, `auth`, or `identity_token`.",
+							Attributes: map[string]schema.Attribute{
+								"username": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "Registry username.",
+								},
+								"password": schema.StringAttribute{
+									Optional:            true,
+									Sensitive:           true,
+									MarkdownDescription: "Registry password.",
+								},
+								"auth": schema.StringAttribute{
+									Optional:            true,
+									Sensitive:           true,
+									MarkdownDescription: "Base64 encoded `username:password`.",
+								},
+								"identity_token": schema.StringAttribute{
+									Optional:            true,
+									Sensitive:           true,
+									MarkdownDescription: "Registry identity token.",
+								},
+							},
+						},
+						"tls": schema.SingleNestedAttribute{
+							Optional:            true,
+							MarkdownDescription: "TLS settings. Inline PEM content conflicts with the matching `_file` path.",
+							Attributes: map[string]schema.Attribute{
+								"ca": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "PEM encoded CA bundle to upload.",
+								},
+								"cert": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "PEM encoded client certificate to upload.",
+								},
+								"key": schema.StringAttribute{
+									Optional:            true,
+									Sensitive:           true,
+									MarkdownDescription: "PEM encoded client key to upload.",
+								},
+								"ca_file": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "Path of a CA bundle already on the host.",
+								},
+								"cert_file": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "Path of a client certificate already on the host.",
+								},
+								"key_file": schema.StringAttribute{
+									Optional:            true,
+									MarkdownDescription: "Path of a client key already on the host.",
+								},
+								"insecure_skip_verify": schema.BoolAttribute{
+									Optional:            true,
+									MarkdownDescription: "Skip verification of the registry certificate.",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r Registries) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"mirrors": types.MapType{ElemType: types.ObjectType{AttrTypes: RegistryMirror{}.AttributeTypes()}},
+		"configs": types.MapType{ElemType: types.ObjectType{AttrTypes: RegistryConfig{}.AttributeTypes()}},
+	}
+}
+
+func (m RegistryMirror) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"endpoints": types.ListType{ElemType: types.StringType},
+		"rewrite":   types.MapType{ElemType: types.StringType},
+	}
+}
+
+func (c RegistryConfig) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"auth": types.ObjectType{AttrTypes: RegistryAuth{}.AttributeTypes()},
+		"tls":  types.ObjectType{AttrTypes: RegistryTls{}.AttributeTypes()},
+	}
+}
+
+func (a RegistryAuth) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"username":       types.StringType,
+		"password":       types.StringType,
+		"auth":           types.StringType,
+		"identity_token": types.StringType,
+	}
+}
+
+func (t RegistryTls) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"ca":                   types.StringType,
+		"cert":                 types.StringType,
+		"key":                  types.StringType,
+		"ca_file":              types.StringType,
+		"cert_file":            types.StringType,
+		"key_file":             types.StringType,
+		"insecure_skip_verify": types.BoolType,
+	}
+}
+
+func (r Registries) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, r)
+}
+
+// Validate checks that the block configures at least one registry.
+func (r Registries) Validate() error {
+	if r.Mirrors.IsNull() && r.Configs.IsNull() {
+		return fmt.Errorf("registries must set mirrors or configs")
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package schemas_test
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func TestRegistries_Validate(t *testing.T) {
+	mirrors := types.MapValueMust(types.ObjectType{AttrTypes: schemas.RegistryMirror{}.AttributeTypes()}, nil)
+	noMirrors := types.MapNull(types.ObjectType{AttrTypes: schemas.RegistryMirror{}.AttributeTypes()})
+	noConfigs := types.MapNull(types.ObjectType{AttrTypes: schemas.RegistryConfig{}.AttributeTypes()})
+
+	if err := (schemas.Registries{Mirrors: mirrors, Configs: noConfigs}).Validate(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	if err := (schemas.Registries{Mirrors: noMirrors, Configs: noConfigs}).Validate(); err == nil {
+		t.Errorf("expected an error for an empty registries block")
+	}
+}
@@ -0,0 +1,120 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework/types/basetypes"
+)
+
+// SecretsEncryption is the typed form of the secrets-encryption server
+// flags, with a trigger for rotating the keys.
+type SecretsEncryption struct {
+	Enabled    types.Bool   `tfsdk:"enabled"`
+	Provider   types.String `tfsdk:"provider"`
+	RotateKeys types.String `tfsdk:"rotate_keys"`
+}
+
+// SecretsEncryptionStatus is the observed `k3s secrets-encrypt status`.
+type SecretsEncryptionStatus struct {
+	Status        types.String `tfsdk:"status"`
+	RotationStage types.String `tfsdk:"rotation_stage"`
+	ServerHashes  types.String `tfsdk:"server_hashes"`
+	ActiveKeyType types.String `tfsdk:"active_key_type"`
+	ActiveKey     types.String `tfsdk:"active_key"`
+}
+
+// Schema implements K3sTypeSchema.
+func (e SecretsEncryption) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Optional:            true,
+		MarkdownDescription: "Encryption of secrets at rest, rendered to the `secrets-encryption` and `secrets-encryption-provider` config keys.",
+		Attributes: map[string]schema.Attribute{
+			"enabled": schema.BoolAttribute{
+				Required:            true,
+				MarkdownDescription: "Encrypt secrets at rest. Secrets that already exist are encrypted by the next key rotation. Turning it off on an existing server is refused until the secrets were decrypted with `k3s secrets-encrypt disable` and `k3s secrets-encrypt reencrypt`, as reported by `secrets_encryption_status.status` being `Disabled`.",
+			},
+			"provider": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Encryption provider, one of `aescbc` or `secretbox`. k3s defaults to `aescbc`. Requires k3s 1.31 or newer.",
+			},
+			"rotate_keys": schema.StringAttribute{
+				Optional: true,
+				MarkdownDescription: "Arbitrary value, setting or changing it on an existing server runs `k3s secrets-encrypt rotate-keys` and waits until every secret was re-encrypted. " +
+					"Only set it on one server of a cluster.",
+			},
+		},
+	}
+}
+
+func (e SecretsEncryption) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"enabled":     types.BoolType,
+		"provider":    types.StringType,
+		"rotate_keys": types.StringType,
+	}
+}
+
+func (e SecretsEncryption) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, e)
+}
+
+// Validate implements K3sTypeSchema.
+func (e SecretsEncryption) Validate() error {
+	if !e.RotateKeys.IsNull() && !e.RotateKeys.IsUnknown() && !e.Enabled.IsUnknown() && !e.Enabled.ValueBool() {
+		return fmt.Errorf("secrets_encryption rotate_keys requires enabled")
+	}
+	return nil
+}
+
+// Schema implements K3sTypeSchema.
+func (s SecretsEncryptionStatus) Schema() schema.Attribute {
+	return schema.SingleNestedAttribute{
+		Computed:            true,
+		MarkdownDescription: "Output of `k3s secrets-encrypt status`, null unless `secrets_encryption` is enabled.",
+		Attributes: map[string]schema.Attribute{
+			"status": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "`Enabled` or `Disabled`.",
+			},
+			"rotation_stage": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Current rotation stage, `reencrypt_finished` once every secret uses the active key.",
+			},
+			"server_hashes": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the encryption config of every server matches.",
+			},
+			"active_key_type": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Type of the active key, for example `AES-CBC`.",
+			},
+			"active_key": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "Name of the active key.",
+			},
+		},
+	}
+}
+
+func (s SecretsEncryptionStatus) AttributeTypes() map[string]attr.Type {
+	return map[string]attr.Type{
+		"status":          types.StringType,
+		"rotation_stage":  types.StringType,
+		"server_hashes":   types.StringType,
+		"active_key_type": types.StringType,
+		"active_key":      types.StringType,
+	}
+}
+
+func (s SecretsEncryptionStatus) ToObject(ctx context.Context) basetypes.ObjectValue {
+	return ToObject(ctx, s)
+}
+
+// Validate implements K3sTypeSchema. The status is computed only.
+func (s SecretsEncryptionStatus) Validate() error {
+	return nil
+}
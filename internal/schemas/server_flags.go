@@ -0,0 +1,221 @@
+package schemas
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	nodeLabelPattern           = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_./]*)?[A-Za-z0-9]=([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+	nodeTaintPattern           = regexp.MustCompile(`^([A-Za-z0-9][-A-Za-z0-9_./]*)?[A-Za-z0-9](=[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+	writeKubeconfigModePattern = regexp.MustCompile(`^0?[0-7]{3}$`)
+	flannelBackends            = []string{"none", "vxlan", "host-gw", "wireguard-native"}
+	disableableComponents      = []string{"coredns", "servicelb", "traefik", "local-storage", "metrics-server", "runtimes"}
+)
+
+// ServerFlags holds the typed k3s server flags that are merged with the raw
+// config. Attribute names mirror the k3s flag names with underscores.
+type ServerFlags struct {
+	TlsSan              types.List   `tfsdk:"tls_san"`
+	NodeLabel           types.List   `tfsdk:"node_label"`
+	NodeTaint           types.List   `tfsdk:"node_taint"`
+	Disable             types.List   `tfsdk:"disable"`
+	ClusterCidr         types.String `tfsdk:"cluster_cidr"`
+	ServiceCidr         types.String `tfsdk:"service_cidr"`
+	ClusterDns          types.String `tfsdk:"cluster_dns"`
+	FlannelBackend      types.String `tfsdk:"flannel_backend"`
+	NodeIp              types.List   `tfsdk:"node_ip"`
+	NodeExternalIp      types.List   `tfsdk:"node_external_ip"`
+	WriteKubeconfigMode types.String `tfsdk:"write_kubeconfig_mode"`
+}
+
+// NullServerFlags returns ServerFlags with every attribute set to null.
+func NullServerFlags() ServerFlags {
+	return ServerFlags{
+		TlsSan:              types.ListNull(types.StringType),
+		NodeLabel:           types.ListNull(types.StringType),
+		NodeTaint:           types.ListNull(types.StringType),
+		Disable:             types.ListNull(types.StringType),
+		ClusterCidr:         types.StringNull(),
+		ServiceCidr:         types.StringNull(),
+		ClusterDns:          types.StringNull(),
+		FlannelBackend:      types.StringNull(),
+		NodeIp:              types.ListNull(types.StringType),
+		NodeExternalIp:      types.ListNull(types.StringType),
+		WriteKubeconfigMode: types.StringNull(),
+	}
+}
+
+// Attributes returns the top level schema attributes for the typed flags.
+func (f ServerFlags) Attributes() map[string]schema.Attribute {
+	return map[string]schema.Attribute{
+		"tls_san": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "Additional hostnames or IPv4/IPv6 addresses as Subject Alternative Names on the server TLS cert. Rendered as `tls-san`.",
+		},
+		"node_label": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "Labels in `key=value` format to register the node with. Rendered as `node-label`.",
+		},
+		"node_taint": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "Taints in `key=value:Effect` format to register the node with. Rendered as `node-taint`.",
+		},
+		"disable": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: fmt.Sprintf("Packaged components to not deploy. One of `%s`. Rendered as `disable`.", strings.Join(disableableComponents, "`, `")),
+		},
+		"cluster_cidr": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "IPv4/IPv6 network CIDRs to use for pod IPs, comma separated for dual-stack. Rendered as `cluster-cidr`.",
+		},
+		"service_cidr": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "IPv4/IPv6 network CIDRs to use for service IPs, comma separated for dual-stack. Rendered as `service-cidr`.",
+		},
+		"cluster_dns": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "IPv4/IPv6 cluster IP for the coredns service, comma separated for dual-stack. Rendered as `cluster-dns`.",
+		},
+		"flannel_backend": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: fmt.Sprintf("Flannel backend. One of `%s`. Rendered as `flannel-backend`.", strings.Join(flannelBackends, "`, `")),
+		},
+		"node_ip": schema.ListAttribute{
+			Optional:    true,
+			Computed:    true,
+			ElementType: types.StringType,
+			MarkdownDescription: "IPv4/IPv6 addresses to advertise for the node. Rendered as `node-ip`. " +
+				"When not set, the `InternalIP` addresses the Node reports, read on every refresh and null until the node registered.",
+			PlanModifiers: []planmodifier.List{
+				listplanmodifier.UseStateForUnknown(),
+			},
+		},
+		"node_external_ip": schema.ListAttribute{
+			Optional:            true,
+			ElementType:         types.StringType,
+			MarkdownDescription: "IPv4/IPv6 external addresses to advertise for the node. Rendered as `node-external-ip`.",
+		},
+		"write_kubeconfig_mode": schema.StringAttribute{
+			Optional:            true,
+			MarkdownDescription: "Octal file mode of the generated kubeconfig, for example `0600`. Rendered as `write-kubeconfig-mode`.",
+		},
+	}
+}
+
+// ConfigValues returns the known, non-null flags keyed by their k3s config
+// name, ready to be merged into config.yaml.
+func (f ServerFlags) ConfigValues(ctx context.Context) (map[string]any, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	values := make(map[string]any)
+
+	lists := map[string]types.List{
+		"tls-san":          f.TlsSan,
+		"node-label":       f.NodeLabel,
+		"node-taint":       f.NodeTaint,
+		"disable":          f.Disable,
+		"node-ip":          f.NodeIp,
+		"node-external-ip": f.NodeExternalIp,
+	}
+	for key, list := range lists {
+		if list.IsNull() || list.IsUnknown() {
+			continue
+		}
+		var elements []string
+		diags.Append(list.ElementsAs(ctx, &elements, false)...)
+		if diags.HasError() {
+			return nil, diags
+		}
+		values[key] = elements
+	}
+
+	strs := map[string]types.String{
+		"cluster-cidr":          f.ClusterCidr,
+		"service-cidr":          f.ServiceCidr,
+		"cluster-dns":           f.ClusterDns,
+		"flannel-backend":       f.FlannelBackend,
+		"write-kubeconfig-mode": f.WriteKubeconfigMode,
+	}
+	for key, str := range strs {
+		if str.IsNull() || str.IsUnknown() {
+			continue
+		}
+		values[key] = str.ValueString()
+	}
+
+	return values, diags
+}
+
+// Validate checks the format of every known flag value.
+func (f ServerFlags) Validate(ctx context.Context) error {
+	values, diags := f.ConfigValues(ctx)
+	if diags.HasError() {
+		return fmt.Errorf("reading server flags")
+	}
+
+	for _, label := range stringList(values["node-label"]) {
+		if !nodeLabelPattern.MatchString(label) {
+			return fmt.Errorf("node_label %q must be in key=value format", label)
+		}
+	}
+	for _, taint := range stringList(values["node-taint"]) {
+		if !nodeTaintPattern.MatchString(taint) {
+			return fmt.Errorf("node_taint %q must be in key=value:Effect format with an effect of NoSchedule, PreferNoSchedule or NoExecute", taint)
+		}
+	}
+	for _, component := range stringList(values["disable"]) {
+		if !slices.Contains(disableableComponents, component) {
+			return fmt.Errorf("disable %q must be one of %s", component, strings.Join(disableableComponents, ", "))
+		}
+	}
+	for _, key := range []string{"node-ip", "node-external-ip"} {
+		for _, ip := range stringList(values[key]) {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("%s %q is not a valid IP address", strings.ReplaceAll(key, "-", "_"), ip)
+			}
+		}
+	}
+	for _, key := range []string{"cluster-cidr", "service-cidr"} {
+		if value, ok := values[key].(string); ok {
+			for _, cidr := range strings.Split(value, ",") {
+				if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+					return fmt.Errorf("%s %q is not a valid CIDR", strings.ReplaceAll(key, "-", "_"), cidr)
+				}
+			}
+		}
+	}
+	if value, ok := values["cluster-dns"].(string); ok {
+		for _, ip := range strings.Split(value, ",") {
+			if net.ParseIP(strings.TrimSpace(ip)) == nil {
+				return fmt.Errorf("cluster_dns %q is not a valid IP address", ip)
+			}
+		}
+	}
+	if value, ok := values["flannel-backend"].(string); ok && !slices.Contains(flannelBackends, value) {
+		return fmt.Errorf("flannel_backend %q must be one of %s", value, strings.Join(flannelBackends, ", "))
+	}
+	if value, ok := values["write-kubeconfig-mode"].(string); ok && !writeKubeconfigModePattern.MatchString(value) {
+		return fmt.Errorf("write_kubeconfig_mode %q must be an octal file mode such as 0600", value)
+	}
+
+	return nil
+}
+
+func stringList(value any) []string {
+	list, _ := value.([]string)
+	return list
+}
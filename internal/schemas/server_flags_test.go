@@ -0,0 +1,125 @@
+package schemas_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"striveworks.us/terraform-provider-k3s/internal/schemas"
+)
+
+func stringList(values ...string) types.List {
+	elements := make([]attr.Value, 0, len(values))
+	for _, value := range values {
+		elements = append(elements, types.StringValue(value))
+	}
+	return types.ListValueMust(types.StringType, elements)
+}
+
+func TestServerFlags_Validate(t *testing.T) {
+	tests := []struct {
+		name        string
+		modify      func(*schemas.ServerFlags)
+		expectError bool
+	}{
+		{
+			name: "all null",
+		},
+		{
+			name: "valid values",
+			modify: func(f *schemas.ServerFlags) {
+				f.TlsSan = stringList("k3s.example.com", "10.0.0.10")
+				f.NodeLabel = stringList("topology.kubernetes.io/zone=a", "role=")
+				f.NodeTaint = stringList("CriticalAddonsOnly=true:NoExecute", "dedicated:NoSchedule")
+				f.Disable = stringList("traefik", "servicelb")
+				f.ClusterCidr = types.StringValue("10.42.0.0/16,2001:cafe:42::/56")
+				f.ServiceCidr = types.StringValue("10.43.0.0/16")
+				f.ClusterDns = types.StringValue("10.43.0.10")
+				f.FlannelBackend = types.StringValue("wireguard-native")
+				f.NodeIp = stringList("10.0.0.10")
+				f.NodeExternalIp = stringList("203.0.113.10")
+				f.WriteKubeconfigMode = types.StringValue("0644")
+			},
+		},
+		{
+			name:        "bad label",
+			modify:      func(f *schemas.ServerFlags) { f.NodeLabel = stringList("role") },
+			expectError: true,
+		},
+		{
+			name:        "bad taint effect",
+			modify:      func(f *schemas.ServerFlags) { f.NodeTaint = stringList("dedicated=true:Never") },
+			expectError: true,
+		},
+		{
+			name:        "unknown component",
+			modify:      func(f *schemas.ServerFlags) { f.Disable = stringList("traefk") },
+			expectError: true,
+		},
+		{
+			name:        "bad cidr",
+			modify:      func(f *schemas.ServerFlags) { f.ClusterCidr = types.StringValue("10.42.0.0") },
+			expectError: true,
+		},
+		{
+			name:        "bad dns",
+			modify:      func(f *schemas.ServerFlags) { f.ClusterDns = types.StringValue("coredns") },
+			expectError: true,
+		},
+		{
+			name:        "bad flannel backend",
+			modify:      func(f *schemas.ServerFlags) { f.FlannelBackend = types.StringValue("ipsec") },
+			expectError: true,
+		},
+		{
+			name:        "bad node ip",
+			modify:      func(f *schemas.ServerFlags) { f.NodeIp = stringList("10.0.0.300") },
+			expectError: true,
+		},
+		{
+			name:        "bad kubeconfig mode",
+			modify:      func(f *schemas.ServerFlags) { f.WriteKubeconfigMode = types.StringValue("644x") },
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := schemas.NullServerFlags()
+			if tt.modify != nil {
+				tt.modify(&flags)
+			}
+
+			err := flags.Validate(context.Background())
+			if tt.expectError && err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestServerFlags_ConfigValues(t *testing.T) {
+	flags := schemas.NullServerFlags()
+	flags.TlsSan = stringList("k3s.example.com")
+	flags.WriteKubeconfigMode = types.StringValue("0600")
+	flags.ClusterDns = types.StringUnknown()
+
+	values, diags := flags.ConfigValues(context.Background())
+	if diags.HasError() {
+		t.Fatalf("ConfigValues() diagnostics = %v", diags)
+	}
+
+	if len(values) != 2 {
+		t.Fatalf("ConfigValues() = %v, want only known non-null values", values)
+	}
+	if got, ok := values["tls-san"].([]string); !ok || len(got) != 1 || got[0] != "k3s.example.com" {
+		t.Errorf("tls-san = %#v, want [k3s.example.com]", values["tls-san"])
+	}
+	if got := values["write-kubeconfig-mode"]; got != "0600" {
+		t.Errorf("write-kubeconfig-mode = %#v, want 0600", got)
+	}
+}
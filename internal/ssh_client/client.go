@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -233,12 +234,15 @@ func (s *SSHClient) WaitForReady() error {
 }
 
 func (s *SSHClient) ReadFile(path string, missingOk bool, sudo bool) (string, error) {
-	command := fmt.Sprintf("cat %s", path)
+	// Quoted here rather than through the k3s command builder, which
+	// depends on this package.
+	quoted := "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+	command := fmt.Sprintf("cat %s", quoted)
 	if sudo {
 		command = fmt.Sprintf("sudo %s", command)
 	}
 	if missingOk {
-		command = fmt.Sprintf("sudo [ -f %s ] && %s || echo ''", path, command)
+		command = fmt.Sprintf("sudo [ -f %s ] && %s || echo ''", quoted, command)
 	}
 
 	result, err := s.Run(command)